@@ -1,31 +1,78 @@
 package account
 
 import (
-	"crypto/rand"
 	"fmt"
+	"sync"
 
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
 	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
 	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/errors"
 	"github.com/srchain/srcd/log"
 )
 
 var (
-	IDPrefix = []byte("ACID")
+	IDPrefix   = []byte("ACID")
+	XPubPrefix = []byte("ACXP")
 )
 
+var (
+	ErrNoKeyStore = errors.New("no keystore configured")
+	ErrNoXPub     = errors.New("account has no stored public key")
+	ErrWrongKey   = errors.New("keystore returned the wrong key")
+)
+
+// KeyStore holds the private keys behind the wallet's accounts.
+type KeyStore interface {
+	// XPrv returns the private key whose public key hashes to pubHash,
+	// unlocked with password.
+	XPrv(pubHash []byte, password string) (chainkd.XPrv, error)
+}
+
+// KeyGenerator is a KeyStore that also generates and stores new keys.
+type KeyGenerator interface {
+	KeyStore
+	// NewXPub generates a key stored under alias, encrypted with password,
+	// and returns its public key.
+	NewXPub(alias, password string) (chainkd.XPub, error)
+}
+
 type AccountManager struct {
 	db       database.Database
 	accounts []Account
+	keys     KeyStore
+
+	changeMu     *sync.Mutex // guards the change key indexes
+	reservations *reserver
 }
 
 func NewAccountManager(db database.Database) *AccountManager {
-	return &AccountManager{db: db}
+	return &AccountManager{
+		db:           db,
+		changeMu:     new(sync.Mutex),
+		reservations: newReserver(),
+	}
+}
+
+// CreateAccount generates a key in the keystore, stored under alias and
+// encrypted with password, and records the account it controls.
+func (am AccountManager) CreateAccount(alias, password string) (Account, error) {
+	kg, ok := am.keys.(KeyGenerator)
+	if !ok {
+		return Account{}, ErrNoKeyStore
+	}
+	xpub, err := kg.NewXPub(alias, password)
+	if err != nil {
+		return Account{}, err
+	}
+	return am.AddAccount(xpub)
 }
 
-func (am AccountManager) CreateAccount() (Account, error) {
-	xpubs := []chainkd.XPub{}
-	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
-	xpubs = append(xpubs, xpub)
+// AddAccount records the account controlled by xpub, whose private key is
+// held by the keystore.
+func (am AccountManager) AddAccount(xpub chainkd.XPub) (Account, error) {
 	program, pubhash, err := CreateP2PKH(xpub)
 	if err != nil {
 		return Account{}, fmt.Errorf("create account fail:%x\n", err)
@@ -33,6 +80,8 @@ func (am AccountManager) CreateAccount() (Account, error) {
 	//ID-Pubhash
 	keyID := append(IDPrefix, pubhash[:]...)
 	am.db.Put(keyID, pubhash)
+	//Pubhash-XPub
+	am.db.Put(append(XPubPrefix, pubhash...), xpub[:])
 	//Pubhash-Account
 	am.db.Put(pubhash, []byte(program.Address))
 	return Account{"", program.Address}, nil
@@ -53,3 +102,141 @@ func (am AccountManager) GetCurrentNodeAccounts(id []byte) ([]Account,error) {
 	}
 	return accounts, nil
 }
+
+// ControlProgram returns the wallet's control program program, naming the
+// account it pays to, or ErrUnknownAccount if no account of the wallet owns
+// it. Accounts are identified by their address.
+func (am AccountManager) ControlProgram(program []byte) (*CtrlProgram, error) {
+	if cp, ok := am.derivedProgram(program); ok {
+		return cp, nil
+	}
+	if addr := transaction.ProgramAddress(program); addr != "" {
+		if acc, ok := am.WatchAccount(addr); ok {
+			return &CtrlProgram{AccountID: acc.Address, Address: acc.Address, ControlProgram: program}, nil
+		}
+	}
+	if !vm.IsP2WPKHProgram(program) {
+		return nil, ErrUnknownAccount
+	}
+	addr, err := am.db.Get(program[2:])
+	if err != nil || len(addr) == 0 {
+		return nil, ErrUnknownAccount
+	}
+	return &CtrlProgram{
+		AccountID:      string(addr),
+		Address:        string(addr),
+		ControlProgram: program,
+	}, nil
+}
+
+// SetKeyStore sets the keystore SignTemplate takes account keys from.
+func (am *AccountManager) SetKeyStore(ks KeyStore) {
+	am.keys = ks
+}
+
+// accountXPub returns the public key of the account whose key hashes to
+// pubHash.
+func (am AccountManager) accountXPub(pubHash []byte) (chainkd.XPub, error) {
+	var xpub chainkd.XPub
+	if ok, _ := am.db.Has(pubHash); !ok {
+		return xpub, ErrUnknownAccount
+	}
+	data, _ := am.db.Get(append(XPubPrefix, pubHash...))
+	if len(data) != len(xpub) {
+		return xpub, ErrNoXPub
+	}
+	copy(xpub[:], data)
+	return xpub, nil
+}
+
+// accountSigners returns the signers of the account with address addr,
+// along with the account's own control program.
+func (am AccountManager) accountSigners(addr string) (signers, []byte, error) {
+	program, err := AddressProgram(addr)
+	if err != nil {
+		return signers{}, nil, err
+	}
+	if acc, ok := am.WatchAccount(addr); ok {
+		return acc.signers(), program, nil
+	}
+	if !vm.IsP2WPKHProgram(program) {
+		return signers{}, nil, ErrUnknownAccount
+	}
+	xpub, err := am.accountXPub(program[2:])
+	if err != nil {
+		return signers{}, nil, err
+	}
+	return singleSigner(xpub), program, nil
+}
+
+// SignTemplate signs every input of tpl expecting a signature from one of
+// the wallet's accounts, with that account's key, or the change key derived
+// from it, unlocked with password. Inputs of watch-only accounts are left
+// for their offline signers.
+func (am AccountManager) SignTemplate(tpl *transaction.Template, password string) error {
+	if am.keys == nil {
+		return ErrNoKeyStore
+	}
+	signed := make(map[chainkd.XPub]bool)
+	for _, sigInst := range tpl.SigningInstructions {
+		for _, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(*transaction.RawTxSigWitness)
+			if !ok {
+				continue
+			}
+			for _, key := range sw.Keys {
+				if signed[key.XPub] {
+					continue
+				}
+				xprv, ok, err := am.signingKey(key.XPub, password)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				if xprv.XPub() != key.XPub {
+					return ErrWrongKey
+				}
+				if err := transaction.Sign(tpl, xprv); err != nil {
+					return err
+				}
+				signed[key.XPub] = true
+			}
+		}
+	}
+	return nil
+}
+
+// signingKey returns the private key of xpub, the key of one of the wallet's
+// accounts or one derived from it, unlocked with password. It reports false
+// if the key is not the wallet's.
+func (am AccountManager) signingKey(xpub chainkd.XPub, password string) (chainkd.XPrv, bool, error) {
+	pubHash := ripemd160.Ripemd160(xpub.PublicKey())
+	if ok, _ := am.db.Has(pubHash); ok {
+		xprv, err := am.keys.XPrv(pubHash, password)
+		return xprv, true, err
+	}
+
+	program, err := vm.P2WSHProgram(pubHash)
+	if err != nil {
+		return chainkd.XPrv{}, false, nil
+	}
+	cp, ok := am.derivedProgram(program)
+	if !ok {
+		return chainkd.XPrv{}, false, nil
+	}
+	// The keys of watch-only accounts are signed with offline.
+	if _, ok := am.WatchAccount(cp.AccountID); ok {
+		return chainkd.XPrv{}, false, nil
+	}
+	accountProgram, err := AddressProgram(cp.AccountID)
+	if err != nil {
+		return chainkd.XPrv{}, false, err
+	}
+	xprv, err := am.keys.XPrv(accountProgram[2:], password)
+	if err != nil {
+		return chainkd.XPrv{}, true, err
+	}
+	return xprv.DerivePath(programPath(cp)), true, nil
+}
@@ -0,0 +1,307 @@
+package account
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/srchain/srcd/common/address"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519"
+	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/params"
+)
+
+// Action types understood by Build.
+const (
+	// ActionSpendAccount spends Amount of AssetID from the account paying
+	// to Account.
+	ActionSpendAccount = "spend_account"
+	// ActionControlAddress pays Amount of AssetID to Address.
+	ActionControlAddress = "control_address"
+	// ActionIssue issues Amount of a new asset.
+	ActionIssue = "issue"
+)
+
+// maxFeeRounds bounds how many times Build reselects inputs while the fee
+// grows with the inputs it pays for.
+const maxFeeRounds = 10
+
+var (
+	ErrBadAction           = errors.New("unknown action type")
+	ErrBadAddress          = errors.New("invalid address")
+	ErrUnknownAccount      = errors.New("account does not belong to this wallet")
+	ErrNoSpend             = errors.New("transaction spends from no account")
+	ErrIssuanceUnsupported = errors.New("asset issuance is not supported by the transaction format")
+	ErrFeeNotConverged     = errors.New("fee estimate did not converge")
+)
+
+// Action is one step of a transaction built by the wallet.
+type Action struct {
+	Type    string              `json:"type"`
+	Account string              `json:"account,omitempty"` // address of the spending account
+	Address string              `json:"address,omitempty"` // address being paid
+	AssetID transaction.AssetID `json:"asset_id"`
+	Amount  uint64              `json:"amount"`
+}
+
+// UTXOSource returns the outputs paying to the control programs of the
+// account with address account that are still available to spend.
+type UTXOSource func(account string) []*transaction.UTXO
+
+// BuildOptions tune how Build selects the inputs of a transaction.
+type BuildOptions struct {
+	// FeeRate is the native asset fee paid per unit of signed weight.
+	FeeRate uint64
+	// Strategy is the coin selection strategy. Empty selects largest first.
+	Strategy string
+	// DustThreshold is the smallest native asset amount the pool accepts in
+	// an output. Smaller change is left to the fee.
+	DustThreshold uint64
+	// ReserveTTL is how long the selected outputs stay reserved for the
+	// built transaction. Zero reserves nothing.
+	ReserveTTL time.Duration
+}
+
+// BuildResult is an unsigned transaction built from actions, along with the
+// fee it pays and its weight once signed.
+type BuildResult struct {
+	Template *transaction.Template `json:"template"`
+	Fee      uint64                `json:"fee"`
+	Weight   uint64                `json:"weight"`
+
+	// ReservedUntil is when the reservation of the inputs expires, if they
+	// were reserved.
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+}
+
+// spend is the total amount of one asset an account spends.
+type spend struct {
+	account string
+	keys    signers
+	assetID transaction.AssetID
+	amount  uint64
+	change  *CtrlProgram // derived when the spend first needs change
+	// changed is whether the last build of the spend returned change.
+	changed bool
+}
+
+// AddressProgram returns the control program paying to addr, a witness
+// pubkey hash or witness script hash address.
+func AddressProgram(addr string) ([]byte, error) {
+	decoded, err := address.DecodeAddress(addr, *params.ActiveNetParams)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrBadAddress, err)
+	}
+	// P2WSHProgram builds the witness program of either kind of address.
+	witness, ok := decoded.(interface{ WitnessProgram() []byte })
+	if !ok {
+		return nil, ErrBadAddress
+	}
+	return vm.P2WSHProgram(witness.WitnessProgram())
+}
+
+// Build turns actions into an unsigned transaction template. Inputs are
+// taken from utxos, skipping those reserved by other builds, as
+// opts.Strategy selects, and whatever they carry beyond an account's spends
+// returns to the next change program of that account. It is recorded, and
+// the account's change index moved past it, only when the inputs are
+// reserved; a build reserving nothing, such as a fee estimate, uses no change
+// index up. The native asset fee,
+// opts.FeeRate per unit of signed transaction weight, is paid by the first
+// spending account.
+func (am AccountManager) Build(actions []*Action, utxos UTXOSource, opts BuildOptions) (*BuildResult, error) {
+	if err := checkStrategy(opts.Strategy); err != nil {
+		return nil, err
+	}
+	var (
+		spends  []*spend
+		outputs []*transaction.TxOutput
+	)
+	for i, a := range actions {
+		switch a.Type {
+		case ActionSpendAccount:
+			s, err := am.findSpend(&spends, a.Account, a.AssetID)
+			if err != nil {
+				return nil, fmt.Errorf("action %d: %v", i, err)
+			}
+			s.amount += a.Amount
+
+		case ActionControlAddress:
+			program, err := AddressProgram(a.Address)
+			if err != nil {
+				return nil, fmt.Errorf("action %d: %v", i, err)
+			}
+			outputs = append(outputs, transaction.NewTxOutput(a.AssetID, a.Amount, program))
+
+		case ActionIssue:
+			return nil, fmt.Errorf("action %d: %v", i, ErrIssuanceUnsupported)
+
+		default:
+			return nil, fmt.Errorf("action %d: %v %q", i, ErrBadAction, a.Type)
+		}
+	}
+	if len(spends) == 0 {
+		return nil, ErrNoSpend
+	}
+
+	am.reservations.mu.Lock()
+	defer am.reservations.mu.Unlock()
+
+	// The fee is taken from the payer's native asset spend, which is
+	// created if the actions did not include one.
+	payer, err := am.findSpend(&spends, spends[0].account, *transaction.SRCAssetID)
+	if err != nil {
+		return nil, err
+	}
+	// Native asset change up to changeWindow is left to the fee: it would
+	// not pay for its own output, or would be dust.
+	var (
+		fee          uint64
+		changeWindow = changeOutputWeight * opts.FeeRate
+	)
+	if opts.DustThreshold > changeWindow+1 {
+		changeWindow = opts.DustThreshold - 1
+	}
+	for round := 0; round < maxFeeRounds; round++ {
+		payer.amount += fee
+		tpl, tx, err := am.buildSpends(spends, outputs, utxos, opts.Strategy, changeWindow)
+		payer.amount -= fee
+		if err != nil {
+			return nil, err
+		}
+		weight := estimateWeight(tpl)
+		if need := weight * opts.FeeRate; need > fee {
+			fee = need
+			continue
+		}
+		// Change within the window was left to the fee.
+		res := &BuildResult{Template: tpl, Fee: transaction.CalculateTxFee(tx), Weight: weight}
+		if opts.ReserveTTL > 0 {
+			if err := am.saveChangePrograms(spends); err != nil {
+				return nil, err
+			}
+			expiry := am.reservations.reserve(&tpl.Transaction, opts.ReserveTTL)
+			res.ReservedUntil = &expiry
+		}
+		return res, nil
+	}
+	return nil, ErrFeeNotConverged
+}
+
+// saveChangePrograms records the change programs the spends returned change
+// to.
+func (am AccountManager) saveChangePrograms(spends []*spend) error {
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	for _, s := range spends {
+		if s.changed {
+			if err := am.saveChangeProgram(s.change); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findSpend returns the entry of spends for assetID from account, appending
+// one if there is none yet.
+func (am AccountManager) findSpend(spends *[]*spend, account string, assetID transaction.AssetID) (*spend, error) {
+	for _, s := range *spends {
+		if s.account == account && s.assetID == assetID {
+			return s, nil
+		}
+	}
+	keys, _, err := am.accountSigners(account)
+	if err != nil {
+		return nil, err
+	}
+	s := &spend{account: account, keys: keys, assetID: assetID}
+	*spends = append(*spends, s)
+	return s, nil
+}
+
+// buildSpends selects inputs covering spends and builds them, together with
+// outputs and the change of each spend, into a template. Native asset change
+// worth no more than changeWindow is left to the fee instead.
+func (am AccountManager) buildSpends(spends []*spend, outputs []*transaction.TxOutput, utxos UTXOSource, strategy string, changeWindow uint64) (*transaction.Template, *transaction.TxData, error) {
+	var inputs []transaction.InputAndSigInst
+	outputs = append([]*transaction.TxOutput(nil), outputs...)
+	for _, s := range spends {
+		s.changed = false
+		if s.amount == 0 {
+			continue
+		}
+		var candidates []*transaction.UTXO
+		for _, u := range am.reservations.available(utxos(s.account)) {
+			if u.AssetID == s.assetID {
+				candidates = append(candidates, u)
+			}
+		}
+		var window uint64
+		if s.assetID == *transaction.SRCAssetID {
+			window = changeWindow
+		}
+		selected, total, err := selectUTXOs(strategy, candidates, s.amount, window)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, u := range selected {
+			keys, path, err := am.programSigners(s.account, s.keys, u.ControlProgram)
+			if err != nil {
+				return nil, nil, err
+			}
+			input, err := keys.spendInput(u, path)
+			if err != nil {
+				return nil, nil, err
+			}
+			inputs = append(inputs, input)
+		}
+		if change := total - s.amount; change > window {
+			if s.change == nil {
+				am.changeMu.Lock()
+				s.change, err = am.nextChangeProgram(s.account)
+				am.changeMu.Unlock()
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			outputs = append(outputs, transaction.NewTxOutput(s.assetID, change, s.change.ControlProgram))
+			s.changed = true
+		}
+	}
+	tpl, tx, err := transaction.BuildUtxoTemplate(inputs, outputs)
+	return tpl, &tx, err
+}
+
+// estimateWeight returns the weight the transaction of tpl will have once
+// its signing instructions are fulfilled.
+func estimateWeight(tpl *transaction.Template) uint64 {
+	signed := tpl.Transaction.TxData
+	signed.Inputs = make([]*transaction.TxInput, len(signed.Inputs))
+	copy(signed.Inputs, tpl.Transaction.Inputs)
+	for _, sigInst := range tpl.SigningInstructions {
+		in := signed.Inputs[sigInst.Position]
+		sp, ok := in.TypedInput.(*transaction.SpendInput)
+		if !ok {
+			continue
+		}
+		var witness [][]byte
+		for _, wc := range sigInst.WitnessComponents {
+			switch wc := wc.(type) {
+			case *transaction.RawTxSigWitness:
+				for i := 0; i < wc.Quorum; i++ {
+					witness = append(witness, make([]byte, ed25519.SignatureSize))
+				}
+			case transaction.DataWitness:
+				witness = append(witness, wc)
+			}
+		}
+		cpy, spCpy := *in, *sp
+		spCpy.Arguments = witness
+		cpy.TypedInput = &spCpy
+		signed.Inputs[sigInst.Position] = &cpy
+	}
+	return signed.Weight()
+}
@@ -0,0 +1,337 @@
+package account
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+	"github.com/srchain/srcd/database"
+)
+
+type testKeyStore map[string]chainkd.XPrv
+
+func (ks testKeyStore) XPrv(pubHash []byte, password string) (chainkd.XPrv, error) {
+	xprv, ok := ks[string(pubHash)]
+	if !ok || password != "secret" {
+		return chainkd.XPrv{}, ErrWrongKey
+	}
+	return xprv, nil
+}
+
+func (ks testKeyStore) NewXPub(alias, password string) (chainkd.XPub, error) {
+	xprv, err := chainkd.NewXPrv(nil)
+	if err != nil {
+		return chainkd.XPub{}, err
+	}
+	ks[string(ripemd160.Ripemd160(xprv.XPub().PublicKey()))] = xprv
+	return xprv.XPub(), nil
+}
+
+func TestBuildAndSign(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	xprv, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.AddAccount(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am.SetKeyStore(testKeyStore{string(ripemd160.Ripemd160(xpub.PublicKey())): xprv})
+
+	_, payee, _ := chainkd.NewXKeys(rand.Reader)
+	payeeProgram, _, err := CreateP2PKH(payee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := AddressProgram(acc.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos := func(account string) []*transaction.UTXO {
+		if account != acc.Address {
+			return nil
+		}
+		var us []*transaction.UTXO
+		for i, amount := range []uint64{3000, 5000} {
+			us = append(us, &transaction.UTXO{
+				OutputID:       transaction.Hash{V0: uint64(i + 1)},
+				SourceID:       transaction.Hash{V0: uint64(i + 10)},
+				AssetID:        *transaction.SRCAssetID,
+				Amount:         amount,
+				ControlProgram: program,
+				Address:        acc.Address,
+			})
+		}
+		return us
+	}
+
+	actions := []*Action{
+		{Type: ActionSpendAccount, Account: acc.Address, AssetID: *transaction.SRCAssetID, Amount: 4500},
+		{Type: ActionControlAddress, Address: payeeProgram.Address, AssetID: *transaction.SRCAssetID, Amount: 4500},
+	}
+	res, err := am.Build(actions, utxos, BuildOptions{FeeRate: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := res.Template.Transaction
+	if len(tx.Inputs) != 2 || len(tx.Outputs) != 2 {
+		t.Fatalf("built %d inputs and %d outputs, want 2 and 2", len(tx.Inputs), len(tx.Outputs))
+	}
+	if fee := transaction.CalculateTxFee(&tx.TxData); fee != res.Fee {
+		t.Errorf("transaction pays fee %d, build reported %d", fee, res.Fee)
+	}
+	if res.Fee < 2*res.Weight {
+		t.Errorf("fee %d below rate for weight %d", res.Fee, res.Weight)
+	}
+
+	// The template must survive the trip through JSON that RPC callers make.
+	data, err := json.Marshal(res.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := new(transaction.Template)
+	if err := json.Unmarshal(data, tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := am.SignTemplate(tpl, "wrong"); err == nil {
+		t.Fatal("signed with the wrong password")
+	}
+	if err := am.SignTemplate(tpl, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, 0); err != nil {
+		t.Fatalf("signed transaction does not verify: %v", err)
+	}
+	if weight := tpl.Transaction.TxData.Weight(); weight != res.Weight {
+		t.Errorf("signed weight %d, estimated %d", weight, res.Weight)
+	}
+}
+
+func TestBuildDustChange(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.AddAccount(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, payee, _ := chainkd.NewXKeys(rand.Reader)
+	payeeProgram, _, err := CreateP2PKH(payee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := AddressProgram(acc.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos := func(string) []*transaction.UTXO {
+		return []*transaction.UTXO{{
+			OutputID:       transaction.Hash{V0: 1},
+			SourceID:       transaction.Hash{V0: 10},
+			AssetID:        *transaction.SRCAssetID,
+			Amount:         10000,
+			ControlProgram: program,
+			Address:        acc.Address,
+		}}
+	}
+	build := func(amount, dust uint64) *BuildResult {
+		actions := []*Action{
+			{Type: ActionSpendAccount, Account: acc.Address, AssetID: *transaction.SRCAssetID, Amount: amount},
+			{Type: ActionControlAddress, Address: payeeProgram.Address, AssetID: *transaction.SRCAssetID, Amount: amount},
+		}
+		res, err := am.Build(actions, utxos, BuildOptions{FeeRate: 1, DustThreshold: dust})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// Leave change worth more than its own output but less than the dust
+	// threshold.
+	amount := 10000 - build(5000, 0).Fee - 600
+	if res := build(amount, 0); len(res.Template.Transaction.Outputs) != 2 {
+		t.Fatalf("change of 600 at fee rate 1 not returned: %d outputs", len(res.Template.Transaction.Outputs))
+	}
+	res := build(amount, 1000)
+	if outputs := res.Template.Transaction.Outputs; len(outputs) != 1 {
+		t.Fatalf("dust change returned: %d outputs", len(outputs))
+	}
+	if res.Fee != 10000-amount {
+		t.Errorf("fee %d, want the dust change folded in: %d", res.Fee, 10000-amount)
+	}
+}
+
+func TestBuildInsufficientFunds(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, _ := am.AddAccount(xpub)
+
+	actions := []*Action{
+		{Type: ActionSpendAccount, Account: acc.Address, AssetID: *transaction.SRCAssetID, Amount: 1},
+	}
+	none := func(string) []*transaction.UTXO { return nil }
+	if _, err := am.Build(actions, none, BuildOptions{FeeRate: 1}); err != ErrInsufficientFunds {
+		t.Fatalf("Build error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	actions[0].Type = ActionIssue
+	if _, err := am.Build(actions, none, BuildOptions{FeeRate: 1}); err == nil {
+		t.Fatal("issuance built")
+	}
+}
+
+func TestChangeAndReservation(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	xprv, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.AddAccount(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am.SetKeyStore(testKeyStore{string(ripemd160.Ripemd160(xpub.PublicKey())): xprv})
+	program, _ := AddressProgram(acc.Address)
+	_, payee, _ := chainkd.NewXKeys(rand.Reader)
+	payeeProgram, _, _ := CreateP2PKH(payee)
+
+	// Reservations hold the output IDs spend inputs commit to, so the
+	// outputs come from a real transaction.
+	fund := transaction.NewTx(transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 10000, 0, []byte{0x51}),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, 5000, program),
+			transaction.NewTxOutput(*transaction.SRCAssetID, 5000, program),
+		},
+	})
+	owned := []*transaction.UTXO{outputUTXO(t, &fund, 0, acc.Address), outputUTXO(t, &fund, 1, acc.Address)}
+	utxos := func(string) []*transaction.UTXO { return owned }
+	actions := []*Action{
+		{Type: ActionSpendAccount, Account: acc.Address, AssetID: *transaction.SRCAssetID, Amount: 1000},
+		{Type: ActionControlAddress, Address: payeeProgram.Address, AssetID: *transaction.SRCAssetID, Amount: 1000},
+	}
+	opts := BuildOptions{FeeRate: 1, ReserveTTL: time.Minute}
+
+	res, err := am.Build(actions, utxos, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ReservedUntil == nil {
+		t.Fatal("inputs not reserved")
+	}
+	tx := res.Template.Transaction
+	if len(tx.Inputs) != 1 || len(tx.Outputs) != 2 {
+		t.Fatalf("built %d inputs and %d outputs, want 1 and 2", len(tx.Inputs), len(tx.Outputs))
+	}
+	change, err := am.ControlProgram(tx.Outputs[1].ControlProgram)
+	if err != nil {
+		t.Fatalf("change output not recognized: %v", err)
+	}
+	if !change.Change || change.AccountID != acc.Address || bytes.Equal(change.ControlProgram, program) {
+		t.Errorf("change program %+v is not a fresh change program of the account", change)
+	}
+
+	// The reserved output is not selected again until released.
+	again, err := am.Build(actions, utxos, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Template.Transaction.SpentOutputIDs[0] == tx.SpentOutputIDs[0] {
+		t.Error("reserved output selected twice")
+	}
+	if _, err := am.Build(actions, utxos, opts); err != ErrInsufficientFunds {
+		t.Errorf("build with every output reserved: err = %v, want %v", err, ErrInsufficientFunds)
+	}
+	am.ReleaseReservation(&tx)
+	if _, err := am.Build(actions, utxos, opts); err != nil {
+		t.Errorf("build after release: %v", err)
+	}
+
+	// Change outputs are spent with the derived change key.
+	changeUTXO := outputUTXO(t, &tx, 1, change.Address)
+	res, err = am.Build(actions, func(string) []*transaction.UTXO { return []*transaction.UTXO{changeUTXO} }, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := am.SignTemplate(res.Template, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.VerifyTx(&res.Template.Transaction.TxWrap, 0); err != nil {
+		t.Fatalf("transaction signed with change key does not verify: %v", err)
+	}
+	next, err := am.ControlProgram(res.Template.Transaction.Outputs[1].ControlProgram)
+	if err != nil || !next.Change || next.KeyIndex <= change.KeyIndex {
+		t.Errorf("change program reused: %+v, %v", next, err)
+	}
+
+	// Builds reserving nothing, like fee estimates, use no change index up.
+	am.ReleaseReservation(&res.Template.Transaction)
+	var programs [][]byte
+	for i := 0; i < 2; i++ {
+		estimate, err := am.Build(actions, func(string) []*transaction.UTXO { return []*transaction.UTXO{changeUTXO} }, BuildOptions{FeeRate: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		program := estimate.Template.Transaction.Outputs[1].ControlProgram
+		if _, err := am.ControlProgram(program); err != ErrUnknownAccount {
+			t.Errorf("estimate %d recorded its change program: %v", i, err)
+		}
+		programs = append(programs, program)
+	}
+	if cp, err := am.CreateChangeProgram(acc.Address); err != nil || !bytes.Equal(cp.ControlProgram, programs[0]) || !bytes.Equal(cp.ControlProgram, programs[1]) {
+		t.Errorf("estimates moved the change index: next program %+v, %v", cp, err)
+	}
+}
+
+// outputUTXO returns output i of tx, paying to addr.
+func outputUTXO(t *testing.T, tx *transaction.Tx, i int, addr string) *transaction.UTXO {
+	entry, err := tx.Output(*tx.ResultIds[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := tx.Outputs[i]
+	return &transaction.UTXO{
+		OutputID:       *tx.ResultIds[i],
+		SourceID:       *entry.Source.Ref,
+		AssetID:        *out.AssetId,
+		Amount:         out.Amount,
+		SourcePos:      entry.Source.Position,
+		ControlProgram: out.ControlProgram,
+		Address:        addr,
+	}
+}
+
+func TestRecoverPrograms(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.AddAccount(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := am.RecoverPrograms(acc.Address, 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, branch := range []uint64{receiveBranch, changeBranch} {
+		for index := uint64(0); index < 4; index++ {
+			child, _ := xpub.DerivePath(chainkd.IndexPath(branch, index))
+			program, _ := newCtrlProgram(child)
+			cp, err := am.ControlProgram(program.ControlProgram)
+			if index == 3 {
+				if err != ErrUnknownAccount {
+					t.Errorf("program %d/%d past the gap limit recognized: %v", branch, index, err)
+				}
+				continue
+			}
+			if err != nil || cp.AccountID != acc.Address || cp.KeyIndex != index || cp.Change != (branch == changeBranch) {
+				t.Errorf("program %d/%d: %+v, %v", branch, index, cp, err)
+			}
+		}
+	}
+	// New change programs do not reuse the recovered ones.
+	cp, err := am.CreateChangeProgram(acc.Address)
+	if err != nil || cp.KeyIndex != 3 {
+		t.Errorf("change program after recovery: %+v, %v", cp, err)
+	}
+}
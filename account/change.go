@@ -0,0 +1,202 @@
+package account
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+)
+
+var (
+	ProgramPrefix     = []byte("ACCP")
+	ChangeIndexPrefix = []byte("ACCI")
+)
+
+// changeBranch is the first step of the derivation path of change keys,
+// m/1/index below the account key.
+const changeBranch = 1
+
+func changePath(index uint64) chainkd.Path {
+	return chainkd.IndexPath(changeBranch, index)
+}
+
+// CreateChangeProgram derives a fresh change key of the account with address
+// accountAddr and returns the control program paying to it. The wallet
+// recognizes outputs paying to it as the account's.
+func (am AccountManager) CreateChangeProgram(accountAddr string) (*CtrlProgram, error) {
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	cp, err := am.nextChangeProgram(accountAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := am.saveChangeProgram(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// nextChangeProgram derives the change program CreateChangeProgram would
+// create next for the account with address accountAddr, without recording
+// it. The caller holds changeMu.
+func (am AccountManager) nextChangeProgram(accountAddr string) (*CtrlProgram, error) {
+	root, program, err := am.accountSigners(accountAddr)
+	if err != nil {
+		return nil, err
+	}
+	index := am.changeIndex(program)
+	child, err := root.derive(changePath(index))
+	if err != nil {
+		return nil, err
+	}
+	cp, err := child.ctrlProgram()
+	if err != nil {
+		return nil, err
+	}
+	cp.AccountID = accountAddr
+	cp.KeyIndex = index
+	cp.Change = true
+	return cp, nil
+}
+
+// saveChangeProgram records cp, a program from nextChangeProgram, and moves
+// the change index of its account past it. The caller holds changeMu.
+func (am AccountManager) saveChangeProgram(cp *CtrlProgram) error {
+	if err := am.putDerivedProgram(cp); err != nil {
+		return err
+	}
+	return am.useChangeIndex(cp)
+}
+
+// useChangeIndex moves the change index of the account of cp, a change
+// program, past it, and derives the change window of a watch-only account
+// further. The caller holds changeMu.
+func (am AccountManager) useChangeIndex(cp *CtrlProgram) error {
+	program, err := AddressProgram(cp.AccountID)
+	if err != nil {
+		return err
+	}
+	if am.changeIndex(program) > cp.KeyIndex {
+		return nil
+	}
+	if err := am.putChangeIndex(program, cp.KeyIndex+1); err != nil {
+		return err
+	}
+	if acc, ok := am.WatchAccount(cp.AccountID); ok {
+		return am.derivePrograms(acc)
+	}
+	return nil
+}
+
+// RecoverPrograms records the first gapLimit receive and change programs of
+// the account with address accountAddr, so that the wallet recognizes the
+// outputs paid to them before the account was recovered. A zero gapLimit
+// selects DefaultGapLimit. Change programs created later come after the
+// recovered ones.
+func (am AccountManager) RecoverPrograms(accountAddr string, gapLimit uint64) error {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	root, program, err := am.accountSigners(accountAddr)
+	if err != nil {
+		return err
+	}
+
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	for _, branch := range []uint64{receiveBranch, changeBranch} {
+		for index := uint64(0); index < gapLimit; index++ {
+			child, err := root.derive(chainkd.IndexPath(branch, index))
+			if err != nil {
+				return err
+			}
+			cp, err := child.ctrlProgram()
+			if err != nil {
+				return err
+			}
+			cp.AccountID = accountAddr
+			cp.KeyIndex = index
+			cp.Change = branch == changeBranch
+			if err := am.putDerivedProgram(cp); err != nil {
+				return err
+			}
+		}
+	}
+	if am.changeIndex(program) < gapLimit {
+		return am.putChangeIndex(program, gapLimit)
+	}
+	return nil
+}
+
+// changeIndex returns the index of the next change key of the account with
+// control program program. The caller holds changeMu.
+func (am AccountManager) changeIndex(program []byte) uint64 {
+	data, _ := am.db.Get(changeIndexKey(program))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// putChangeIndex saves index as the index of the next change key of the
+// account with control program program. The caller holds changeMu.
+func (am AccountManager) putChangeIndex(program []byte, index uint64) error {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], index)
+	return am.db.Put(changeIndexKey(program), data[:])
+}
+
+func changeIndexKey(program []byte) []byte {
+	return append(append([]byte{}, ChangeIndexPrefix...), program[2:]...)
+}
+
+// putDerivedProgram records cp, a program derived from the keys of its
+// account, so that outputs paying to it are recognized.
+func (am AccountManager) putDerivedProgram(cp *CtrlProgram) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return am.db.Put(append(append([]byte{}, ProgramPrefix...), cp.ControlProgram...), data)
+}
+
+// derivedProgram returns the derived control program the wallet recorded
+// for program, if there is one.
+func (am AccountManager) derivedProgram(program []byte) (*CtrlProgram, bool) {
+	data, err := am.db.Get(append(append([]byte{}, ProgramPrefix...), program...))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	cp := new(CtrlProgram)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, false
+	}
+	return cp, true
+}
+
+// programPath returns the derivation path of the keys of cp below the keys
+// of its account.
+func programPath(cp *CtrlProgram) chainkd.Path {
+	if cp.Change {
+		return changePath(cp.KeyIndex)
+	}
+	return chainkd.IndexPath(receiveBranch, cp.KeyIndex)
+}
+
+// programSigners returns the signers of program, one of the programs of
+// account, whose own signers are root, along with the path deriving them
+// from root.
+func (am AccountManager) programSigners(account string, root signers, program []byte) (signers, chainkd.Path, error) {
+	cp, ok := am.derivedProgram(program)
+	if !ok {
+		return root, nil, nil
+	}
+	if cp.AccountID != account {
+		return signers{}, nil, ErrUnknownAccount
+	}
+	path := programPath(cp)
+	s, err := root.derive(path)
+	return s, path, err
+}
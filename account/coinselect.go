@@ -0,0 +1,139 @@
+package account
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/errors"
+)
+
+// Coin selection strategies understood by Build.
+const (
+	// SelectLargestFirst spends the largest outputs first, using as few
+	// inputs as possible. It is the default.
+	SelectLargestFirst = "largest_first"
+	// SelectBranchAndBound looks for outputs adding up to the amount closely
+	// enough that no change output is needed, and falls back to largest
+	// first when there are none.
+	SelectBranchAndBound = "branch_and_bound"
+	// SelectRandom spends outputs in random order, so that the inputs of a
+	// transaction reveal less about the rest of the wallet.
+	SelectRandom = "random"
+)
+
+// maxBranchAndBoundTries bounds the number of subsets branch and bound
+// explores before giving up.
+const maxBranchAndBoundTries = 100000
+
+var ErrBadStrategy = errors.New("unknown coin selection strategy")
+
+// changeOutputWeight is the weight a change output adds to a transaction.
+var changeOutputWeight = func() uint64 {
+	var tx transaction.TxData
+	base := tx.Weight()
+	tx.Outputs = []*transaction.TxOutput{
+		transaction.NewTxOutput(*transaction.SRCAssetID, math.MaxInt64, make([]byte, 22)),
+	}
+	return tx.Weight() - base
+}()
+
+func checkStrategy(strategy string) error {
+	switch strategy {
+	case "", SelectLargestFirst, SelectBranchAndBound, SelectRandom:
+		return nil
+	}
+	return fmt.Errorf("%v %q", ErrBadStrategy, strategy)
+}
+
+// selectUTXOs picks outputs among candidates, all of one asset, adding up to
+// at least amount according to strategy, and returns them with their total.
+// Branch and bound accepts totals up to window above amount.
+func selectUTXOs(strategy string, candidates []*transaction.UTXO, amount, window uint64) ([]*transaction.UTXO, uint64, error) {
+	candidates = append([]*transaction.UTXO(nil), candidates...)
+	switch strategy {
+	case SelectBranchAndBound:
+		if selected, total := branchAndBound(candidates, amount, window); selected != nil {
+			return selected, total, nil
+		}
+		sortLargestFirst(candidates)
+	case SelectRandom:
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	default:
+		sortLargestFirst(candidates)
+	}
+
+	var (
+		selected []*transaction.UTXO
+		total    uint64
+	)
+	for _, u := range candidates {
+		if total >= amount {
+			break
+		}
+		selected = append(selected, u)
+		total += u.Amount
+	}
+	if total < amount {
+		return nil, 0, ErrInsufficientFunds
+	}
+	return selected, total, nil
+}
+
+func sortLargestFirst(utxos []*transaction.UTXO) {
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount })
+}
+
+// branchAndBound searches the subsets of candidates, largest outputs first,
+// for the one whose total exceeds amount by the least, but by no more than
+// window. It returns nil if there is none.
+func branchAndBound(candidates []*transaction.UTXO, amount, window uint64) ([]*transaction.UTXO, uint64) {
+	sortLargestFirst(candidates)
+	// remaining[i] is the total of candidates[i:].
+	remaining := make([]uint64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].Amount
+	}
+
+	var (
+		included   = make([]bool, len(candidates))
+		best       []*transaction.UTXO
+		bestTotal  uint64
+		bestExcess uint64 = math.MaxUint64
+		tries      int
+		search     func(i int, total uint64)
+	)
+	search = func(i int, total uint64) {
+		if tries >= maxBranchAndBoundTries || bestExcess == 0 {
+			return
+		}
+		tries++
+		if total >= amount {
+			if excess := total - amount; excess <= window && excess < bestExcess {
+				best = best[:0]
+				for j, in := range included[:i] {
+					if in {
+						best = append(best, candidates[j])
+					}
+				}
+				bestTotal, bestExcess = total, excess
+			}
+			return
+		}
+		if i == len(candidates) || total+remaining[i] < amount {
+			return
+		}
+		included[i] = true
+		search(i+1, total+candidates[i].Amount)
+		included[i] = false
+		search(i+1, total)
+	}
+	search(0, 0)
+
+	if bestExcess == math.MaxUint64 {
+		return nil, 0
+	}
+	return best, bestTotal
+}
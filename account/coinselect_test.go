@@ -0,0 +1,63 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/srchain/srcd/core/transaction"
+)
+
+func testUTXOs(amounts ...uint64) []*transaction.UTXO {
+	var utxos []*transaction.UTXO
+	for i, amount := range amounts {
+		utxos = append(utxos, &transaction.UTXO{OutputID: transaction.Hash{V0: uint64(i + 1)}, Amount: amount})
+	}
+	return utxos
+}
+
+func TestSelectUTXOs(t *testing.T) {
+	utxos := testUTXOs(1000, 700, 400, 300, 50)
+	tests := []struct {
+		strategy string
+		amount   uint64
+		window   uint64
+		want     uint64 // total of the selected outputs
+	}{
+		{SelectLargestFirst, 1050, 0, 1700},
+		{SelectBranchAndBound, 1050, 0, 1050},
+		{SelectBranchAndBound, 1100, 0, 1100},
+		{SelectBranchAndBound, 1090, 20, 1100},
+		// Nothing within the window: fall back to largest first.
+		{SelectBranchAndBound, 2401, 0, 2450},
+		{SelectBranchAndBound, 1001, 0, 1700},
+	}
+	for _, test := range tests {
+		_, total, err := selectUTXOs(test.strategy, utxos, test.amount, test.window)
+		if err != nil {
+			t.Errorf("%s %d: %v", test.strategy, test.amount, err)
+			continue
+		}
+		if total != test.want {
+			t.Errorf("%s %d: selected %d, want %d", test.strategy, test.amount, total, test.want)
+		}
+	}
+
+	for _, strategy := range []string{SelectLargestFirst, SelectBranchAndBound, SelectRandom} {
+		selected, total, err := selectUTXOs(strategy, utxos, 1500, 0)
+		if err != nil || total < 1500 {
+			t.Errorf("%s: selected %d, %v", strategy, total, err)
+		}
+		var sum uint64
+		for _, u := range selected {
+			sum += u.Amount
+		}
+		if sum != total {
+			t.Errorf("%s: reported total %d, selected %d", strategy, total, sum)
+		}
+		if _, _, err := selectUTXOs(strategy, utxos, 2451, 0); err != ErrInsufficientFunds {
+			t.Errorf("%s: err = %v, want %v", strategy, err, ErrInsufficientFunds)
+		}
+	}
+	if err := checkStrategy("knapsack"); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
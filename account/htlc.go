@@ -0,0 +1,131 @@
+package account
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/errors"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrNotHTLCRecipient  = errors.New("key is not the htlc recipient")
+	ErrNotHTLCSender     = errors.New("key is not the htlc sender")
+	ErrWrongPreimage     = errors.New("preimage does not match the htlc secret hash")
+	ErrNoPreimage        = errors.New("no htlc preimage revealed in transaction")
+	ErrNotUTXOOwner      = errors.New("utxo is not paid to the key")
+	ErrPreimageSize      = errors.New("htlc preimage has the wrong size")
+)
+
+// BuildHTLCInitiate builds a transaction locking amount of utxo, a P2WPKH
+// output owned by xpub, into the HTLC described by contract. Whatever is left
+// after amount and fee is returned to changeProgram.
+func BuildHTLCInitiate(xpub chainkd.XPub, utxo *transaction.UTXO, contract *vm.HTLC, amount, fee uint64, changeProgram []byte) (*transaction.Template, error) {
+	if amount+fee < amount || amount+fee > utxo.Amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	program, err := vm.HTLCProgram(contract)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := singleSigner(xpub)
+	owner, err := signer.ctrlProgram()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(owner.ControlProgram, utxo.ControlProgram) {
+		return nil, ErrNotUTXOOwner
+	}
+	input, err := signer.spendInput(utxo, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []*transaction.TxOutput{transaction.NewTxOutput(utxo.AssetID, amount, program)}
+	if change := utxo.Amount - amount - fee; change > 0 {
+		outputs = append(outputs, transaction.NewTxOutput(utxo.AssetID, change, changeProgram))
+	}
+
+	tpl, _, err := transaction.BuildUtxoTemplate([]transaction.InputAndSigInst{input}, outputs)
+	return tpl, err
+}
+
+// BuildHTLCRedeem builds a transaction claiming the HTLC output utxo for the
+// recipient xpub by revealing preimage, which must be vm.HTLCPreimageSize
+// bytes long. The funds, less fee, are paid to
+// receiver.
+func BuildHTLCRedeem(xpub chainkd.XPub, utxo *transaction.UTXO, preimage []byte, fee uint64, receiver []byte) (*transaction.Template, error) {
+	contract, err := vm.ParseHTLCProgram(utxo.ControlProgram)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(contract.Recipient, xpub.PublicKey()) {
+		return nil, ErrNotHTLCRecipient
+	}
+	if len(preimage) != vm.HTLCPreimageSize {
+		return nil, ErrPreimageSize
+	}
+	if h := sha256.Sum256(preimage); !bytes.Equal(h[:], contract.SecretHash) {
+		return nil, ErrWrongPreimage
+	}
+
+	sigInst := &transaction.SigningInstruction{}
+	sigInst.WitnessComponents = append(sigInst.WitnessComponents,
+		transaction.NewRawTxSigWitness(1, []chainkd.XPub{xpub}),
+		transaction.DataWitness(preimage),
+		transaction.DataWitness(vm.HTLCRedeemClause),
+	)
+	return buildHTLCSpend(utxo, sigInst, fee, receiver)
+}
+
+// BuildHTLCRefund builds a transaction returning the HTLC output utxo to the
+// sender xpub once the contract has timed out. The funds, less fee, are paid
+// to receiver.
+func BuildHTLCRefund(xpub chainkd.XPub, utxo *transaction.UTXO, fee uint64, receiver []byte) (*transaction.Template, error) {
+	contract, err := vm.ParseHTLCProgram(utxo.ControlProgram)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(contract.Sender, xpub.PublicKey()) {
+		return nil, ErrNotHTLCSender
+	}
+
+	sigInst := &transaction.SigningInstruction{}
+	sigInst.WitnessComponents = append(sigInst.WitnessComponents,
+		transaction.NewRawTxSigWitness(1, []chainkd.XPub{xpub}),
+		transaction.DataWitness(vm.HTLCRefundClause),
+	)
+	return buildHTLCSpend(utxo, sigInst, fee, receiver)
+}
+
+func buildHTLCSpend(utxo *transaction.UTXO, sigInst *transaction.SigningInstruction, fee uint64, receiver []byte) (*transaction.Template, error) {
+	if fee >= utxo.Amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	input := transaction.NewSpendInput(nil, utxo.SourceID, utxo.AssetID, utxo.Amount, utxo.SourcePos, utxo.ControlProgram)
+	outputs := []*transaction.TxOutput{transaction.NewTxOutput(utxo.AssetID, utxo.Amount-fee, receiver)}
+
+	tpl, _, err := transaction.BuildUtxoTemplate([]transaction.InputAndSigInst{transaction.NewInputAndSigInst(input, sigInst)}, outputs)
+	return tpl, err
+}
+
+// ExtractHTLCPreimage returns the preimage of secretHash revealed by a
+// redeeming input of tx.
+func ExtractHTLCPreimage(tx *transaction.Tx, secretHash []byte) ([]byte, error) {
+	for _, in := range tx.Inputs {
+		contract, err := vm.ParseHTLCProgram(in.ControlProgram())
+		if err != nil || !bytes.Equal(contract.SecretHash, secretHash) {
+			continue
+		}
+		if preimage, err := vm.HTLCPreimage(contract, in.Arguments()); err == nil {
+			return preimage, nil
+		}
+	}
+	return nil, ErrNoPreimage
+}
@@ -0,0 +1,111 @@
+package account
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+)
+
+func TestHTLCSwap(t *testing.T) {
+	senderPrv, sender, _ := chainkd.NewXKeys(rand.Reader)
+	recipientPrv, recipient, _ := chainkd.NewXKeys(rand.Reader)
+	senderProgram, _ := newCtrlProgram(sender)
+	recipientProgram, _ := newCtrlProgram(recipient)
+
+	preimage := make([]byte, vm.HTLCPreimageSize)
+	rand.Read(preimage)
+	hash := sha256.Sum256(preimage)
+	contract := &vm.HTLC{
+		SecretHash: hash[:],
+		Recipient:  recipient.PublicKey(),
+		Sender:     sender.PublicKey(),
+		Timeout:    100,
+	}
+
+	fund := transaction.NewTx(transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 10000, 0, []byte{0x51}),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, 10000, senderProgram.ControlProgram),
+		},
+	})
+	funds := outputUTXO(t, &fund, 0, senderProgram.Address)
+
+	if _, err := BuildHTLCInitiate(recipient, funds, contract, 6000, 100, senderProgram.ControlProgram); err != ErrNotUTXOOwner {
+		t.Fatalf("initiate with another key: err = %v, want %v", err, ErrNotUTXOOwner)
+	}
+	tpl, err := BuildHTLCInitiate(sender, funds, contract, 6000, 100, senderProgram.ControlProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.Sign(tpl, senderPrv); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, 0); err != nil {
+		t.Fatalf("initiate does not verify: %v", err)
+	}
+	initiate := tpl.Transaction
+	if len(initiate.Outputs) != 2 || initiate.Outputs[1].Amount != 3900 {
+		t.Fatalf("initiate outputs mismatch: %+v", initiate.Outputs)
+	}
+	locked := outputUTXO(t, &initiate, 0, "")
+
+	// The recipient redeems before the timeout, revealing the preimage.
+	if _, err := BuildHTLCRedeem(sender, locked, preimage, 100, senderProgram.ControlProgram); err != ErrNotHTLCRecipient {
+		t.Errorf("redeem by sender: err = %v, want %v", err, ErrNotHTLCRecipient)
+	}
+	if _, err := BuildHTLCRedeem(recipient, locked, preimage[1:], 100, recipientProgram.ControlProgram); err != ErrPreimageSize {
+		t.Errorf("redeem with short preimage: err = %v, want %v", err, ErrPreimageSize)
+	}
+	if _, err := BuildHTLCRedeem(recipient, locked, make([]byte, vm.HTLCPreimageSize), 100, recipientProgram.ControlProgram); err != ErrWrongPreimage {
+		t.Errorf("redeem with wrong preimage: err = %v, want %v", err, ErrWrongPreimage)
+	}
+	tpl, err = BuildHTLCRedeem(recipient, locked, preimage, 100, recipientProgram.ControlProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.Sign(tpl, recipientPrv); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, contract.Timeout-1); err != nil {
+		t.Fatalf("redeem does not verify: %v", err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, contract.Timeout); err == nil {
+		t.Error("redeem verifies after the timeout")
+	}
+	revealed, err := ExtractHTLCPreimage(&tpl.Transaction, contract.SecretHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(revealed, preimage) {
+		t.Errorf("extracted preimage %x, want %x", revealed, preimage)
+	}
+
+	// The sender refunds from the timeout on, revealing nothing.
+	if _, err := BuildHTLCRefund(recipient, locked, 100, recipientProgram.ControlProgram); err != ErrNotHTLCSender {
+		t.Errorf("refund by recipient: err = %v, want %v", err, ErrNotHTLCSender)
+	}
+	tpl, err = BuildHTLCRefund(sender, locked, 100, senderProgram.ControlProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.Sign(tpl, senderPrv); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, contract.Timeout); err != nil {
+		t.Fatalf("refund does not verify: %v", err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, contract.Timeout-1); err == nil {
+		t.Error("refund verifies before the timeout")
+	}
+	if _, err := ExtractHTLCPreimage(&tpl.Transaction, contract.SecretHash); err != ErrNoPreimage {
+		t.Errorf("extract from refund: err = %v, want %v", err, ErrNoPreimage)
+	}
+}
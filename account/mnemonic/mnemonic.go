@@ -0,0 +1,212 @@
+// Package mnemonic encodes wallet seeds as phrases of words, following
+// BIP-39: the entropy and a checksum of it are split into 11-bit word
+// indexes, and the phrase is stretched into a seed with PBKDF2.
+package mnemonic
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/errors"
+	"github.com/tendermint/go-crypto/keys/wordlist"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DefaultLanguage is the language of the wordlist used when none is given.
+	DefaultLanguage = "english"
+
+	// DefaultEntropyBits is the size of the entropy behind new phrases,
+	// giving 12 words.
+	DefaultEntropyBits = 128
+
+	wordBits        = 11
+	seedIterations  = 2048
+	seedLength      = 64
+	seedSaltPrefix  = "mnemonic"
+	wordlistEntries = 1 << wordBits
+)
+
+// Languages are the languages of the available wordlists.
+var Languages = []string{"chinese_simplified", "english", "japanese", "spanish"}
+
+var (
+	ErrEntropyLength = errors.New("entropy must be 128 to 256 bits, a multiple of 32")
+	ErrWordCount     = errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrUnknownWord   = errors.New("word not in the wordlist")
+	ErrChecksum      = errors.New("mnemonic checksum mismatch")
+	ErrLanguage      = errors.New("unknown mnemonic language")
+)
+
+type wordList struct {
+	words []string
+	index map[string]int
+}
+
+var (
+	wordListsMu sync.Mutex
+	wordLists   = make(map[string]*wordList)
+)
+
+// getWordList loads the wordlist of language from the vendored assets.
+func getWordList(language string) (*wordList, error) {
+	wordListsMu.Lock()
+	defer wordListsMu.Unlock()
+
+	if wl, ok := wordLists[language]; ok {
+		return wl, nil
+	}
+	data, err := wordlist.Asset("keys/wordlist/" + language + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %q", ErrLanguage, language)
+	}
+	words := strings.Fields(string(data))
+	if len(words) != wordlistEntries {
+		return nil, fmt.Errorf("wordlist %s has %d words, want %d", language, len(words), wordlistEntries)
+	}
+	wl := &wordList{words: words, index: make(map[string]int, len(words))}
+	for i, w := range words {
+		wl.index[norm.NFKD.String(w)] = i
+	}
+	wordLists[language] = wl
+	return wl, nil
+}
+
+// NewEntropy returns bits of random entropy for a new phrase.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+func checkEntropyBits(bits int) error {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return ErrEntropyLength
+	}
+	return nil
+}
+
+// New encodes entropy, followed by the first bits of its SHA-256 hash as a
+// checksum, as a phrase of words from the wordlist of language.
+func New(entropy []byte, language string) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropyBits(bits); err != nil {
+		return "", err
+	}
+	wl, err := getWordList(language)
+	if err != nil {
+		return "", err
+	}
+
+	checksumBits := uint(bits / 32)
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	words := make([]string, (bits+int(checksumBits))/wordBits)
+	mask := big.NewInt(wordlistEntries - 1)
+	for i := len(words) - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask)
+		words[i] = wl.words[index.Int64()]
+		data.Rsh(data, wordBits)
+	}
+
+	sep := " "
+	if language == "japanese" {
+		sep = "　"
+	}
+	return strings.Join(words, sep), nil
+}
+
+// Entropy decodes a phrase of words from the wordlist of language back into
+// the entropy it encodes, verifying its checksum.
+func Entropy(mnemonic, language string) ([]byte, error) {
+	wl, err := getWordList(language)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, ErrWordCount
+	}
+
+	data := new(big.Int)
+	for _, w := range words {
+		index, ok := wl.index[w]
+		if !ok {
+			return nil, fmt.Errorf("%v: %q", ErrUnknownWord, w)
+		}
+		data.Lsh(data, wordBits)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := uint(len(words) * wordBits / 33)
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, checksumBits)
+
+	entropy := make([]byte, int(checksumBits)*32/8)
+	dataBytes := data.Bytes()
+	copy(entropy[len(entropy)-len(dataBytes):], dataBytes)
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// DetectLanguage returns the language of the wordlist holding every word of
+// mnemonic.
+func DetectLanguage(mnemonic string) (string, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	for _, language := range Languages {
+		wl, err := getWordList(language)
+		if err != nil {
+			return "", err
+		}
+		found := len(words) > 0
+		for _, w := range words {
+			if _, ok := wl.index[w]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return language, nil
+		}
+	}
+	return "", ErrUnknownWord
+}
+
+// Seed stretches mnemonic, protected by an optional passphrase, into a
+// 64-byte seed. It does not check the phrase is valid; Entropy does.
+func Seed(mnemonic, passphrase string) []byte {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	password := []byte(strings.Join(words, " "))
+	salt := []byte(norm.NFKD.String(seedSaltPrefix + passphrase))
+	return pbkdf2.Key(password, salt, seedIterations, seedLength, sha512.New)
+}
+
+// RootXPrv checks mnemonic is a valid phrase in language and returns the
+// root key of the seed it stretches into.
+func RootXPrv(mnemonic, passphrase, language string) (chainkd.XPrv, error) {
+	if _, err := Entropy(mnemonic, language); err != nil {
+		return chainkd.XPrv{}, err
+	}
+	return chainkd.RootXPrv(Seed(mnemonic, passphrase)), nil
+}
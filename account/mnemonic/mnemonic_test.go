@@ -0,0 +1,114 @@
+package mnemonic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Vectors from the BIP-39 reference implementation, with passphrase TREZOR.
+var vectors = []struct {
+	entropy, mnemonic, seed string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := New(entropy, DefaultLanguage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != v.mnemonic {
+			t.Errorf("New(%s) = %q, want %q", v.entropy, mnemonic, v.mnemonic)
+		}
+		decoded, err := Entropy(v.mnemonic, DefaultLanguage)
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("Entropy(%q) = %x, %v, want %s", v.mnemonic, decoded, err, v.entropy)
+		}
+		if seed := hex.EncodeToString(Seed(v.mnemonic, "TREZOR")); seed != v.seed {
+			t.Errorf("Seed(%q) = %s, want %s", v.mnemonic, seed, v.seed)
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	for _, language := range Languages {
+		entropy, err := NewEntropy(256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mnemonic, err := New(entropy, language)
+		if err != nil {
+			t.Fatalf("%s: %v", language, err)
+		}
+		if n := len(strings.Fields(mnemonic)); n != 24 {
+			t.Errorf("%s: %d words, want 24", language, n)
+		}
+		if detected, err := DetectLanguage(mnemonic); err != nil || detected != language {
+			t.Errorf("%s: detected %q, %v", language, detected, err)
+		}
+		decoded, err := Entropy(mnemonic, language)
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("%s: round trip gave %x, %v, want %x", language, decoded, err, entropy)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if _, err := NewEntropy(100); err != ErrEntropyLength {
+		t.Errorf("NewEntropy(100): err = %v, want %v", err, ErrEntropyLength)
+	}
+	if _, err := New(make([]byte, 15), DefaultLanguage); err != ErrEntropyLength {
+		t.Errorf("New with 120 bits: err = %v, want %v", err, ErrEntropyLength)
+	}
+	if _, err := New(make([]byte, 16), "klingon"); err == nil {
+		t.Error("unknown language accepted")
+	}
+
+	valid := vectors[0].mnemonic
+	if _, err := Entropy(strings.Replace(valid, "about", "abandon", 1), DefaultLanguage); err != ErrChecksum {
+		t.Errorf("bad checksum: err = %v, want %v", err, ErrChecksum)
+	}
+	if _, err := Entropy(valid+" about", DefaultLanguage); err != ErrWordCount {
+		t.Errorf("13 words: err = %v, want %v", err, ErrWordCount)
+	}
+	if _, err := Entropy(strings.Replace(valid, "about", "aboot", 1), DefaultLanguage); err == nil {
+		t.Error("unknown word accepted")
+	}
+	if _, err := RootXPrv(strings.Replace(valid, "about", "abandon", 1), "", DefaultLanguage); err != ErrChecksum {
+		t.Errorf("root key of bad phrase: err = %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestRootXPrv(t *testing.T) {
+	v := vectors[1]
+	xprv, err := RootXPrv("  "+strings.ToLower(v.mnemonic)+"\n", "TREZOR", DefaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := RootXPrv(v.mnemonic, "", DefaultLanguage)
+	if xprv == other {
+		t.Error("passphrase does not change the root key")
+	}
+	again, _ := RootXPrv(v.mnemonic, "TREZOR", DefaultLanguage)
+	if xprv != again {
+		t.Error("root key is not deterministic")
+	}
+}
@@ -0,0 +1,65 @@
+package account
+
+import (
+	"sync"
+	"time"
+
+	"github.com/srchain/srcd/core/transaction"
+)
+
+// DefaultReserveTTL is how long the outputs a built transaction spends stay
+// reserved for it when the caller does not say.
+const DefaultReserveTTL = 2 * time.Minute
+
+// reserver keeps the outputs selected by recent builds from being selected
+// again until their transaction is submitted or the reservation expires.
+type reserver struct {
+	mu       sync.Mutex // held for a whole build, so selection and reservation are atomic
+	reserved map[transaction.Hash]time.Time
+	now      func() time.Time
+}
+
+func newReserver() *reserver {
+	return &reserver{
+		reserved: make(map[transaction.Hash]time.Time),
+		now:      time.Now,
+	}
+}
+
+// available returns the outputs of utxos not reserved, dropping expired
+// reservations. The caller holds mu.
+func (r *reserver) available(utxos []*transaction.UTXO) []*transaction.UTXO {
+	now := r.now()
+	var free []*transaction.UTXO
+	for _, u := range utxos {
+		if expiry, ok := r.reserved[u.OutputID]; ok {
+			if now.Before(expiry) {
+				continue
+			}
+			delete(r.reserved, u.OutputID)
+		}
+		free = append(free, u)
+	}
+	return free
+}
+
+// reserve reserves the outputs spent by tx for ttl and returns when the
+// reservation expires. The caller holds mu.
+func (r *reserver) reserve(tx *transaction.Tx, ttl time.Duration) time.Time {
+	expiry := r.now().Add(ttl)
+	for _, id := range tx.SpentOutputIDs {
+		r.reserved[id] = expiry
+	}
+	return expiry
+}
+
+// ReleaseReservation frees the outputs tx spends for other builds, for
+// when a built transaction is abandoned before its reservation expires.
+func (am AccountManager) ReleaseReservation(tx *transaction.Tx) {
+	am.reservations.mu.Lock()
+	defer am.reservations.mu.Unlock()
+
+	for _, id := range tx.SpentOutputIDs {
+		delete(am.reservations.reserved, id)
+	}
+}
@@ -0,0 +1,83 @@
+package account
+
+import (
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+)
+
+// signers are the keys controlling the programs of an account: a single key
+// paid through P2WPKH programs, or a quorum of several keys paid through
+// multisig witness script programs.
+type signers struct {
+	xpubs  []chainkd.XPub
+	quorum int
+}
+
+func singleSigner(xpub chainkd.XPub) signers {
+	return signers{xpubs: []chainkd.XPub{xpub}, quorum: 1}
+}
+
+// derive returns the signers derived from s along path.
+func (s signers) derive(path chainkd.Path) (signers, error) {
+	d := signers{xpubs: make([]chainkd.XPub, len(s.xpubs)), quorum: s.quorum}
+	for i, xpub := range s.xpubs {
+		child, err := xpub.DerivePath(path)
+		if err != nil {
+			return signers{}, err
+		}
+		d.xpubs[i] = child
+	}
+	return d, nil
+}
+
+// script returns the multisig script of s, or nil for a single key.
+func (s signers) script() ([]byte, error) {
+	if len(s.xpubs) == 1 {
+		return nil, nil
+	}
+	pubkeys := make([]ed25519.PublicKey, len(s.xpubs))
+	for i, xpub := range s.xpubs {
+		pubkeys[i] = xpub.PublicKey()
+	}
+	return vm.MultisigProgram(pubkeys, s.quorum)
+}
+
+// ctrlProgram returns the control program paying to s.
+func (s signers) ctrlProgram() (*CtrlProgram, error) {
+	if len(s.xpubs) == 1 {
+		return newCtrlProgram(s.xpubs[0])
+	}
+	script, err := s.script()
+	if err != nil {
+		return nil, err
+	}
+	program, err := vm.P2WSHScriptProgram(script)
+	if err != nil {
+		return nil, err
+	}
+	return &CtrlProgram{Address: transaction.ProgramAddress(program), ControlProgram: program}, nil
+}
+
+// spendInput returns the input spending u, which pays to the program of s,
+// along with the instruction for its witness: the signatures, followed by
+// the public key or the multisig script. path, leading from the account's
+// own keys to s, is recorded for signers holding only those.
+func (s signers) spendInput(u *transaction.UTXO, path chainkd.Path) (transaction.InputAndSigInst, error) {
+	data := []byte(s.xpubs[0].PublicKey())
+	if len(s.xpubs) > 1 {
+		script, err := s.script()
+		if err != nil {
+			return transaction.InputAndSigInst{}, err
+		}
+		data = script
+	}
+	input := transaction.NewSpendInput(nil, u.SourceID, u.AssetID, u.Amount, u.SourcePos, u.ControlProgram)
+	sigInst := &transaction.SigningInstruction{}
+	sigInst.WitnessComponents = append(sigInst.WitnessComponents,
+		transaction.NewDerivedRawTxSigWitness(s.quorum, s.xpubs, path.Selectors()),
+		transaction.DataWitness(data),
+	)
+	return transaction.NewInputAndSigInst(input, sigInst), nil
+}
@@ -1,7 +1,7 @@
 package account
 
 import (
-	"github.com/srchain/srcd/account/wallet/address"
+	"github.com/srchain/srcd/common/address"
 	"github.com/srchain/srcd/core/vm"
 	"github.com/srchain/srcd/crypto/ed25519/chainkd"
 	"github.com/srchain/srcd/crypto/ripemd160"
@@ -9,29 +9,31 @@ import (
 )
 
 type CtrlProgram struct {
-	AccountID      string
-	Address        string
-	KeyIndex       uint64
-	ControlProgram []byte
-	Change         bool    // Mark whether this control program is for UTXO change
+	AccountID      string `json:"account_id"`
+	Address        string `json:"address"`
+	KeyIndex       uint64 `json:"key_index"`
+	ControlProgram []byte `json:"control_program"`
+	Change         bool   `json:"change"` // Mark whether this control program is for UTXO change
 }
 
 func CreateP2PKH(xpub chainkd.XPub) (*CtrlProgram, []byte, error) {
-	pubKey := xpub.PublicKey()
-	pubHash := ripemd160.Ripemd160(pubKey)
-
-	address, err := address.NewAddressWitnessPubKeyHash(pubHash, &params.TestNetParams)
+	cp, err := newCtrlProgram(xpub)
 	if err != nil {
 		return nil, nil, err
 	}
+	return cp, cp.ControlProgram[2:], nil
+}
 
-	control, err := vm.P2WSHProgram([]byte(pubHash))
+// newCtrlProgram returns the P2WPKH control program paying to xpub.
+func newCtrlProgram(xpub chainkd.XPub) (*CtrlProgram, error) {
+	pubHash := ripemd160.Ripemd160(xpub.PublicKey())
+	addr, err := address.NewAddressWitnessPubKeyHash(pubHash, params.ActiveNetParams)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-
-	return &CtrlProgram{
-		Address:        address.EncodeAddress(),
-		ControlProgram: control,
-	}, pubHash, nil
+	program, err := vm.P2WSHProgram(pubHash)
+	if err != nil {
+		return nil, err
+	}
+	return &CtrlProgram{Address: addr.EncodeAddress(), ControlProgram: program}, nil
 }
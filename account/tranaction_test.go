@@ -8,6 +8,7 @@ import (
 
 	"github.com/srchain/srcd/crypto/ed25519/chainkd"
 	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
 	"github.com/srchain/srcd/core/vm"
 	"github.com/srchain/srcd/database"
 )
@@ -27,14 +28,28 @@ func mockAM() *AccountManager {
 
 
 func TestCreateAccount(t *testing.T) {
-	am := mockAM()
-	account, e := am.CreateAccount()
-	fmt.Println(account)
-
-	accounts, e := am.GetCurrentNodeAccounts(nil)
-	fmt.Println(accounts)
-	must(e)
+	am := NewAccountManager(database.NewMemDatabase())
+	if _, err := am.CreateAccount("alice", "secret"); err != ErrNoKeyStore {
+		t.Fatalf("create without keystore: err = %v, want %v", err, ErrNoKeyStore)
+	}
+	am.SetKeyStore(testKeyStore{})
+	account, err := am.CreateAccount("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
 
+	program, err := AddressProgram(account.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub, err := am.accountXPub(program[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	xprv, err := am.keys.XPrv(program[2:], "secret")
+	if err != nil || xprv.XPub() != xpub {
+		t.Fatalf("account key not kept in the keystore: %v", err)
+	}
 }
 
 func TestGetAccount(t *testing.T){
@@ -117,7 +132,7 @@ var cliJsonStr = `
 }`
 
 func TestAddTransaction(t *testing.T) {
-	tp := transaction.NewTxPool()
+	tp := txpool.NewTxPool(nil, nil)
 
 	//chain := blockchain.BlockChain{}
 
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/srchain/srcd/core/transaction"
+)
+
+// Balance is the amount of one asset an account holds.
+type Balance struct {
+	AccountID string              `json:"account_id"`
+	AssetID   transaction.AssetID `json:"asset_id"`
+
+	// Confirmed is the total of the account's unspent confirmed outputs.
+	Confirmed uint64 `json:"confirmed"`
+	// Unconfirmed is what the balance will be once the transactions in the
+	// pool are confirmed.
+	Unconfirmed uint64 `json:"unconfirmed"`
+}
+
+type balanceKey struct {
+	account string
+	asset   transaction.AssetID
+}
+
+// Balances returns the balance of every asset held by the account
+// accountID, or by every account of the wallet if accountID is empty,
+// ordered by account and asset.
+func (w *Wallet) Balances(accountID string) ([]*Balance, error) {
+	balances := make(map[balanceKey]*Balance)
+	get := func(utxo *Utxo) *Balance {
+		key := balanceKey{utxo.AccountID, utxo.AssetID}
+		b, ok := balances[key]
+		if !ok {
+			b = &Balance{AccountID: utxo.AccountID, AssetID: utxo.AssetID}
+			balances[key] = b
+		}
+		return b
+	}
+
+	iter := w.db.NewIteratorWithPrefix(utxoPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		utxo := new(Utxo)
+		if err := json.Unmarshal(iter.Value(), utxo); err != nil {
+			return nil, err
+		}
+		if utxo.SpentBy != nil || (accountID != "" && utxo.AccountID != accountID) {
+			continue
+		}
+		b := get(utxo)
+		b.Confirmed += utxo.Amount
+		if _, ok := w.utxokeeper.Spender(utxo.OutputID); !ok {
+			b.Unconfirmed += utxo.Amount
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	for _, utxo := range w.utxokeeper.Unconfirmed() {
+		if accountID == "" || utxo.AccountID == accountID {
+			get(utxo).Unconfirmed += utxo.Amount
+		}
+	}
+
+	res := make([]*Balance, 0, len(balances))
+	for _, b := range balances {
+		res = append(res, b)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].AccountID != res[j].AccountID {
+			return res[i].AccountID < res[j].AccountID
+		}
+		return bytes.Compare(res[i].AssetID.Bytes(), res[j].AssetID.Bytes()) < 0
+	})
+	return res, nil
+}
+
+// UTXOs returns the confirmed outputs of the account accountID that neither
+// the chain nor an unconfirmed transaction spends.
+func (w *Wallet) UTXOs(accountID string) ([]*Utxo, error) {
+	var utxos []*Utxo
+	iter := w.db.NewIteratorWithPrefix(utxoPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		utxo := new(Utxo)
+		if err := json.Unmarshal(iter.Value(), utxo); err != nil {
+			return nil, err
+		}
+		if utxo.SpentBy != nil || utxo.AccountID != accountID {
+			continue
+		}
+		if _, ok := w.utxokeeper.Spender(utxo.OutputID); ok {
+			continue
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, iter.Error()
+}
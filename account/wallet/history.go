@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/log"
+)
+
+// Statuses of the transactions in the history.
+const (
+	// StatusPending marks a transaction waiting in the pool.
+	StatusPending = "pending"
+	// StatusConfirmed marks a transaction in a block of the canonical chain.
+	StatusConfirmed = "confirmed"
+	// StatusReplaced marks an unconfirmed transaction whose inputs a later
+	// unconfirmed transaction spent instead.
+	StatusReplaced = "replaced"
+	// StatusConflicted marks an unconfirmed transaction whose inputs a
+	// confirmed transaction spent.
+	StatusConflicted = "conflicted"
+	// StatusDropped marks an unconfirmed transaction restored after a
+	// restart that the pool refused to take back.
+	StatusDropped = "dropped"
+)
+
+// DefaultHistoryLimit is the page size of History when the filter sets none.
+const DefaultHistoryLimit = 50
+
+var txRecordPrefix = []byte("TXH:")
+
+func txRecordKey(id transaction.Hash) []byte {
+	return append(append([]byte{}, txRecordPrefix...), id.Bytes()...)
+}
+
+// NetAmount is the amount of an asset a transaction moved into an account,
+// negative if it moved out.
+type NetAmount struct {
+	AccountID string              `json:"account_id"`
+	AssetID   transaction.AssetID `json:"asset_id"`
+	Amount    int64               `json:"amount"`
+}
+
+// TxRecord is a transaction touching the wallet's accounts, annotated for
+// statements.
+type TxRecord struct {
+	ID             transaction.Hash `json:"id"`
+	Status         string           `json:"status"`
+	Net            []*NetAmount     `json:"net"`
+	Counterparties []string         `json:"counterparties"` // addresses paying or paid by the wallet
+	Fee            uint64           `json:"fee"`
+
+	// Timestamp is the time of the block confirming the transaction, or
+	// the time the pool accepted it while it is unconfirmed, in Unix
+	// seconds.
+	Timestamp   uint64       `json:"timestamp"`
+	BlockHeight uint64       `json:"block_height,omitempty"`
+	BlockHash   *common.Hash `json:"block_hash,omitempty"`
+
+	// ConflictingTx is the transaction that replaced or conflicts with a
+	// transaction that will not confirm.
+	ConflictingTx *transaction.Hash `json:"conflicting_tx,omitempty"`
+
+	// Confirmations is the number of blocks from the one confirming the
+	// transaction to the head. It is filled in when the record is read.
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// HistoryFilter selects a page of the history.
+type HistoryFilter struct {
+	AccountID string               `json:"account_id,omitempty"`
+	AssetID   *transaction.AssetID `json:"asset_id,omitempty"`
+
+	// From and To bound the timestamps of the transactions, in Unix
+	// seconds, From inclusive and To exclusive. Zero leaves a bound open.
+	From uint64 `json:"from,omitempty"`
+	To   uint64 `json:"to,omitempty"`
+
+	Offset int `json:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+}
+
+// HistoryPage is a page of the history, with the number of transactions
+// matching the filter across all pages.
+type HistoryPage struct {
+	Total        int         `json:"total"`
+	Transactions []*TxRecord `json:"transactions"`
+}
+
+// History returns the transactions of the wallet's accounts matching f,
+// newest first.
+func (w *Wallet) History(f HistoryFilter) (*HistoryPage, error) {
+	var matched []*TxRecord
+	iter := w.db.NewIteratorWithPrefix(txRecordPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		rec := new(TxRecord)
+		if err := json.Unmarshal(iter.Value(), rec); err != nil {
+			return nil, err
+		}
+		if f.matches(rec) {
+			matched = append(matched, rec)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp > b.Timestamp
+		}
+		if a.BlockHeight != b.BlockHeight {
+			return a.BlockHeight > b.BlockHeight
+		}
+		return bytes.Compare(a.ID.Bytes(), b.ID.Bytes()) < 0
+	})
+
+	page := &HistoryPage{Total: len(matched), Transactions: []*TxRecord{}}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if f.Offset < 0 || f.Offset >= len(matched) {
+		return page, nil
+	}
+	matched = matched[f.Offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	var head uint64
+	if block := w.chain.CurrentBlock(); block != nil {
+		head = block.NumberU64()
+	}
+	for _, rec := range matched {
+		if rec.Status == StatusConfirmed && head >= rec.BlockHeight {
+			rec.Confirmations = head - rec.BlockHeight + 1
+		}
+	}
+	page.Transactions = matched
+	return page, nil
+}
+
+func (f *HistoryFilter) matches(rec *TxRecord) bool {
+	if f.From != 0 && rec.Timestamp < f.From {
+		return false
+	}
+	if f.To != 0 && rec.Timestamp >= f.To {
+		return false
+	}
+	if f.AccountID == "" && f.AssetID == nil {
+		return true
+	}
+	for _, n := range rec.Net {
+		if (f.AccountID == "" || n.AccountID == f.AccountID) && (f.AssetID == nil || n.AssetID == *f.AssetID) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTxRecord annotates tx, or returns nil if it does not touch the
+// wallet's accounts. The status and block fields are left to the caller.
+func (w *Wallet) newTxRecord(tx *transaction.Tx) *TxRecord {
+	var (
+		net            = make(map[balanceKey]*NetAmount)
+		counterparties = make(map[string]bool)
+	)
+	add := func(utxo *Utxo, amount int64) {
+		key := balanceKey{utxo.AccountID, utxo.AssetID}
+		n, ok := net[key]
+		if !ok {
+			n = &NetAmount{AccountID: utxo.AccountID, AssetID: utxo.AssetID}
+			net[key] = n
+		}
+		n.Amount += amount
+	}
+	counterparty := func(program []byte) {
+		if addr := transaction.ProgramAddress(program); addr != "" {
+			counterparties[addr] = true
+		}
+	}
+	for _, spent := range spentOutputs(tx) {
+		if w.setOwner(spent) {
+			add(spent, -int64(spent.Amount))
+		} else {
+			counterparty(spent.ControlProgram)
+		}
+	}
+	for _, out := range txOutToUtxos(tx) {
+		if w.setOwner(out) {
+			add(out, int64(out.Amount))
+		} else {
+			counterparty(out.ControlProgram)
+		}
+	}
+	if len(net) == 0 {
+		return nil
+	}
+
+	rec := &TxRecord{
+		ID:             tx.ID,
+		Fee:            transaction.CalculateTxFee(&tx.TxData),
+		Net:            make([]*NetAmount, 0, len(net)),
+		Counterparties: make([]string, 0, len(counterparties)),
+	}
+	for _, n := range net {
+		rec.Net = append(rec.Net, n)
+	}
+	sort.Slice(rec.Net, func(i, j int) bool {
+		if rec.Net[i].AccountID != rec.Net[j].AccountID {
+			return rec.Net[i].AccountID < rec.Net[j].AccountID
+		}
+		return bytes.Compare(rec.Net[i].AssetID.Bytes(), rec.Net[j].AssetID.Bytes()) < 0
+	})
+	for addr := range counterparties {
+		rec.Counterparties = append(rec.Counterparties, addr)
+	}
+	sort.Strings(rec.Counterparties)
+	return rec
+}
+
+// getTxRecord returns the history record of the transaction id, or nil if
+// there is none.
+func (w *Wallet) getTxRecord(id transaction.Hash) (*TxRecord, error) {
+	data, err := w.db.Get(txRecordKey(id))
+	if err != nil || len(data) == 0 {
+		return nil, nil
+	}
+	rec := new(TxRecord)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func putTxRecord(db database.Putter, rec *TxRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return db.Put(txRecordKey(rec.ID), data)
+}
+
+// markTxRecord sets the status of the record of the unconfirmed transaction
+// id, which will not confirm because of the transaction by.
+func (w *Wallet) markTxRecord(db database.Putter, id transaction.Hash, status string, by transaction.Hash) {
+	rec, err := w.getTxRecord(id)
+	if rec == nil {
+		if err != nil {
+			log.Error("Failed to read wallet transaction", "id", id.Bytes(), "err", err)
+		}
+		return
+	}
+	rec.Status, rec.ConflictingTx = status, &by
+	if err := putTxRecord(db, rec); err != nil {
+		log.Error("Failed to update wallet transaction", "id", id.Bytes(), "err", err)
+	}
+}
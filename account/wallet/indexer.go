@@ -0,0 +1,309 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/log"
+)
+
+// spentPruneDepth is how many blocks deep the spending of an owned output
+// is buried before its record is pruned. A reorg replacing more blocks than
+// that does not unspend the output again; a rescan finds it.
+const spentPruneDepth = 100
+
+var (
+	utxoPrefix  = []byte("UTXO:")
+	spentPrefix = []byte("UTXP:")
+)
+
+func utxoKey(id transaction.Hash) []byte {
+	return append(append([]byte{}, utxoPrefix...), id.Bytes()...)
+}
+
+// spentKey indexes the record of the owned output id, spent at height, by
+// that height so that the records buried deep enough are found in order.
+func spentKey(height uint64, id transaction.Hash) []byte {
+	key := make([]byte, len(spentPrefix)+8, len(spentPrefix)+8+32)
+	copy(key, spentPrefix)
+	binary.BigEndian.PutUint64(key[len(spentPrefix):], height)
+	return append(key, id.Bytes()...)
+}
+
+// getUtxo returns the record of the owned output id, or nil if the wallet
+// has none.
+func (w *Wallet) getUtxo(id transaction.Hash) (*Utxo, error) {
+	data, err := w.db.Get(utxoKey(id))
+	if err != nil || len(data) == 0 {
+		return nil, nil
+	}
+	utxo := new(Utxo)
+	if err := json.Unmarshal(data, utxo); err != nil {
+		return nil, err
+	}
+	return utxo, nil
+}
+
+// blockUpdate collects the changes one block makes to the output records, so
+// that outputs created and spent within the block are seen by later
+// transactions before anything is written.
+type blockUpdate struct {
+	w       *Wallet
+	changed map[transaction.Hash]*Utxo // nil marks a deleted record
+	order   []transaction.Hash
+}
+
+func (u *blockUpdate) get(id transaction.Hash) (*Utxo, error) {
+	if utxo, ok := u.changed[id]; ok {
+		return utxo, nil
+	}
+	return u.w.getUtxo(id)
+}
+
+func (u *blockUpdate) set(id transaction.Hash, utxo *Utxo) {
+	if _, ok := u.changed[id]; !ok {
+		u.order = append(u.order, id)
+	}
+	u.changed[id] = utxo
+}
+
+func (u *blockUpdate) write(batch database.Batch) error {
+	for _, id := range u.order {
+		utxo := u.changed[id]
+		if utxo == nil {
+			if err := batch.Delete(utxoKey(id)); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.Marshal(utxo)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(utxoKey(id), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachBlock records the outputs of block paying to the wallet, marks the
+// owned outputs it spends, confirms the history of its transactions and
+// drops the unconfirmed transactions it confirms or conflicts with. The
+// records of outputs spent spentPruneDepth blocks below it are pruned.
+func (w *Wallet) attachBlock(block *types.Block) error {
+	var (
+		update = &blockUpdate{w: w, changed: make(map[transaction.Hash]*Utxo)}
+		batch  = w.db.NewBatch()
+	)
+	if err := w.indexBlock(batch, update, block); err != nil {
+		return err
+	}
+	if err := update.write(batch); err != nil {
+		return err
+	}
+	if err := w.pruneSpent(batch, block.NumberU64()); err != nil {
+		return err
+	}
+	status := walletStatus{Height: block.NumberU64(), Hash: block.Hash(), Birthday: w.status.Birthday}
+	if err := w.saveStatus(batch, status); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	w.status = status
+	return nil
+}
+
+// indexBlock queues the changes block makes to the wallet's records into
+// update and batch. Outputs already recorded are kept as they are, so that a
+// rescan of a block does not undo what later blocks did to them.
+func (w *Wallet) indexBlock(batch database.Batch, update *blockUpdate, block *types.Block) error {
+	height := block.NumberU64()
+	for _, t := range block.Transactions() {
+		tx := transaction.NewTx(t.Tx)
+		for _, spent := range spentOutputs(&tx) {
+			utxo, err := update.get(spent.OutputID)
+			if err != nil {
+				return err
+			}
+			if utxo != nil {
+				spentBy := tx.ID
+				utxo.SpentBy, utxo.SpentHeight = &spentBy, height
+				update.set(utxo.OutputID, utxo)
+				if err := batch.Put(spentKey(height, utxo.OutputID), []byte{}); err != nil {
+					return err
+				}
+			}
+			if spender, ok := w.utxokeeper.Spender(spent.OutputID); ok && spender != tx.ID {
+				w.removeUnconfirmedTx(batch, spender)
+				w.markTxRecord(batch, spender, StatusConflicted, tx.ID)
+			}
+		}
+		for _, utxo := range w.ownedOutputs(&tx) {
+			known, err := update.get(utxo.OutputID)
+			if err != nil {
+				return err
+			}
+			if known == nil {
+				utxo.BlockHeight = height
+				update.set(utxo.OutputID, utxo)
+			}
+		}
+		w.removeUnconfirmedTx(batch, tx.ID)
+
+		if rec := w.newTxRecord(&tx); rec != nil {
+			hash := block.Hash()
+			rec.Status = StatusConfirmed
+			rec.Timestamp = block.Time().Uint64()
+			rec.BlockHeight, rec.BlockHash = height, &hash
+			if err := putTxRecord(batch, rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneSpent queues into batch the removal of the records of owned outputs
+// spent at least spentPruneDepth blocks below height.
+func (w *Wallet) pruneSpent(batch database.Deleter, height uint64) error {
+	if height < spentPruneDepth {
+		return nil
+	}
+	iter := w.db.NewIteratorWithPrefix(spentPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		key := append([]byte{}, iter.Key()...)
+		if binary.BigEndian.Uint64(key[len(spentPrefix):]) > height-spentPruneDepth {
+			break
+		}
+		var id [32]byte
+		copy(id[:], key[len(spentPrefix)+8:])
+		if err := batch.Delete(utxoKey(transaction.NewHash(id))); err != nil {
+			return err
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// detachBlock undoes attachBlock for a block leaving the canonical chain:
+// the outputs it created are forgotten and those it spent are unspent again.
+// Its transactions, which the pool keeps, are unconfirmed again.
+func (w *Wallet) detachBlock(block *types.Block) error {
+	var (
+		update = &blockUpdate{w: w, changed: make(map[transaction.Hash]*Utxo)}
+		batch  = w.db.NewBatch()
+		txs    = block.Transactions()
+	)
+	for i := len(txs) - 1; i >= 0; i-- {
+		tx := transaction.NewTx(txs[i].Tx)
+		for _, utxo := range w.ownedOutputs(&tx) {
+			update.set(utxo.OutputID, nil)
+		}
+		for _, spent := range spentOutputs(&tx) {
+			utxo, err := update.get(spent.OutputID)
+			if err != nil {
+				return err
+			}
+			if utxo != nil && utxo.SpentBy != nil && *utxo.SpentBy == tx.ID {
+				if err := batch.Delete(spentKey(utxo.SpentHeight, utxo.OutputID)); err != nil {
+					return err
+				}
+				utxo.SpentBy, utxo.SpentHeight = nil, 0
+				update.set(utxo.OutputID, utxo)
+			}
+		}
+		if err := w.unconfirmTx(batch, &tx); err != nil {
+			return err
+		}
+	}
+	if err := update.write(batch); err != nil {
+		return err
+	}
+	status := walletStatus{Birthday: w.status.Birthday}
+	if height := block.NumberU64(); height > 0 {
+		status.Height, status.Hash = height-1, block.ParentHash()
+	}
+	if err := w.saveStatus(batch, status); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	w.status = status
+	return nil
+}
+
+// unconfirmTx returns tx, leaving the canonical chain, to the unconfirmed
+// view and its history record to pending. Coinbase transactions, which
+// cannot be confirmed elsewhere, are dropped from the history instead.
+func (w *Wallet) unconfirmTx(batch database.Batch, tx *transaction.Tx) error {
+	rec, err := w.getTxRecord(tx.ID)
+	if err != nil || rec == nil {
+		return err
+	}
+	if isCoinbase(tx) {
+		return batch.Delete(txRecordKey(tx.ID))
+	}
+	data, err := tx.TxData.MarshalText()
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(unconfirmedTxKey(tx.ID), data); err != nil {
+		return err
+	}
+	w.trackUnconfirmedTx(tx)
+	rec.Status = StatusPending
+	rec.BlockHeight, rec.BlockHash = 0, nil
+	return putTxRecord(batch, rec)
+}
+
+func isCoinbase(tx *transaction.Tx) bool {
+	for _, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*transaction.CoinbaseInput); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedOutputs returns the outputs of tx paying to the wallet's accounts.
+// The receive programs they pay to are marked used, moving the gap windows
+// of watch-only accounts along.
+func (w *Wallet) ownedOutputs(tx *transaction.Tx) []*Utxo {
+	var owned []*Utxo
+	for _, utxo := range txOutToUtxos(tx) {
+		if w.setOwner(utxo) {
+			if err := w.accounts.MarkProgramUsed(utxo.ControlProgram); err != nil {
+				log.Error("Failed to mark program used", "account", utxo.AccountID, "err", err)
+			}
+			owned = append(owned, utxo)
+		}
+	}
+	return owned
+}
+
+// setOwner fills in the account utxo pays to, reporting whether it belongs
+// to the wallet.
+func (w *Wallet) setOwner(utxo *Utxo) bool {
+	if w.accounts == nil {
+		return false
+	}
+	cp, err := w.accounts.ControlProgram(utxo.ControlProgram)
+	if err != nil {
+		return false
+	}
+	utxo.AccountID = cp.AccountID
+	utxo.Address = cp.Address
+	utxo.ControlProgramIndex = cp.KeyIndex
+	utxo.Change = cp.Change
+	return true
+}
@@ -0,0 +1,254 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/log"
+)
+
+var rescanKey = []byte("WRS")
+
+// rescanLogInterval is how often a running rescan logs its progress.
+const rescanLogInterval = 8 * time.Second
+
+var errRescanBlockMissing = errors.New("rescan block missing")
+
+// rescanStatus is the progress of a rescan. It is saved with every block
+// scanned, so that a rescan interrupted by a restart resumes where it was.
+type rescanStatus struct {
+	From uint64 `json:"from"`
+	Next uint64 `json:"next"`
+}
+
+// RescanProgress reports a running rescan: it started at From, scans
+// Current next and ends after Target, the last block the wallet processed.
+type RescanProgress struct {
+	From    uint64 `json:"from"`
+	Current uint64 `json:"current"`
+	Target  uint64 `json:"target"`
+}
+
+// Rescan scans the canonical blocks again from height from, or from the
+// wallet's birthday when from is nil, recording the outputs paying to the
+// programs the wallet tracks now and the history of their transactions. It
+// finds the outputs of imported keys and rebuilds lost records.
+//
+// The rescan runs in the background while the wallet keeps following the
+// chain, and ends when it reaches the last block the wallet processed;
+// blocks above it are left to the normal block processing, which then knows
+// the outputs the rescan found. Rescanning while a rescan runs moves it back
+// to from if that is lower.
+func (w *Wallet) Rescan(from *uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	start := w.status.Birthday
+	if from != nil {
+		start = *from
+	}
+	status := mergeRescan(w.rescan, start)
+	if status == nil {
+		return nil
+	}
+	if err := saveRescan(w.db, status); err != nil {
+		return err
+	}
+	w.rescan = status
+	log.Info("Wallet rescan started", "from", start, "head", w.status.Height)
+	w.startRescan()
+	return nil
+}
+
+// ScheduleRescan records in db, the records of a wallet that is not running,
+// a rescan from height from, or from the wallet's birthday when from is nil.
+// The wallet carries it out when it next starts. It returns the height the
+// rescan will continue from, and false if db holds no wallet yet: a new
+// wallet with accounts processes the chain from the genesis block anyway.
+func ScheduleRescan(db database.Database, from *uint64) (uint64, bool, error) {
+	data, _ := db.Get(statusKey)
+	if len(data) == 0 {
+		return 0, false, nil
+	}
+	var status walletStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return 0, false, err
+	}
+	start := status.Birthday
+	if from != nil {
+		start = *from
+	}
+	var running *rescanStatus
+	if data, _ := db.Get(rescanKey); len(data) > 0 {
+		running = new(rescanStatus)
+		if err := json.Unmarshal(data, running); err != nil {
+			return 0, false, err
+		}
+	}
+	rescan := mergeRescan(running, start)
+	if rescan == nil {
+		return running.Next, true, nil
+	}
+	return rescan.Next, true, saveRescan(db, rescan)
+}
+
+// mergeRescan returns the rescan from start merged with the running one, or
+// nil if the running one still has to scan start.
+func mergeRescan(running *rescanStatus, start uint64) *rescanStatus {
+	status := &rescanStatus{From: start, Next: start}
+	if running != nil {
+		if start >= running.Next {
+			return nil
+		}
+		if running.From < start {
+			status.From = running.From
+		}
+	}
+	return status
+}
+
+// RescanProgress returns the progress of the running rescan, or nil if none
+// runs.
+func (w *Wallet) RescanProgress() *RescanProgress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rescan == nil {
+		return nil
+	}
+	return &RescanProgress{From: w.rescan.From, Current: w.rescan.Next, Target: w.status.Height}
+}
+
+// loadRescan restores the rescan saved by an earlier run.
+func (w *Wallet) loadRescan() error {
+	data, _ := w.db.Get(rescanKey)
+	if len(data) == 0 {
+		return nil
+	}
+	w.rescan = new(rescanStatus)
+	return json.Unmarshal(data, w.rescan)
+}
+
+// resumeRescan continues the rescan restored by loadRescan, if any.
+func (w *Wallet) resumeRescan() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rescan != nil {
+		log.Info("Wallet rescan resumed", "from", w.rescan.From, "next", w.rescan.Next, "head", w.status.Height)
+		w.startRescan()
+	}
+}
+
+// startRescan runs the rescan in the background unless a goroutine already
+// does. The caller holds w.mu.
+func (w *Wallet) startRescan() {
+	if w.rescanning {
+		return
+	}
+	w.rescanning = true
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		logged := time.Now()
+		for {
+			select {
+			case <-w.quit:
+				w.stopRescan()
+				return
+			default:
+			}
+			done, err := w.rescanBlock()
+			if err != nil {
+				log.Error("Wallet rescan failed", "err", err)
+				w.stopRescan()
+				return
+			}
+			if done {
+				return
+			}
+			if time.Since(logged) > rescanLogInterval {
+				if p := w.RescanProgress(); p != nil {
+					log.Info("Rescanning wallet", "number", p.Current, "head", p.Target)
+				}
+				logged = time.Now()
+			}
+		}
+	}()
+}
+
+func (w *Wallet) stopRescan() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rescanning = false
+}
+
+// rescanBlock scans the next block of the rescan and reports whether the
+// rescan is done. Blocks are scanned one at a time under w.mu, so that block
+// processing goes on between them.
+func (w *Wallet) rescanBlock() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.rescan == nil {
+		w.rescanning = false
+		return true, nil
+	}
+	// Scan the blocks of the wallet's own view of the chain.
+	w.syncLocked()
+	if w.status.Hash == (common.Hash{}) || w.rescan.Next > w.status.Height {
+		if err := w.db.Delete(rescanKey); err != nil {
+			return false, err
+		}
+		log.Info("Wallet rescan finished", "from", w.rescan.From, "head", w.status.Height)
+		w.rescan, w.rescanning = nil, false
+		return true, nil
+	}
+
+	block := w.chain.GetBlockByNumber(w.rescan.Next)
+	if block == nil {
+		return false, errRescanBlockMissing
+	}
+	var (
+		update = &blockUpdate{w: w, changed: make(map[transaction.Hash]*Utxo)}
+		batch  = w.db.NewBatch()
+		status = rescanStatus{From: w.rescan.From, Next: w.rescan.Next + 1}
+	)
+	if err := w.indexBlock(batch, update, block); err != nil {
+		return false, err
+	}
+	if err := update.write(batch); err != nil {
+		return false, err
+	}
+	if err := putRescan(batch, &status); err != nil {
+		return false, err
+	}
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	w.rescan = &status
+	return false, nil
+}
+
+// saveRescan saves the rescan status into db.
+func saveRescan(db database.Database, status *rescanStatus) error {
+	batch := db.NewBatch()
+	if err := putRescan(batch, status); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// putRescan queues the rescan status into batch.
+func putRescan(batch database.Putter, status *rescanStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return batch.Put(rescanKey, data)
+}
@@ -1,54 +1,241 @@
 package wallet
 
 import (
+	"time"
+
 	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
+	"github.com/srchain/srcd/database"
 	"github.com/srchain/srcd/log"
-	"github.com/gin-gonic/gin/json"
 )
 
+var unconfirmedTxPrefix = []byte("UTXS:")
+
+func unconfirmedTxKey(id transaction.Hash) []byte {
+	return append(append([]byte{}, unconfirmedTxPrefix...), id.Bytes()...)
+}
+
+// Utxo is an output paying to one of the wallet's accounts.
 type Utxo struct {
-	OutputID            transaction.Hash
-	SourceID            transaction.Hash
-	AssetID             transaction.AssetID
-	Amount              uint64
-	SourcePos           uint64
-	ControlProgram      []byte
-	AccountID           string
-	Address             string
-	ControlProgramIndex uint64
-	Change              bool
+	OutputID            transaction.Hash    `json:"output_id"`
+	SourceID            transaction.Hash    `json:"source_id"`
+	AssetID             transaction.AssetID `json:"asset_id"`
+	Amount              uint64              `json:"amount"`
+	SourcePos           uint64              `json:"source_pos"`
+	ControlProgram      []byte              `json:"control_program"`
+	AccountID           string              `json:"account_id"`
+	Address             string              `json:"address"`
+	ControlProgramIndex uint64              `json:"control_program_index"`
+	Change              bool                `json:"change"`
+
+	// BlockHeight is the height of the block confirming the output.
+	BlockHeight uint64 `json:"block_height"`
+	// SpentBy is the confirmed transaction spending the output, if any,
+	// and SpentHeight the height of its block.
+	SpentBy     *transaction.Hash `json:"spent_by,omitempty"`
+	SpentHeight uint64            `json:"spent_height,omitempty"`
 }
 
 // AddUnconfirmedTx handle wallet status update when tx add into txpool
-func (w *Wallet) AddUnconfirmedTx(tx *transaction.TxPoolMsg) {
+func (w *Wallet) AddUnconfirmedTx(msg *txpool.TxPoolMsg) {
+	tx := msg.Tx
+	if tx.TxHeader == nil {
+		tx = transaction.NewTx(tx.TxData)
+	}
+	seen := msg.Added
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.addUnconfirmedTx(&tx, seen) {
+		return
+	}
 	//db
-	if err := w.saveUnconfirmedTx(tx.Tx); err != nil {
-		log.Error("err",err," fail on saveUnconfirmedTx ")
+	if err := w.saveUnconfirmedTx(&tx); err != nil {
+		log.Error("Failed to save unconfirmed transaction", "id", tx.ID.Bytes(), "err", err)
+	}
+}
+
+// addUnconfirmedTx adds tx, accepted by the pool at seen, to the unconfirmed
+// view and the history if it pays to or spends from the wallet's accounts
+// and is not confirmed yet, reporting whether it did. Unconfirmed
+// transactions spending the same outputs are replaced by it.
+func (w *Wallet) addUnconfirmedTx(tx *transaction.Tx, seen time.Time) bool {
+	rec, err := w.getTxRecord(tx.ID)
+	if err != nil {
+		log.Error("Failed to read wallet transaction", "id", tx.ID.Bytes(), "err", err)
+		return false
+	}
+	if rec != nil && rec.Status != StatusPending && rec.Status != StatusDropped {
+		return false
+	}
+	for _, utxo := range w.ownedOutputs(tx) {
+		if ok, _ := w.db.Has(utxoKey(utxo.OutputID)); ok {
+			return false
+		}
+	}
+	for _, spent := range spentOutputs(tx) {
+		if spender, ok := w.utxokeeper.Spender(spent.OutputID); ok && spender != tx.ID {
+			w.removeUnconfirmedTx(w.db, spender)
+			w.markTxRecord(w.db, spender, StatusReplaced, tx.ID)
+		}
+	}
+	if !w.trackUnconfirmedTx(tx) {
+		return false
+	}
+	if rec == nil || rec.Status == StatusDropped {
+		rec = w.newTxRecord(tx)
+		rec.Status = StatusPending
+		rec.Timestamp = uint64(seen.Unix())
+		if err := putTxRecord(w.db, rec); err != nil {
+			log.Error("Failed to save wallet transaction", "id", tx.ID.Bytes(), "err", err)
+		}
+	}
+	return true
+}
+
+// trackUnconfirmedTx adds the owned outputs tx creates and spends to the
+// unconfirmed view, reporting whether there are any.
+func (w *Wallet) trackUnconfirmedTx(tx *transaction.Tx) bool {
+	outputs := w.ownedOutputs(tx)
+	var spends []transaction.Hash
+	for _, spent := range spentOutputs(tx) {
+		if w.setOwner(spent) {
+			spends = append(spends, spent.OutputID)
+		}
+	}
+	if len(outputs) == 0 && len(spends) == 0 {
+		return false
 	}
 	//buffer
-	utxos := txOutToUtxos(tx.Tx)
-	w.utxokeeper.AddUnconfirmedTx(utxos)
+	w.utxokeeper.AddUnconfirmedTx(tx.ID, outputs, spends)
+	return true
+}
+
+func (w *Wallet) saveUnconfirmedTx(tx *transaction.Tx) error {
+	data, err := tx.TxData.MarshalText()
+	if err != nil {
+		return err
+	}
+	return w.db.Put(unconfirmedTxKey(tx.ID), data)
 }
 
-func (w *Wallet) saveUnconfirmedTx(tx transaction.Tx) error {
-	bytes, e := json.Marshal(tx)
-	if e != nil {
-		return e
+// removeUnconfirmedTx drops the unconfirmed transaction id, queueing the
+// removal of its saved copy into batch. It reports whether id was
+// unconfirmed.
+func (w *Wallet) removeUnconfirmedTx(batch database.Deleter, id transaction.Hash) bool {
+	if !w.utxokeeper.RemoveUnconfirmedTx(id) {
+		return false
 	}
-	w.db.Put([]byte("UTXS:" + tx.ID.String()), bytes)
+	batch.Delete(unconfirmedTxKey(id))
+	return true
 }
 
-func txOutToUtxos(tx transaction.Tx) []*Utxo {
+// loadUnconfirmedTxs restores the unconfirmed transactions saved by
+// AddUnconfirmedTx, keeping them for resubmitUnconfirmedTxs. Saved copies of
+// transactions that are no longer unconfirmed are deleted.
+func (w *Wallet) loadUnconfirmedTxs() error {
+	iter := w.db.NewIteratorWithPrefix(unconfirmedTxPrefix)
+	defer iter.Release()
+	batch := w.db.NewBatch()
+	for iter.Next() {
+		var data transaction.TxData
+		if err := data.UnmarshalText(iter.Value()); err != nil {
+			return err
+		}
+		tx := transaction.NewTx(data)
+		if !w.addUnconfirmedTx(&tx, time.Now()) {
+			batch.Delete(unconfirmedTxKey(tx.ID))
+			continue
+		}
+		w.restored = append(w.restored, &tx)
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// resubmitUnconfirmedTxs returns the unconfirmed transactions restored from
+// an earlier run to the pool, which starts empty. Those the pool refuses are
+// dropped, so that the outputs they spend are available again. A transaction
+// spending the outputs of another is retried until no more are accepted, as
+// the saved copies come in no particular order.
+func (w *Wallet) resubmitUnconfirmedTxs() {
+	w.mu.Lock()
+	pending := w.restored
+	w.restored = nil
+	w.mu.Unlock()
+
+	for len(pending) > 0 {
+		var retry []*transaction.Tx
+		for _, tx := range pending {
+			select {
+			case <-w.quit:
+				return
+			default:
+			}
+			switch err := w.txPool.AddTransaction(*tx, transaction.CalculateTxFee(&tx.TxData)); err {
+			case nil, txpool.ErrKnownTx:
+			case txpool.ErrMissingInput:
+				retry = append(retry, tx)
+			default:
+				w.dropUnconfirmedTx(tx.ID, err)
+			}
+		}
+		if len(retry) == len(pending) {
+			for _, tx := range retry {
+				w.dropUnconfirmedTx(tx.ID, txpool.ErrMissingInput)
+			}
+			return
+		}
+		pending = retry
+	}
+}
+
+// dropUnconfirmedTx drops the unconfirmed transaction id, which the pool
+// refused with err, and marks its history record dropped.
+func (w *Wallet) dropUnconfirmedTx(id transaction.Hash, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	batch := w.db.NewBatch()
+	if !w.removeUnconfirmedTx(batch, id) {
+		return
+	}
+	log.Debug("Dropped unconfirmed transaction", "id", id.Bytes(), "err", err)
+	rec, err := w.getTxRecord(id)
+	if err != nil {
+		log.Error("Failed to read wallet transaction", "id", id.Bytes(), "err", err)
+	}
+	if rec != nil {
+		rec.Status = StatusDropped
+		if err := putTxRecord(batch, rec); err != nil {
+			log.Error("Failed to update wallet transaction", "id", id.Bytes(), "err", err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to drop unconfirmed transaction", "id", id.Bytes(), "err", err)
+	}
+}
+
+// txOutToUtxos returns the outputs tx creates.
+func txOutToUtxos(tx *transaction.Tx) []*Utxo {
 	utxos := []*Utxo{}
 	for i, out := range tx.Outputs {
+		if i >= len(tx.ResultIds) || out.AssetId == nil {
+			continue
+		}
 		bcOut, err := tx.Output(*tx.ResultIds[i])
 		if err != nil {
 			continue
 		}
 
 		utxos = append(utxos, &Utxo{
-			OutputID:       *tx.OutputID(i),
-			AssetID:        *out.AssetAmount.AssetId,
+			OutputID:       *tx.ResultIds[i],
+			AssetID:        *out.AssetId,
 			Amount:         out.Amount,
 			ControlProgram: out.ControlProgram,
 			SourceID:       *bcOut.Source.Ref,
@@ -57,3 +244,28 @@ func txOutToUtxos(tx transaction.Tx) []*Utxo {
 	}
 	return utxos
 }
+
+// spentOutputs returns the outputs the spend inputs of tx consume, as their
+// spend commitments describe them.
+func spentOutputs(tx *transaction.Tx) []*Utxo {
+	var utxos []*Utxo
+	for i, in := range tx.Inputs {
+		sp, ok := in.TypedInput.(*transaction.SpendInput)
+		if !ok || sp.AssetId == nil {
+			continue
+		}
+		spend, ok := tx.Entries[tx.InputIDs[i]].(*transaction.Spend)
+		if !ok || spend.SpentOutputId == nil {
+			continue
+		}
+		utxos = append(utxos, &Utxo{
+			OutputID:       *spend.SpentOutputId,
+			SourceID:       sp.SourceID,
+			AssetID:        *sp.AssetId,
+			Amount:         sp.Amount,
+			SourcePos:      sp.SourcePosition,
+			ControlProgram: sp.ControlProgram,
+		})
+	}
+	return utxos
+}
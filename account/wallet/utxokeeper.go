@@ -3,21 +3,95 @@ package wallet
 import (
 	"sync"
 
-	"github.com/srchain/srcd/protocol/transaction"
+	"github.com/srchain/srcd/core/transaction"
 )
 
+// unconfirmedTx is what an unconfirmed transaction changes in the wallet.
+type unconfirmedTx struct {
+	outputs []transaction.Hash // owned outputs it creates
+	spends  []transaction.Hash // owned outputs it spends
+}
+
 type utxoKeeper struct {
 	// `sync/atomic` expects the first word in an allocated struct to be 64-bit
 	// aligned on both ARM and x86-32. See https://goo.gl/zW7dgq for more details.
-	mtx           sync.RWMutex
-	unconfirmed  map[transaction.Hash]*Utxo
+	mtx         sync.RWMutex
+	unconfirmed map[transaction.Hash]*Utxo
+	spent       map[transaction.Hash]transaction.Hash // owned output -> unconfirmed spender
+	txs         map[transaction.Hash]*unconfirmedTx
+}
+
+func newUtxoKeeper() utxoKeeper {
+	return utxoKeeper{
+		unconfirmed: make(map[transaction.Hash]*Utxo),
+		spent:       make(map[transaction.Hash]transaction.Hash),
+		txs:         make(map[transaction.Hash]*unconfirmedTx),
+	}
 }
 
-func (uk *utxoKeeper) AddUnconfirmedTx(utxos []*Utxo)  {
+// AddUnconfirmedTx records the unconfirmed transaction id, which creates the
+// owned outputs utxos and spends the owned outputs spends.
+func (uk *utxoKeeper) AddUnconfirmedTx(id transaction.Hash, utxos []*Utxo, spends []transaction.Hash) {
 	uk.mtx.Lock()
 	defer uk.mtx.Unlock()
 
+	if _, ok := uk.txs[id]; ok {
+		return
+	}
+	utx := &unconfirmedTx{spends: spends}
 	for _, utxo := range utxos {
 		uk.unconfirmed[utxo.OutputID] = utxo
+		utx.outputs = append(utx.outputs, utxo.OutputID)
+	}
+	for _, spent := range spends {
+		uk.spent[spent] = id
+	}
+	uk.txs[id] = utx
+}
+
+// RemoveUnconfirmedTx forgets the unconfirmed transaction id, reporting
+// whether it was known.
+func (uk *utxoKeeper) RemoveUnconfirmedTx(id transaction.Hash) bool {
+	uk.mtx.Lock()
+	defer uk.mtx.Unlock()
+
+	utx, ok := uk.txs[id]
+	if !ok {
+		return false
+	}
+	for _, out := range utx.outputs {
+		delete(uk.unconfirmed, out)
+	}
+	for _, spent := range utx.spends {
+		if uk.spent[spent] == id {
+			delete(uk.spent, spent)
+		}
+	}
+	delete(uk.txs, id)
+	return true
+}
+
+// Spender returns the unconfirmed transaction spending the owned output id,
+// if there is one.
+func (uk *utxoKeeper) Spender(id transaction.Hash) (transaction.Hash, bool) {
+	uk.mtx.RLock()
+	defer uk.mtx.RUnlock()
+
+	spender, ok := uk.spent[id]
+	return spender, ok
+}
+
+// Unconfirmed returns the owned outputs of unconfirmed transactions that no
+// other unconfirmed transaction spends.
+func (uk *utxoKeeper) Unconfirmed() []*Utxo {
+	uk.mtx.RLock()
+	defer uk.mtx.RUnlock()
+
+	utxos := make([]*Utxo, 0, len(uk.unconfirmed))
+	for id, utxo := range uk.unconfirmed {
+		if _, ok := uk.spent[id]; !ok {
+			utxos = append(utxos, utxo)
+		}
 	}
+	return utxos
 }
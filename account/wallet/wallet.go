@@ -1,8 +1,248 @@
+// Package wallet tracks the outputs paying to the accounts of the node as the
+// chain and the transaction pool change, and reports their balances.
 package wallet
 
-import "github.com/srchain/srcd/database"
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/event"
+	"github.com/srchain/srcd/log"
+)
+
+const (
+	chainHeadChanSize  = 10
+	chainReorgChanSize = 10
+	txChanSize         = 4096
+)
+
+var statusKey = []byte("WST")
+
+// Chain is the blockchain the wallet follows.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
+}
+
+// TxPool is the transaction pool the wallet takes unconfirmed transactions
+// from, and returns those restored from an earlier run to.
+type TxPool interface {
+	AddTransaction(tx transaction.Tx, fee uint64) error
+	SubscribeNewTxs(ch chan<- *txpool.TxPoolMsg) event.Subscription
+}
+
+// walletStatus is the last block the wallet processed. A zero Hash means it
+// has processed none.
+type walletStatus struct {
+	Height uint64      `json:"height"`
+	Hash   common.Hash `json:"hash"`
+	// Birthday is the height of the first block the wallet processed. No
+	// output of its accounts was paid below it.
+	Birthday uint64 `json:"birthday"`
+}
 
 type Wallet struct {
 	db         database.Database
+	accounts   *account.AccountManager
+	chain      Chain
+	txPool     TxPool
 	utxokeeper utxoKeeper
+
+	mu         sync.Mutex // serializes block processing
+	status     walletStatus
+	rescan     *rescanStatus     // nil when no rescan is running
+	rescanning bool              // whether a goroutine works on rescan
+	restored   []*transaction.Tx // unconfirmed transactions to resubmit
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a wallet storing its records in db, matching outputs against
+// the control programs of accounts. Unconfirmed transactions saved by an
+// earlier run are restored, and returned to txPool by Start.
+//
+// A new wallet is born at the head of chain when accounts has no account
+// yet, and at the genesis block otherwise; outputs paid below its birthday
+// are only found by a rescan.
+func New(db database.Database, accounts *account.AccountManager, chain Chain, txPool TxPool) (*Wallet, error) {
+	w := &Wallet{
+		db:         db,
+		accounts:   accounts,
+		chain:      chain,
+		txPool:     txPool,
+		utxokeeper: newUtxoKeeper(),
+		quit:       make(chan struct{}),
+	}
+	if data, _ := db.Get(statusKey); len(data) > 0 {
+		if err := json.Unmarshal(data, &w.status); err != nil {
+			return nil, err
+		}
+	} else if err := w.initStatus(); err != nil {
+		return nil, err
+	}
+	if err := w.loadRescan(); err != nil {
+		return nil, err
+	}
+	if err := w.loadUnconfirmedTxs(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Start catches the wallet up with the chain, resubmits the unconfirmed
+// transactions restored by New to the transaction pool and keeps following
+// both until Stop is called.
+func (w *Wallet) Start() {
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	reorgCh := make(chan core.ChainReorgEvent, chainReorgChanSize)
+	txCh := make(chan *txpool.TxPoolMsg, txChanSize)
+	headSub := w.chain.SubscribeChainHeadEvent(headCh)
+	reorgSub := w.chain.SubscribeChainReorgEvent(reorgCh)
+	var txSub event.Subscription
+	if w.txPool != nil {
+		txSub = w.txPool.SubscribeNewTxs(txCh)
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer headSub.Unsubscribe()
+		defer reorgSub.Unsubscribe()
+		if txSub != nil {
+			defer txSub.Unsubscribe()
+		}
+
+		w.sync()
+		w.resumeRescan()
+		if w.txPool != nil {
+			// The pool announces what it accepts to txCh, which is
+			// only drained below.
+			w.wg.Add(1)
+			go func() {
+				defer w.wg.Done()
+				w.resubmitUnconfirmedTxs()
+			}()
+		}
+		for {
+			select {
+			case <-headCh:
+				w.sync()
+			case <-reorgCh:
+				w.sync()
+			case msg := <-txCh:
+				w.AddUnconfirmedTx(msg)
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops following the chain.
+func (w *Wallet) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+// Status returns the height and hash of the last block the wallet processed.
+func (w *Wallet) Status() (uint64, common.Hash) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status.Height, w.status.Hash
+}
+
+// Birthday returns the height of the first block the wallet processed.
+func (w *Wallet) Birthday() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status.Birthday
+}
+
+// initStatus sets the birthday of a new wallet and saves it.
+func (w *Wallet) initStatus() error {
+	accounts, err := w.accounts.GetCurrentNodeAccounts(nil)
+	if err != nil {
+		return err
+	}
+	watched, err := w.accounts.WatchAccounts()
+	if err != nil {
+		return err
+	}
+	if head := w.chain.CurrentBlock(); head != nil && len(accounts) == 0 && len(watched) == 0 {
+		w.status.Birthday = head.NumberU64()
+	}
+	batch := w.db.NewBatch()
+	if err := w.saveStatus(batch, w.status); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// sync brings the wallet to the head of the canonical chain: blocks it
+// processed that are no longer canonical are detached, highest first, and
+// the canonical blocks above them attached in order. Head and reorg events
+// only trigger it, so missed events are made up for on the next one.
+func (w *Wallet) sync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncLocked()
+}
+
+// syncLocked is sync for callers holding w.mu.
+func (w *Wallet) syncLocked() {
+	for {
+		if w.status.Hash != (common.Hash{}) {
+			canonical := w.chain.GetBlockByNumber(w.status.Height)
+			if canonical == nil || canonical.Hash() != w.status.Hash {
+				block := w.chain.GetBlock(w.status.Hash, w.status.Height)
+				if block == nil {
+					log.Error("Wallet block missing", "number", w.status.Height, "hash", w.status.Hash)
+					return
+				}
+				if err := w.detachBlock(block); err != nil {
+					log.Error("Failed to detach block from wallet", "number", w.status.Height, "err", err)
+					return
+				}
+				continue
+			}
+		}
+
+		next, parent := w.status.Height+1, w.status.Hash
+		if parent == (common.Hash{}) {
+			next = w.status.Birthday
+		}
+		head := w.chain.CurrentBlock()
+		if head == nil || next > head.NumberU64() {
+			return
+		}
+		block := w.chain.GetBlockByNumber(next)
+		if block == nil || (parent != (common.Hash{}) && block.ParentHash() != parent) {
+			// The chain moved under us; the event announcing it retries.
+			return
+		}
+		if err := w.attachBlock(block); err != nil {
+			log.Error("Failed to attach block to wallet", "number", next, "err", err)
+			return
+		}
+	}
+}
+
+// saveStatus queues the wallet status into batch.
+func (w *Wallet) saveStatus(batch database.Putter, status walletStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return batch.Put(statusKey, data)
 }
@@ -0,0 +1,531 @@
+package wallet
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/event"
+)
+
+// testChain is a chain whose canonical blocks the test sets directly.
+type testChain struct {
+	blocks    map[common.Hash]*types.Block
+	canonical []*types.Block
+	headFeed  event.Feed
+	reorgFeed event.Feed
+}
+
+func newTestChain() *testChain {
+	c := &testChain{blocks: make(map[common.Hash]*types.Block)}
+	c.extend(0)
+	return c
+}
+
+// extend makes a block with the given transactions canonical on top of the
+// first height blocks of the chain. Blocks made at the same height differ in
+// their time.
+func (c *testChain) extend(height int, txs ...transaction.TxData) *types.Block {
+	header := &types.Header{Number: big.NewInt(int64(height)), Time: big.NewInt(int64(len(c.blocks)))}
+	if height > 0 {
+		header.ParentHash = c.canonical[height-1].Hash()
+	}
+	var body []*types.Transaction
+	for _, tx := range txs {
+		body = append(body, &types.Transaction{Tx: tx})
+	}
+	block := types.NewBlock(header, body)
+	c.blocks[block.Hash()] = block
+	c.canonical = append(c.canonical[:height], block)
+	return block
+}
+
+func (c *testChain) CurrentBlock() *types.Block { return c.canonical[len(c.canonical)-1] }
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	if number >= uint64(len(c.canonical)) {
+		return nil
+	}
+	return c.canonical[number]
+}
+
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := c.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block
+	}
+	return nil
+}
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+func (c *testChain) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return c.reorgFeed.Subscribe(ch)
+}
+
+// testPool is a pool refusing the transactions in refused and those
+// spending outputs in unknown until a transaction creating them is added.
+type testPool struct {
+	mu      sync.Mutex
+	refused map[transaction.Hash]error
+	unknown map[transaction.Hash]bool
+	added   []transaction.Hash
+	feed    event.Feed
+}
+
+func (p *testPool) AddTransaction(tx transaction.Tx, fee uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.refused[tx.ID]; err != nil {
+		return err
+	}
+	for _, id := range tx.SpentOutputIDs {
+		if p.unknown[id] {
+			return txpool.ErrMissingInput
+		}
+	}
+	for _, id := range tx.ResultIds {
+		delete(p.unknown, *id)
+	}
+	p.added = append(p.added, tx.ID)
+	return nil
+}
+
+func (p *testPool) SubscribeNewTxs(ch chan<- *txpool.TxPoolMsg) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+func checkBalance(t *testing.T, w *Wallet, accountID string, confirmed, unconfirmed uint64) {
+	t.Helper()
+	balances, err := w.Balances(accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 {
+		t.Fatalf("have %d balances, want 1", len(balances))
+	}
+	b := balances[0]
+	if b.AssetID != *transaction.SRCAssetID || b.Confirmed != confirmed || b.Unconfirmed != unconfirmed {
+		t.Errorf("balance = %d confirmed, %d unconfirmed, want %d, %d", b.Confirmed, b.Unconfirmed, confirmed, unconfirmed)
+	}
+}
+
+// testWallet is a wallet following a test chain, with one account.
+type testWallet struct {
+	*Wallet
+	db      database.Database
+	am      *account.AccountManager
+	chain   *testChain
+	account string
+	program []byte
+	foreign []byte // program paying to someone else
+}
+
+func newTestWallet(t *testing.T) *testWallet {
+	db := database.NewMemDatabase()
+	am := account.NewAccountManager(db)
+	xprv, err := chainkd.NewXPrv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := am.AddAccount(xprv.XPub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := account.AddressProgram(acc.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := chainkd.NewXPrv(nil)
+	foreign, _, err := account.CreateP2PKH(other.XPub())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := newTestChain()
+	w, err := New(db, am, chain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testWallet{w, db, am, chain, acc.Address, prog, foreign.ControlProgram}
+}
+
+// pay returns a transaction paying amount to the wallet's account, along
+// with the output it creates.
+func (tw *testWallet) pay(t *testing.T, source uint64, amount uint64) (transaction.TxData, *Utxo) {
+	data := transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: source}, *transaction.SRCAssetID, amount+500, 0, tw.foreign),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, amount, tw.program),
+			transaction.NewTxOutput(*transaction.SRCAssetID, 500, tw.foreign),
+		},
+	}
+	tx := transaction.NewTx(data)
+	owned := tw.ownedOutputs(&tx)
+	if len(owned) != 1 {
+		t.Fatalf("have %d owned outputs, want 1", len(owned))
+	}
+	return data, owned[0]
+}
+
+// spend returns a transaction spending u, paying amount away and taking the
+// rest back as change.
+func (tw *testWallet) spend(u *Utxo, amount uint64) transaction.TxData {
+	return transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, u.SourceID, u.AssetID, u.Amount, u.SourcePos, u.ControlProgram),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, amount, tw.foreign),
+			transaction.NewTxOutput(*transaction.SRCAssetID, u.Amount-amount, tw.program),
+		},
+	}
+}
+
+func TestWalletFollowsChain(t *testing.T) {
+	tw := newTestWallet(t)
+	w, db, am, chain := tw.Wallet, tw.db, tw.am, tw.chain
+	acc := struct{ Address string }{tw.account}
+
+	// A payment of 1000 to the account is confirmed.
+	pay, u := tw.pay(t, 1, 1000)
+	chain.extend(1, pay)
+	w.sync()
+	checkBalance(t, w, acc.Address, 1000, 1000)
+
+	// The account spends it, paying 300 and taking 700 back as change.
+	spendData := tw.spend(u, 300)
+	spend := transaction.NewTx(spendData)
+	if spend.SpentOutputIDs[0] != u.OutputID {
+		t.Fatal("spend does not consume the owned output")
+	}
+	w.AddUnconfirmedTx(&txpool.TxPoolMsg{Tx: spend})
+	checkBalance(t, w, acc.Address, 1000, 700)
+
+	// Restarting keeps the unconfirmed view.
+	w, err := New(db, am, chain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, w, acc.Address, 1000, 700)
+
+	chain.extend(2, spendData)
+	w.sync()
+	checkBalance(t, w, acc.Address, 700, 700)
+	if len(w.utxokeeper.txs) != 0 {
+		t.Error("confirmed transaction still unconfirmed")
+	}
+	record, _ := w.getUtxo(u.OutputID)
+	if record == nil || record.SpentBy == nil || *record.SpentBy != spend.ID || record.SpentHeight != 2 {
+		t.Errorf("spent output record = %+v", record)
+	}
+
+	// A reorg drops the block spending the output, whose transaction is
+	// unconfirmed again.
+	chain.extend(2)
+	chain.extend(3)
+	w.sync()
+	if height, hash := w.Status(); height != 3 || hash != chain.CurrentBlock().Hash() {
+		t.Errorf("status = %d %x, want 3 %x", height, hash, chain.CurrentBlock().Hash())
+	}
+	checkBalance(t, w, acc.Address, 1000, 700)
+	if record, _ := w.getUtxo(u.OutputID); record == nil || record.SpentBy != nil {
+		t.Errorf("output not unspent after reorg: %+v", record)
+	}
+
+	// Nothing stays confirmed when the chain is replaced from genesis.
+	chain.canonical = chain.canonical[:0]
+	chain.extend(0)
+	w.sync()
+	checkBalance(t, w, acc.Address, 0, 700)
+}
+
+func TestResubmitUnconfirmed(t *testing.T) {
+	tw := newTestWallet(t)
+	pay1, u1 := tw.pay(t, 1, 1000)
+	pay2, u2 := tw.pay(t, 2, 2000)
+	tw.chain.extend(1, pay1, pay2)
+	tw.sync()
+
+	// A spend of the first payment, a spend of its change and a spend of
+	// the second payment wait in the pool when the node stops.
+	parent := transaction.NewTx(tw.spend(u1, 300))
+	change := tw.ownedOutputs(&parent)[0]
+	child := transaction.NewTx(tw.spend(change, 200))
+	other := transaction.NewTx(tw.spend(u2, 500))
+	for _, tx := range []transaction.Tx{parent, child, other} {
+		tw.AddUnconfirmedTx(&txpool.TxPoolMsg{Tx: tx})
+	}
+	checkBalance(t, tw.Wallet, tw.account, 3000, 2000)
+
+	// The pool the node restarts with takes back the first two, parent
+	// first, and refuses the third.
+	pool := &testPool{
+		refused: map[transaction.Hash]error{other.ID: txpool.ErrDoubleSpend},
+		unknown: map[transaction.Hash]bool{change.OutputID: true},
+	}
+	w, err := New(tw.db, tw.am, tw.chain, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if rec, _ := w.getTxRecord(other.ID); rec != nil && rec.Status == StatusDropped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refused transaction not dropped")
+		}
+	}
+	w.Stop()
+
+	if want := []transaction.Hash{parent.ID, child.ID}; !reflect.DeepEqual(pool.added, want) {
+		t.Errorf("resubmitted %x, want %x", pool.added, want)
+	}
+	checkBalance(t, w, tw.account, 3000, 2500)
+	if ok, _ := tw.db.Has(unconfirmedTxKey(other.ID)); ok {
+		t.Error("saved copy of the dropped transaction kept")
+	}
+	if _, ok := w.utxokeeper.Spender(u2.OutputID); ok {
+		t.Error("input of the dropped transaction still spent")
+	}
+}
+
+func TestPruneSpent(t *testing.T) {
+	tw := newTestWallet(t)
+	pay, u := tw.pay(t, 1, 1000)
+	tw.chain.extend(1, pay)
+	tw.chain.extend(2, tw.spend(u, 300))
+	for height := 3; height < 2+spentPruneDepth; height++ {
+		tw.chain.extend(height)
+	}
+	tw.sync()
+	if record, _ := tw.getUtxo(u.OutputID); record == nil || record.SpentHeight != 2 {
+		t.Fatalf("spent output record = %+v", record)
+	}
+
+	tw.chain.extend(2 + spentPruneDepth)
+	tw.sync()
+	if record, _ := tw.getUtxo(u.OutputID); record != nil {
+		t.Errorf("spent output record kept %d blocks deep: %+v", spentPruneDepth, record)
+	}
+	if ok, _ := tw.db.Has(spentKey(2, u.OutputID)); ok {
+		t.Error("index of the pruned record kept")
+	}
+	checkBalance(t, tw.Wallet, tw.account, 700, 700)
+}
+
+func TestHistory(t *testing.T) {
+	tw := newTestWallet(t)
+	pay, u := tw.pay(t, 1, 1000)
+	tw.chain.extend(1, pay)
+	tw.sync()
+
+	// Two unconfirmed spends of the same output: the second replaces the
+	// first, and a third one confirming conflicts with the second.
+	var spends []transaction.Tx
+	for i, amount := range []uint64{100, 200, 300} {
+		spends = append(spends, transaction.NewTx(tw.spend(u, amount)))
+		if i < 2 {
+			tw.AddUnconfirmedTx(&txpool.TxPoolMsg{Tx: spends[i], Added: time.Unix(int64(100+i), 0)})
+		}
+	}
+	tw.chain.extend(2, spends[2].TxData)
+	tw.sync()
+
+	page, err := tw.History(HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || len(page.Transactions) != 4 {
+		t.Fatalf("history has %d of %d transactions, want 4", len(page.Transactions), page.Total)
+	}
+	want := []struct {
+		id            transaction.Hash
+		status        string
+		net           int64
+		conflicting   *transaction.Hash
+		confirmations uint64
+	}{
+		{spends[1].ID, StatusConflicted, -200, &spends[2].ID, 0},
+		{spends[0].ID, StatusReplaced, -100, &spends[1].ID, 0},
+		{spends[2].ID, StatusConfirmed, -300, nil, 1},
+		{transaction.NewTx(pay).ID, StatusConfirmed, 1000, nil, 2},
+	}
+	for i, rec := range page.Transactions {
+		w := want[i]
+		if rec.ID != w.id || rec.Status != w.status || rec.Confirmations != w.confirmations {
+			t.Errorf("transaction %d: %x %s with %d confirmations, want %x %s with %d", i, rec.ID.Bytes(), rec.Status, rec.Confirmations, w.id.Bytes(), w.status, w.confirmations)
+		}
+		if len(rec.Net) != 1 || rec.Net[0].Amount != w.net || rec.Net[0].AccountID != tw.account {
+			t.Errorf("transaction %d: net %+v, want %d", i, rec.Net, w.net)
+		}
+		if (rec.ConflictingTx == nil) != (w.conflicting == nil) || (w.conflicting != nil && *rec.ConflictingTx != *w.conflicting) {
+			t.Errorf("transaction %d: conflicting transaction %v, want %v", i, rec.ConflictingTx, w.conflicting)
+		}
+		if len(rec.Counterparties) != 1 || rec.Counterparties[0] != transaction.ProgramAddress(tw.foreign) {
+			t.Errorf("transaction %d: counterparties %v", i, rec.Counterparties)
+		}
+	}
+	if fee := page.Transactions[3].Fee; fee != 0 {
+		t.Errorf("payment fee %d, want 0", fee)
+	}
+
+	filters := []struct {
+		filter HistoryFilter
+		total  int
+		first  transaction.Hash
+	}{
+		{HistoryFilter{Offset: 1, Limit: 2}, 4, spends[0].ID},
+		{HistoryFilter{To: 100}, 2, spends[2].ID},
+		{HistoryFilter{From: 100, To: 101}, 1, spends[0].ID},
+		{HistoryFilter{AccountID: tw.account, AssetID: transaction.SRCAssetID}, 4, spends[1].ID},
+		{HistoryFilter{AccountID: "nobody"}, 0, transaction.Hash{}},
+		{HistoryFilter{AssetID: &transaction.AssetID{V0: 7}}, 0, transaction.Hash{}},
+	}
+	for i, f := range filters {
+		page, err := tw.History(f.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != f.total {
+			t.Errorf("filter %d: %d transactions, want %d", i, page.Total, f.total)
+			continue
+		}
+		if f.total > 0 && page.Transactions[0].ID != f.first {
+			t.Errorf("filter %d: first transaction %x, want %x", i, page.Transactions[0].ID.Bytes(), f.first.Bytes())
+		}
+	}
+	if page, _ := tw.History(HistoryFilter{Offset: 1, Limit: 2}); len(page.Transactions) != 2 {
+		t.Errorf("page has %d transactions, want 2", len(page.Transactions))
+	}
+}
+
+func TestRescan(t *testing.T) {
+	tw := newTestWallet(t)
+	xprv, err := chainkd.NewXPrv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, _, err := account.CreateP2PKH(xprv.XPub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payTo := func(source, amount uint64) transaction.TxData {
+		return transaction.TxData{
+			Version: 1,
+			Inputs: []*transaction.TxInput{
+				transaction.NewSpendInput(nil, transaction.Hash{V0: source}, *transaction.SRCAssetID, amount, 0, tw.foreign),
+			},
+			Outputs: []*transaction.TxOutput{
+				transaction.NewTxOutput(*transaction.SRCAssetID, amount, imported.ControlProgram),
+			},
+		}
+	}
+
+	// A key is paid 1000 and spends 300 of it before the wallet knows it.
+	pay := payTo(1, 1000)
+	payTx := transaction.NewTx(pay)
+	spender := *tw
+	spender.program = imported.ControlProgram
+	tw.chain.extend(1, pay)
+	tw.chain.extend(2, spender.spend(txOutToUtxos(&payTx)[0], 300))
+	tw.sync()
+
+	acc, err := tw.am.AddAccount(xprv.XPub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances, _ := tw.Balances(acc.Address); len(balances) != 0 {
+		t.Fatalf("imported key has balances %+v before the rescan", balances)
+	}
+
+	// The rescan is stepped by hand while a block paying the key joins the
+	// chain; it is processed as the tip, not missed.
+	tw.mu.Lock()
+	tw.rescan = &rescanStatus{}
+	tw.mu.Unlock()
+	if done, err := tw.rescanBlock(); done || err != nil {
+		t.Fatalf("first rescan step: done %v, %v", done, err)
+	}
+	tw.chain.extend(3, payTo(2, 2000))
+	steps := 1
+	for ; steps < 10; steps++ {
+		done, err := tw.rescanBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			break
+		}
+	}
+	if steps != 4 {
+		t.Errorf("rescan took %d steps, want 4", steps)
+	}
+	if height, _ := tw.Status(); height != 3 {
+		t.Errorf("wallet at height %d, want 3", height)
+	}
+	checkBalance(t, tw.Wallet, acc.Address, 2700, 2700)
+	if page, _ := tw.History(HistoryFilter{AccountID: acc.Address}); page.Total != 3 {
+		t.Errorf("imported key has %d transactions, want 3", page.Total)
+	}
+	if tw.RescanProgress() != nil {
+		t.Error("rescan still running")
+	}
+
+	// A rescan scheduled while the wallet is stopped, or interrupted by a
+	// restart, is resumed when the wallet starts.
+	if _, ok, err := ScheduleRescan(database.NewMemDatabase(), nil); ok || err != nil {
+		t.Errorf("rescan scheduled without a wallet: %v", err)
+	}
+	from := uint64(2)
+	if next, ok, err := ScheduleRescan(tw.db, &from); !ok || err != nil || next != 2 {
+		t.Fatalf("scheduled rescan from %d, %v, %v", next, ok, err)
+	}
+	w, err := New(tw.db, tw.am, tw.chain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := w.RescanProgress(); p == nil || p.Current != 2 || p.Target != 3 {
+		t.Fatalf("restored rescan progress %+v", p)
+	}
+	w.resumeRescan()
+	for deadline := time.Now().Add(5 * time.Second); w.RescanProgress() != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("rescan did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Stop()
+	checkBalance(t, w, acc.Address, 2700, 2700)
+
+	// A wallet created before any account is born at the head of the chain.
+	db := database.NewMemDatabase()
+	fresh, err := New(db, account.NewAccountManager(db), tw.chain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Birthday() != 3 || tw.Birthday() != 0 {
+		t.Errorf("birthdays %d and %d, want 3 and 0", fresh.Birthday(), tw.Birthday())
+	}
+	fresh.sync()
+	if height, hash := fresh.Status(); height != 3 || hash != tw.chain.CurrentBlock().Hash() {
+		t.Errorf("fresh wallet at %d %x", height, hash)
+	}
+}
@@ -0,0 +1,273 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/srchain/srcd/common/address"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/params"
+)
+
+var WatchPrefix = []byte("ACWO")
+
+// DefaultGapLimit is how many unused receive and change programs a watch-only
+// account keeps derived past the last one handed out or paid.
+const DefaultGapLimit = 20
+
+// receiveBranch is the first step of the derivation path of receive keys,
+// m/0/index below the account keys.
+const receiveBranch = 0
+
+var (
+	ErrNoKeys       = errors.New("no public keys given")
+	ErrBadQuorum    = errors.New("quorum must be between 1 and the number of keys")
+	ErrDuplicateKey = errors.New("public key given twice")
+	ErrKnownAccount = errors.New("account already belongs to this wallet")
+)
+
+// WatchAccount is a watch-only account: the wallet knows its public keys,
+// so it can follow its outputs and build its transactions, but the private
+// keys are held elsewhere and the transactions are signed offline.
+type WatchAccount struct {
+	Address string         `json:"address"`
+	XPubs   []chainkd.XPub `json:"xpubs"`
+	Quorum  int            `json:"quorum"`
+
+	// GapLimit is how many unused receive programs are kept derived past
+	// NextIndex, and change programs past the account's change index.
+	GapLimit uint64 `json:"gap_limit"`
+	// NextIndex is the index of the next receive program to hand out. It
+	// moves past every receive program seen paid.
+	NextIndex uint64 `json:"next_index"`
+	// Derived is the number of receive programs derived so far.
+	Derived uint64 `json:"derived"`
+	// ChangeDerived is the number of change programs derived so far.
+	ChangeDerived uint64 `json:"change_derived"`
+}
+
+func (acc *WatchAccount) signers() signers {
+	return signers{xpubs: acc.XPubs, quorum: acc.Quorum}
+}
+
+func watchKey(addr string) []byte {
+	return append(append([]byte{}, WatchPrefix...), addr...)
+}
+
+// ImportWatchOnly records a watch-only account controlled by quorum of
+// xpubs, or by a single xpub, and derives its first gapLimit receive and
+// change programs, so that outputs paid to the keys before are found. A zero
+// gapLimit selects DefaultGapLimit. The same keys given in any order make the
+// same account.
+func (am AccountManager) ImportWatchOnly(xpubs []chainkd.XPub, quorum int, gapLimit uint64) (*WatchAccount, error) {
+	if len(xpubs) == 0 {
+		return nil, ErrNoKeys
+	}
+	if quorum < 1 || quorum > len(xpubs) {
+		return nil, fmt.Errorf("%v: %d of %d", ErrBadQuorum, quorum, len(xpubs))
+	}
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	xpubs = append([]chainkd.XPub(nil), xpubs...)
+	sort.Slice(xpubs, func(i, j int) bool { return bytes.Compare(xpubs[i][:], xpubs[j][:]) < 0 })
+	for i := 1; i < len(xpubs); i++ {
+		if xpubs[i] == xpubs[i-1] {
+			return nil, fmt.Errorf("%v: %s", ErrDuplicateKey, xpubs[i])
+		}
+	}
+	if len(xpubs) == 1 {
+		if ok, _ := am.db.Has(ripemd160.Ripemd160(xpubs[0].PublicKey())); ok {
+			return nil, ErrKnownAccount
+		}
+	}
+
+	acc := &WatchAccount{XPubs: xpubs, Quorum: quorum, GapLimit: gapLimit}
+	cp, err := acc.signers().ctrlProgram()
+	if err != nil {
+		return nil, err
+	}
+	acc.Address = cp.Address
+
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	if _, ok := am.WatchAccount(acc.Address); ok {
+		return nil, ErrKnownAccount
+	}
+	if err := am.derivePrograms(acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// WatchAccount returns the watch-only account with address addr. An account
+// recorded under the legacy address prefix of the network is found by either
+// form of its address.
+func (am AccountManager) WatchAccount(addr string) (*WatchAccount, bool) {
+	data, err := am.db.Get(watchKey(addr))
+	if err != nil || len(data) == 0 {
+		legacy := legacyAddress(addr)
+		if legacy == "" || legacy == addr {
+			return nil, false
+		}
+		if data, err = am.db.Get(watchKey(legacy)); err != nil || len(data) == 0 {
+			return nil, false
+		}
+	}
+	acc := new(WatchAccount)
+	if err := json.Unmarshal(data, acc); err != nil {
+		return nil, false
+	}
+	return acc, true
+}
+
+// WatchAccounts returns the watch-only accounts of the wallet.
+func (am AccountManager) WatchAccounts() ([]*WatchAccount, error) {
+	var accounts []*WatchAccount
+	iter := am.db.NewIteratorWithPrefix(WatchPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		acc := new(WatchAccount)
+		if err := json.Unmarshal(iter.Value(), acc); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, iter.Error()
+}
+
+// legacyAddress returns addr encoded with the legacy address prefix of the
+// active network, or the empty string if the network has none or addr is
+// not a witness address.
+func legacyAddress(addr string) string {
+	hrp := params.ActiveNetParams.LegacyBech32HRPSegwit
+	if hrp == "" {
+		return ""
+	}
+	program, err := AddressProgram(addr)
+	if err != nil {
+		return ""
+	}
+	legacy := &params.NetParams{Bech32HRPSegwit: hrp}
+	var decoded address.Address
+	switch {
+	case vm.IsP2WPKHProgram(program):
+		decoded, err = address.NewAddressWitnessPubKeyHash(program[2:], legacy)
+	case vm.IsP2WSHProgram(program):
+		decoded, err = address.NewAddressWitnessScriptHash(program[2:], legacy)
+	default:
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	return decoded.EncodeAddress()
+}
+
+// CreateReceiveProgram hands out the next unused receive program of the
+// watch-only account with address accountAddr, deriving more so that
+// GapLimit unused ones stay ahead of it.
+func (am AccountManager) CreateReceiveProgram(accountAddr string) (*CtrlProgram, error) {
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	acc, ok := am.WatchAccount(accountAddr)
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	index := acc.NextIndex
+	acc.NextIndex++
+	if err := am.derivePrograms(acc); err != nil {
+		return nil, err
+	}
+	cp, ok := am.derivedProgram(am.receiveProgram(acc, index))
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	return cp, nil
+}
+
+// MarkProgramUsed notes that program was paid. When it is a change program,
+// the account's change index moves past it. When it is a receive program of
+// a watch-only account, the account's next receive program does. The gap
+// windows of a watch-only account are derived further.
+func (am AccountManager) MarkProgramUsed(program []byte) error {
+	cp, ok := am.derivedProgram(program)
+	if !ok {
+		return nil
+	}
+
+	am.changeMu.Lock()
+	defer am.changeMu.Unlock()
+
+	if cp.Change {
+		return am.useChangeIndex(cp)
+	}
+	acc, ok := am.WatchAccount(cp.AccountID)
+	if !ok || cp.KeyIndex < acc.NextIndex {
+		return nil
+	}
+	acc.NextIndex = cp.KeyIndex + 1
+	return am.derivePrograms(acc)
+}
+
+// receiveProgram returns the control program of the receive key index of
+// acc, which must have been derived.
+func (am AccountManager) receiveProgram(acc *WatchAccount, index uint64) []byte {
+	s, err := acc.signers().derive(chainkd.IndexPath(receiveBranch, index))
+	if err != nil {
+		return nil
+	}
+	cp, err := s.ctrlProgram()
+	if err != nil {
+		return nil
+	}
+	return cp.ControlProgram
+}
+
+// derivePrograms records the receive programs of acc up to GapLimit past
+// NextIndex and its change programs up to GapLimit past its change index,
+// then saves acc. The caller holds changeMu.
+func (am AccountManager) derivePrograms(acc *WatchAccount) error {
+	program, err := AddressProgram(acc.Address)
+	if err != nil {
+		return err
+	}
+	for ; acc.Derived < acc.NextIndex+acc.GapLimit; acc.Derived++ {
+		if err := am.deriveProgram(acc, receiveBranch, acc.Derived); err != nil {
+			return err
+		}
+	}
+	for end := am.changeIndex(program) + acc.GapLimit; acc.ChangeDerived < end; acc.ChangeDerived++ {
+		if err := am.deriveProgram(acc, changeBranch, acc.ChangeDerived); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(acc)
+	if err != nil {
+		return err
+	}
+	return am.db.Put(watchKey(acc.Address), data)
+}
+
+// deriveProgram records the program of key index on branch of acc.
+func (am AccountManager) deriveProgram(acc *WatchAccount, branch, index uint64) error {
+	s, err := acc.signers().derive(chainkd.IndexPath(branch, index))
+	if err != nil {
+		return err
+	}
+	cp, err := s.ctrlProgram()
+	if err != nil {
+		return err
+	}
+	cp.AccountID = acc.Address
+	cp.KeyIndex = index
+	cp.Change = branch == changeBranch
+	return am.putDerivedProgram(cp)
+}
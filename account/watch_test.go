@@ -0,0 +1,208 @@
+package account
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/params"
+)
+
+func TestWatchOnlyMultisig(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	am.SetKeyStore(testKeyStore{})
+
+	var (
+		xprvs = make([]chainkd.XPrv, 3)
+		xpubs = make([]chainkd.XPub, 3)
+	)
+	for i := range xprvs {
+		xprvs[i], xpubs[i], _ = chainkd.NewXKeys(rand.Reader)
+	}
+	acc, err := am.ImportWatchOnly(xpubs, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := am.ImportWatchOnly([]chainkd.XPub{xpubs[2], xpubs[0], xpubs[1]}, 2, 5); err != ErrKnownAccount {
+		t.Errorf("importing the keys in another order: %v, want %v", err, ErrKnownAccount)
+	}
+	if _, err := am.ImportWatchOnly(xpubs, 4, 5); err == nil {
+		t.Error("imported 4 of 3 keys")
+	}
+	program, err := AddressProgram(acc.Address)
+	if err != nil || !vm.IsP2WSHProgram(program) {
+		t.Fatalf("account address %s is not a witness script address: %x, %v", acc.Address, program, err)
+	}
+	if cp, err := am.ControlProgram(program); err != nil || cp.AccountID != acc.Address {
+		t.Errorf("ControlProgram(account program) = %+v, %v", cp, err)
+	}
+
+	// The gap window covers the first five receive programs and moves past
+	// programs seen paid.
+	receive := func(index uint64) []byte { return am.receiveProgram(acc, index) }
+	if _, err := am.ControlProgram(receive(4)); err != nil {
+		t.Errorf("receive program 4 not recognized: %v", err)
+	}
+	if _, err := am.ControlProgram(receive(5)); err != ErrUnknownAccount {
+		t.Errorf("receive program 5 recognized before the window moved: %v", err)
+	}
+	if err := am.MarkProgramUsed(receive(3)); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err := am.ControlProgram(receive(8)); err != nil || cp.KeyIndex != 8 || cp.Change {
+		t.Errorf("receive program 8 after using 3: %+v, %v", cp, err)
+	}
+	if cp, err := am.CreateReceiveProgram(acc.Address); err != nil || cp.KeyIndex != 4 {
+		t.Errorf("next receive program: %+v, %v", cp, err)
+	}
+
+	// So does the change window, over the change programs the keys may have
+	// been paid to before the import.
+	changeProgram := func(index uint64) []byte {
+		s, _ := acc.signers().derive(changePath(index))
+		cp, _ := s.ctrlProgram()
+		return cp.ControlProgram
+	}
+	if cp, err := am.ControlProgram(changeProgram(4)); err != nil || cp.KeyIndex != 4 || !cp.Change {
+		t.Errorf("change program 4: %+v, %v", cp, err)
+	}
+	if _, err := am.ControlProgram(changeProgram(5)); err != ErrUnknownAccount {
+		t.Errorf("change program 5 recognized before the window moved: %v", err)
+	}
+	if err := am.MarkProgramUsed(changeProgram(2)); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err := am.ControlProgram(changeProgram(7)); err != nil || cp.KeyIndex != 7 || !cp.Change {
+		t.Errorf("change program 7 after using 2: %+v, %v", cp, err)
+	}
+
+	// A transaction spending from the account is built without its keys
+	// and signed offline by two of them.
+	funding := transaction.NewTx(transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 80000, 0, []byte{0x51}),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, 30000, program),
+			transaction.NewTxOutput(*transaction.SRCAssetID, 50000, receive(2)),
+		},
+	})
+	utxos := []*transaction.UTXO{outputUTXO(t, &funding, 0, acc.Address), outputUTXO(t, &funding, 1, "")}
+	_, payee, _ := chainkd.NewXKeys(rand.Reader)
+	payeeProgram, _, err := CreateP2PKH(payee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []*Action{
+		{Type: ActionSpendAccount, Account: acc.Address, AssetID: *transaction.SRCAssetID, Amount: 60000},
+		{Type: ActionControlAddress, Address: payeeProgram.Address, AssetID: *transaction.SRCAssetID, Amount: 60000},
+	}
+	res, err := am.Build(actions, func(string) []*transaction.UTXO { return utxos }, BuildOptions{FeeRate: 1, ReserveTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := res.Template
+	if len(tpl.Transaction.Inputs) != 2 {
+		t.Fatalf("built %d inputs, want 2", len(tpl.Transaction.Inputs))
+	}
+	change, err := am.ControlProgram(tpl.Transaction.Outputs[1].ControlProgram)
+	if err != nil || change.AccountID != acc.Address || !change.Change {
+		t.Errorf("change output pays to %+v, %v", change, err)
+	}
+
+	if err := am.SignTemplate(tpl, "secret"); err != nil {
+		t.Fatalf("signing a watch-only template online: %v", err)
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, 0); err == nil {
+		t.Fatal("watch-only transaction verifies without signatures")
+	}
+	for _, sigInst := range tpl.SigningInstructions {
+		sw := sigInst.WitnessComponents[0].(*transaction.RawTxSigWitness)
+		var path [][]byte
+		for _, sel := range sw.Keys[0].DerivationPath {
+			path = append(path, sel)
+		}
+		for _, xprv := range []chainkd.XPrv{xprvs[0], xprvs[2]} {
+			if err := transaction.Sign(tpl, xprv.Derive(path)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := transaction.VerifyTx(&tpl.Transaction.TxWrap, 0); err != nil {
+		t.Fatalf("transaction signed offline does not verify: %v", err)
+	}
+	if weight := tpl.Transaction.TxData.Weight(); weight != res.Weight {
+		t.Errorf("signed weight %d, estimated %d", weight, res.Weight)
+	}
+}
+
+func TestWatchOnlySingleKey(t *testing.T) {
+	am := NewAccountManager(database.NewMemDatabase())
+	_, owned, _ := chainkd.NewXKeys(rand.Reader)
+	if _, err := am.AddAccount(owned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := am.ImportWatchOnly([]chainkd.XPub{owned}, 1, 0); err != ErrKnownAccount {
+		t.Errorf("importing the key of an account: %v, want %v", err, ErrKnownAccount)
+	}
+
+	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.ImportWatchOnly([]chainkd.XPub{xpub}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, _, err := CreateP2PKH(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Address != cp.Address || acc.Derived != DefaultGapLimit || acc.ChangeDerived != DefaultGapLimit {
+		t.Errorf("imported %+v, want address %s with %d receive and change programs", acc, cp.Address, DefaultGapLimit)
+	}
+	if got, err := am.ControlProgram(cp.ControlProgram); err != nil || got.AccountID != acc.Address {
+		t.Errorf("ControlProgram(account program) = %+v, %v", got, err)
+	}
+	if accounts, err := am.WatchAccounts(); err != nil || len(accounts) != 1 {
+		t.Errorf("WatchAccounts() = %d accounts, %v", len(accounts), err)
+	}
+}
+
+func TestWatchOnlyLegacyAddress(t *testing.T) {
+	defer func(active *params.NetParams) { params.ActiveNetParams = active }(params.ActiveNetParams)
+
+	// The account was imported while test network addresses had the main
+	// network prefix.
+	params.ActiveNetParams = &params.MainNetParams
+	am := NewAccountManager(database.NewMemDatabase())
+	_, xpub, _ := chainkd.NewXKeys(rand.Reader)
+	acc, err := am.ImportWatchOnly([]chainkd.XPub{xpub}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params.ActiveNetParams = &params.TestNetParams
+	cp, _, err := CreateP2PKH(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Address == acc.Address {
+		t.Fatalf("address %s encoded the same on both networks", cp.Address)
+	}
+	if program, err := AddressProgram(acc.Address); err != nil || !bytes.Equal(program, cp.ControlProgram) {
+		t.Errorf("legacy address %s decodes to %x, %v", acc.Address, program, err)
+	}
+	if got, ok := am.WatchAccount(cp.Address); !ok || got.Address != acc.Address {
+		t.Errorf("WatchAccount(%s) = %+v, %v", cp.Address, got, ok)
+	}
+	if got, err := am.ControlProgram(cp.ControlProgram); err != nil || got.AccountID != acc.Address {
+		t.Errorf("ControlProgram(account program) = %+v, %v", got, err)
+	}
+	if _, err := am.ImportWatchOnly([]chainkd.XPub{xpub}, 1, 0); err != ErrKnownAccount {
+		t.Errorf("importing the key again: %v, want %v", err, ErrKnownAccount)
+	}
+}
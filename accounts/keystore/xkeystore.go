@@ -0,0 +1,502 @@
+package keystore
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+	"github.com/srchain/srcd/log"
+)
+
+// xkeyType marks key files holding chainkd keys, telling them apart from
+// the ECDSA key files sharing the directory.
+const xkeyType = "chainkd"
+
+var (
+	ErrEmptyAlias     = errors.New("key alias is empty")
+	ErrDuplicateAlias = errors.New("key alias already in use")
+	ErrXKeyExists     = errors.New("key already exists")
+)
+
+// XKeyInfo describes a chainkd key held by an XKeyStore.
+type XKeyInfo struct {
+	Alias string       `json:"alias"`
+	XPub  chainkd.XPub `json:"xpub"`
+	File  string       `json:"file"`
+}
+
+// PubHash returns the hash of the key's public key, which identifies the key
+// and the account it controls.
+func (k XKeyInfo) PubHash() []byte {
+	return ripemd160.Ripemd160(k.XPub.PublicKey())
+}
+
+type encryptedXKeyJSON struct {
+	Type   string     `json:"type"`
+	Alias  string     `json:"alias"`
+	XPub   string     `json:"xpub"`
+	Crypto cryptoJSON `json:"crypto"`
+	Id     string     `json:"id"`
+}
+
+type unlockedXKey struct {
+	xprv  *chainkd.XPrv
+	abort chan struct{}
+}
+
+// XKeyStore manages a directory of chainkd extended private keys, each
+// encrypted under its own passphrase in the format of the ECDSA key files.
+type XKeyStore struct {
+	keydir  string
+	scryptN int
+	scryptP int
+
+	mu       sync.RWMutex
+	keys     map[string]XKeyInfo      // Keys on disk, by public key hash
+	unlocked map[string]*unlockedXKey // Currently unlocked keys, by public key hash
+}
+
+// NewXKeyStore creates a chainkd keystore for the given directory.
+func NewXKeyStore(keydir string, scryptN, scryptP int) *XKeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &XKeyStore{
+		keydir:   keydir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		unlocked: make(map[string]*unlockedXKey),
+	}
+	ks.Refresh()
+	return ks
+}
+
+// Dir returns the key directory of the keystore.
+func (ks *XKeyStore) Dir() string {
+	return ks.keydir
+}
+
+// Refresh reloads the index of keys from the key directory, picking up key
+// files written by other processes.
+func (ks *XKeyStore) Refresh() {
+	keys := make(map[string]XKeyInfo)
+	files, err := ioutil.ReadDir(ks.keydir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Debug("Failed to read keystore directory", "dir", ks.keydir, "err", err)
+	}
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(ks.keydir, fi.Name())
+		k, err := readXKeyFile(path)
+		if err != nil {
+			continue
+		}
+		info := XKeyInfo{Alias: k.Alias, File: path}
+		if err := info.XPub.UnmarshalText([]byte(k.XPub)); err != nil {
+			log.Debug("Failed to decode chainkd key", "path", path, "err", err)
+			continue
+		}
+		keys[string(info.PubHash())] = info
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+}
+
+// readXKeyFile reads the chainkd key file at path, failing on ECDSA key files
+// and anything else that is not one.
+func readXKeyFile(path string) (*encryptedXKeyJSON, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k := new(encryptedXKeyJSON)
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	if k.Type != xkeyType {
+		return nil, fmt.Errorf("not a %s key file", xkeyType)
+	}
+	return k, nil
+}
+
+// Keys returns the keys in the keystore, ordered by alias.
+func (ks *XKeyStore) Keys() []XKeyInfo {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]XKeyInfo, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Alias < keys[j].Alias })
+	return keys
+}
+
+// Find returns the key whose public key hashes to pubHash.
+func (ks *XKeyStore) Find(pubHash []byte) (XKeyInfo, error) {
+	ks.mu.RLock()
+	k, ok := ks.keys[string(pubHash)]
+	ks.mu.RUnlock()
+	if !ok {
+		ks.Refresh()
+		ks.mu.RLock()
+		k, ok = ks.keys[string(pubHash)]
+		ks.mu.RUnlock()
+	}
+	if !ok {
+		return XKeyInfo{}, ErrNoMatch
+	}
+	return k, nil
+}
+
+// FindAlias returns the key with the given alias.
+func (ks *XKeyStore) FindAlias(alias string) (XKeyInfo, error) {
+	alias = normalizeAlias(alias)
+	for _, k := range ks.Keys() {
+		if k.Alias == alias {
+			return k, nil
+		}
+	}
+	return XKeyInfo{}, ErrNoMatch
+}
+
+// NewKey generates a new key and stores it into the key directory under alias,
+// encrypting it with the passphrase.
+func (ks *XKeyStore) NewKey(alias, passphrase string) (XKeyInfo, error) {
+	xprv, err := chainkd.NewXPrv(crand.Reader)
+	if err != nil {
+		return XKeyInfo{}, err
+	}
+	defer zeroXPrv(&xprv)
+	return ks.ImportXPrv(xprv, alias, passphrase)
+}
+
+// NewXPub generates a new key like NewKey, returning its public key.
+func (ks *XKeyStore) NewXPub(alias, passphrase string) (chainkd.XPub, error) {
+	info, err := ks.NewKey(alias, passphrase)
+	return info.XPub, err
+}
+
+// ImportXPrv stores the given key into the key directory under alias,
+// encrypting it with the passphrase.
+func (ks *XKeyStore) ImportXPrv(xprv chainkd.XPrv, alias, passphrase string) (XKeyInfo, error) {
+	alias = normalizeAlias(alias)
+	if alias == "" {
+		return XKeyInfo{}, ErrEmptyAlias
+	}
+	info := XKeyInfo{Alias: alias, XPub: xprv.XPub()}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.checkNew(info); err != nil {
+		return XKeyInfo{}, err
+	}
+	info.File = filepath.Join(ks.keydir, xkeyFileName(info.PubHash()))
+	if err := ks.storeKey(info, xprv, uuid.NewRandom(), passphrase); err != nil {
+		return XKeyInfo{}, err
+	}
+	ks.keys[string(info.PubHash())] = info
+	return info, nil
+}
+
+// checkNew fails if info repeats the key or alias of a stored key.
+func (ks *XKeyStore) checkNew(info XKeyInfo) error {
+	pubHash := string(info.PubHash())
+	for hash, k := range ks.keys {
+		if hash == pubHash {
+			return ErrXKeyExists
+		}
+		if k.Alias == info.Alias {
+			return ErrDuplicateAlias
+		}
+	}
+	return nil
+}
+
+// storeKey encrypts xprv with the passphrase and writes it to info.File.
+func (ks *XKeyStore) storeKey(info XKeyInfo, xprv chainkd.XPrv, id uuid.UUID, passphrase string) error {
+	keyJSON, err := encryptXKey(info, xprv, id, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(info.File, keyJSON)
+}
+
+func encryptXKey(info XKeyInfo, xprv chainkd.XPrv, id uuid.UUID, auth string, scryptN, scryptP int) ([]byte, error) {
+	cryptoStruct, err := encryptData(xprv[:], auth, scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedXKeyJSON{
+		Type:   xkeyType,
+		Alias:  info.Alias,
+		XPub:   info.XPub.String(),
+		Crypto: cryptoStruct,
+		Id:     id.String(),
+	})
+}
+
+// decryptXKey decrypts a chainkd key file, checking the key matches the xpub
+// stored beside it.
+func decryptXKey(k *encryptedXKeyJSON, auth string) (chainkd.XPrv, error) {
+	var xprv chainkd.XPrv
+	plainText, err := decryptData(k.Crypto, auth)
+	if err != nil {
+		return xprv, err
+	}
+	if len(plainText) != len(xprv) {
+		return xprv, fmt.Errorf("key has %d bytes, want %d", len(plainText), len(xprv))
+	}
+	copy(xprv[:], plainText)
+	if xpub := xprv.XPub().String(); xpub != k.XPub {
+		zeroXPrv(&xprv)
+		return xprv, fmt.Errorf("key content mismatch: have xpub %s, want %s", xpub, k.XPub)
+	}
+	return xprv, nil
+}
+
+// getDecryptedKey reads and decrypts the key whose public key hashes to
+// pubHash.
+func (ks *XKeyStore) getDecryptedKey(pubHash []byte, auth string) (XKeyInfo, *encryptedXKeyJSON, chainkd.XPrv, error) {
+	info, err := ks.Find(pubHash)
+	if err != nil {
+		return info, nil, chainkd.XPrv{}, err
+	}
+	k, err := readXKeyFile(info.File)
+	if err != nil {
+		return info, nil, chainkd.XPrv{}, err
+	}
+	xprv, err := decryptXKey(k, auth)
+	return info, k, xprv, err
+}
+
+// XPrv returns the key whose public key hashes to pubHash. An unlocked key is
+// returned as is, any other is decrypted with the password.
+func (ks *XKeyStore) XPrv(pubHash []byte, password string) (chainkd.XPrv, error) {
+	// The key is copied under the lock, as expire zeroes it under the lock.
+	ks.mu.RLock()
+	u, found := ks.unlocked[string(pubHash)]
+	var xprv chainkd.XPrv
+	if found {
+		xprv = *u.xprv
+	}
+	ks.mu.RUnlock()
+	if found {
+		return xprv, nil
+	}
+	_, _, xprv, err := ks.getDecryptedKey(pubHash, password)
+	return xprv, err
+}
+
+// Unlock unlocks the given key indefinitely.
+func (ks *XKeyStore) Unlock(pubHash []byte, passphrase string) error {
+	return ks.TimedUnlock(pubHash, passphrase, 0)
+}
+
+// Lock removes the private key with the given public key hash from memory.
+func (ks *XKeyStore) Lock(pubHash []byte) error {
+	ks.mu.Lock()
+	if u, found := ks.unlocked[string(pubHash)]; found {
+		ks.mu.Unlock()
+		ks.expire(string(pubHash), u, 0)
+	} else {
+		ks.mu.Unlock()
+	}
+	return nil
+}
+
+// TimedUnlock unlocks the given key with the passphrase. The key stays
+// unlocked for the duration of timeout. A timeout of 0 unlocks the key until
+// the program exits.
+//
+// If the key is already unlocked for a duration, TimedUnlock extends or
+// shortens the active unlock timeout. If the key was previously unlocked
+// indefinitely the timeout is not altered.
+func (ks *XKeyStore) TimedUnlock(pubHash []byte, passphrase string, timeout time.Duration) error {
+	_, _, xprv, err := ks.getDecryptedKey(pubHash, passphrase)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	u, found := ks.unlocked[string(pubHash)]
+	if found {
+		if u.abort == nil {
+			// The key was unlocked indefinitely, so unlocking
+			// it with a timeout would be confusing.
+			zeroXPrv(&xprv)
+			return nil
+		}
+		// Terminate the expire goroutine and replace it below.
+		close(u.abort)
+	}
+	if timeout > 0 {
+		u = &unlockedXKey{xprv: &xprv, abort: make(chan struct{})}
+		go ks.expire(string(pubHash), u, timeout)
+	} else {
+		u = &unlockedXKey{xprv: &xprv}
+	}
+	ks.unlocked[string(pubHash)] = u
+	return nil
+}
+
+// expire locks the key unlocked as u once timeout passes, unless it is
+// unlocked again before.
+func (ks *XKeyStore) expire(pubHash string, u *unlockedXKey, timeout time.Duration) {
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-u.abort:
+			return
+		case <-t.C:
+		}
+	}
+	ks.mu.Lock()
+	if ks.unlocked[pubHash] == u {
+		zeroXPrv(u.xprv)
+		delete(ks.unlocked, pubHash)
+	}
+	ks.mu.Unlock()
+}
+
+// Update changes the passphrase of an existing key.
+func (ks *XKeyStore) Update(pubHash []byte, passphrase, newPassphrase string) error {
+	info, k, xprv, err := ks.getDecryptedKey(pubHash, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroXPrv(&xprv)
+	return ks.storeKey(info, xprv, uuid.Parse(k.Id), newPassphrase)
+}
+
+// SetAlias renames an existing key. The key file is rewritten without
+// decrypting the key.
+func (ks *XKeyStore) SetAlias(pubHash []byte, alias string) error {
+	alias = normalizeAlias(alias)
+	if alias == "" {
+		return ErrEmptyAlias
+	}
+	info, err := ks.Find(pubHash)
+	if err != nil {
+		return err
+	}
+	k, err := readXKeyFile(info.File)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	for hash, other := range ks.keys {
+		if other.Alias == alias && hash != string(pubHash) {
+			return ErrDuplicateAlias
+		}
+	}
+	k.Alias = alias
+	keyJSON, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyFile(info.File, keyJSON); err != nil {
+		return err
+	}
+	info.Alias = alias
+	ks.keys[string(pubHash)] = info
+	return nil
+}
+
+// Delete deletes the key matched by pubHash if the passphrase is correct.
+func (ks *XKeyStore) Delete(pubHash []byte, passphrase string) error {
+	// Decrypting the key isn't really necessary, but we do
+	// it anyway to check the password and zero out the key
+	// immediately afterwards.
+	info, _, xprv, err := ks.getDecryptedKey(pubHash, passphrase)
+	if err != nil {
+		return err
+	}
+	zeroXPrv(&xprv)
+	if err := os.Remove(info.File); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	delete(ks.keys, string(pubHash))
+	u := ks.unlocked[string(pubHash)]
+	ks.mu.Unlock()
+	if u != nil {
+		ks.expire(string(pubHash), u, 0)
+	}
+	return nil
+}
+
+// Export exports the key as an encrypted key file, encrypted with
+// newPassphrase.
+func (ks *XKeyStore) Export(pubHash []byte, passphrase, newPassphrase string) ([]byte, error) {
+	info, k, xprv, err := ks.getDecryptedKey(pubHash, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroXPrv(&xprv)
+	return encryptXKey(info, xprv, uuid.Parse(k.Id), newPassphrase, ks.scryptN, ks.scryptP)
+}
+
+// Import stores the given encrypted key file into the key directory,
+// re-encrypted with newPassphrase. The key keeps the alias it was exported
+// with.
+func (ks *XKeyStore) Import(keyJSON []byte, passphrase, newPassphrase string) (XKeyInfo, error) {
+	return ks.ImportAs(keyJSON, passphrase, newPassphrase, "")
+}
+
+// ImportAs is like Import, storing the key under alias instead unless alias
+// is empty.
+func (ks *XKeyStore) ImportAs(keyJSON []byte, passphrase, newPassphrase, alias string) (XKeyInfo, error) {
+	k := new(encryptedXKeyJSON)
+	if err := json.Unmarshal(keyJSON, k); err != nil {
+		return XKeyInfo{}, err
+	}
+	if k.Type != xkeyType {
+		return XKeyInfo{}, fmt.Errorf("not a %s key file", xkeyType)
+	}
+	xprv, err := decryptXKey(k, passphrase)
+	if err != nil {
+		return XKeyInfo{}, err
+	}
+	defer zeroXPrv(&xprv)
+	if alias == "" {
+		alias = k.Alias
+	}
+	return ks.ImportXPrv(xprv, alias, newPassphrase)
+}
+
+// xkeyFileName implements the naming convention for chainkd key files:
+// UTC--<created_at UTC ISO8601>--<public key hash hex>.
+func xkeyFileName(pubHash []byte) string {
+	return fmt.Sprintf("UTC--%s--%s", toISO8601(time.Now().UTC()), hex.EncodeToString(pubHash))
+}
+
+func normalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// zeroXPrv zeroes a private key in memory.
+func zeroXPrv(xprv *chainkd.XPrv) {
+	for i := range xprv {
+		xprv[i] = 0
+	}
+}
@@ -0,0 +1,169 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	veryLightScryptN = 2
+	veryLightScryptP = 1
+)
+
+func tmpXKeyStore(t *testing.T) (string, *XKeyStore) {
+	dir, err := ioutil.TempDir("", "srcd-xkeystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, NewXKeyStore(dir, veryLightScryptN, veryLightScryptP)
+}
+
+func TestXKeyStoreLifecycle(t *testing.T) {
+	dir, ks := tmpXKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	info, err := ks.NewKey(" Alice ", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Alias != "alice" {
+		t.Errorf("alias = %q, want alice", info.Alias)
+	}
+	if _, err := ks.NewKey("alice", "bar"); err != ErrDuplicateAlias {
+		t.Errorf("duplicate alias: err = %v, want %v", err, ErrDuplicateAlias)
+	}
+
+	xprv, err := ks.XPrv(info.PubHash(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xprv.XPub() != info.XPub {
+		t.Fatal("decrypted key does not match its xpub")
+	}
+	if _, err := ks.XPrv(info.PubHash(), "bar"); err != ErrDecrypt {
+		t.Errorf("wrong password: err = %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := ks.ImportXPrv(xprv, "bob", "bar"); err != ErrXKeyExists {
+		t.Errorf("reimport: err = %v, want %v", err, ErrXKeyExists)
+	}
+
+	// A second keystore over the directory finds the key on disk.
+	if found, err := NewXKeyStore(dir, veryLightScryptN, veryLightScryptP).FindAlias("ALICE"); err != nil || found.XPub != info.XPub {
+		t.Fatalf("key not found on disk: %v", err)
+	}
+
+	if err := ks.Update(info.PubHash(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.XPrv(info.PubHash(), "foo"); err != ErrDecrypt {
+		t.Errorf("old password after update: err = %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.SetAlias(info.PubHash(), "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.XPrv(info.PubHash(), "bar"); err != nil {
+		t.Errorf("key unreadable after rename: %v", err)
+	}
+
+	if err := ks.Delete(info.PubHash(), "foo"); err != ErrDecrypt {
+		t.Errorf("delete with wrong password: err = %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.Delete(info.PubHash(), "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(info.File); !os.IsNotExist(err) {
+		t.Error("key file still exists after delete")
+	}
+	if len(ks.Keys()) != 0 {
+		t.Errorf("keystore holds %d keys after delete", len(ks.Keys()))
+	}
+}
+
+func TestXKeyStoreTimedUnlock(t *testing.T) {
+	dir, ks := tmpXKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	info, err := ks.NewKey("alice", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.TimedUnlock(info.PubHash(), "bar", time.Second); err != ErrDecrypt {
+		t.Fatalf("unlock with wrong password: err = %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.TimedUnlock(info.PubHash(), "foo", 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if xprv, err := ks.XPrv(info.PubHash(), ""); err != nil || xprv.XPub() != info.XPub {
+		t.Fatalf("unlocked key not available: %v", err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if _, err := ks.XPrv(info.PubHash(), ""); err != ErrDecrypt {
+		t.Errorf("key still unlocked after timeout: err = %v", err)
+	}
+
+	if err := ks.Unlock(info.PubHash(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	ks.Lock(info.PubHash())
+	if _, err := ks.XPrv(info.PubHash(), ""); err != ErrDecrypt {
+		t.Errorf("key still unlocked after lock: err = %v", err)
+	}
+
+	// A key read while it is locked is whole or not returned.
+	if err := ks.Unlock(info.PubHash(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if xprv, err := ks.XPrv(info.PubHash(), ""); err == nil && xprv.XPub() != info.XPub {
+				t.Error("read a partly locked key")
+				return
+			}
+		}
+	}()
+	ks.Lock(info.PubHash())
+	<-done
+}
+
+func TestXKeyStoreExportImport(t *testing.T) {
+	dir, ks := tmpXKeyStore(t)
+	defer os.RemoveAll(dir)
+	dir2, ks2 := tmpXKeyStore(t)
+	defer os.RemoveAll(dir2)
+
+	info, err := ks.NewKey("alice", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := ks.Export(info.PubHash(), "foo", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks2.Import(keyJSON, "foo", "bar"); err != ErrDecrypt {
+		t.Errorf("import with wrong password: err = %v, want %v", err, ErrDecrypt)
+	}
+	imported, err := ks2.Import(keyJSON, "export", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.XPub != info.XPub || imported.Alias != "alice" {
+		t.Errorf("imported %s %q, want %s alice", imported.XPub, imported.Alias, info.XPub)
+	}
+	if _, err := ks2.XPrv(info.PubHash(), "bar"); err != nil {
+		t.Error(err)
+	}
+
+	dir3, ks3 := tmpXKeyStore(t)
+	defer os.RemoveAll(dir3)
+	renamed, err := ks3.ImportAs(keyJSON, "export", "bar", "Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Alias != "bob" {
+		t.Errorf("imported as %q, want bob", renamed.Alias)
+	}
+}
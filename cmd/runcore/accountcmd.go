@@ -1,39 +1,57 @@
 package main
 
 import (
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/account/mnemonic"
+	"github.com/srchain/srcd/account/wallet"
 	"github.com/srchain/srcd/accounts/keystore"
 	"github.com/srchain/srcd/cmd/utils"
 	"github.com/srchain/srcd/console"
-	"github.com/srchain/srcd/crypto/crypto"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+	"github.com/srchain/srcd/node"
 
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	importMnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Import the key derived from a mnemonic phrase, prompted for, instead of a key file",
+	}
+
 	accountCommand = cli.Command{
 		Name:     "account",
 		Usage:    "Manage accounts",
 		Category: "ACCOUNT COMMANDS",
 		Description: `
 
-Manage accounts, list all existing accounts, import a private key into a new
-account, create a new account or update an existing account.
+Manage accounts: list the existing accounts, create a new account, import an
+account from an encrypted key file or a mnemonic phrase, export the key of an
+account or change its passphrase.
 
-It supports interactive mode, when you are prompted for password as well as
-non-interactive mode where passwords are supplied via a given password file.
-Non-interactive mode is only meant for scripted use on test networks or known
-safe environments.
+The account commands work on the keystore of the node, which need not and, for
+the commands recording accounts in the wallet, must not be running. Passphrases
+are prompted for, or read one per line from the file given by --password.
+Password files are only meant for scripted use on test networks or known safe
+environments.
 
-Make sure you remember the password you gave when creating a new account (with
-either new or import). Without it you are not able to unlock your account.
+Make sure you remember the passphrase you gave when creating a new account
+(with either new or import). Without it you are not able to unlock your
+account.
 
 Note that exporting your key in unencrypted format is NOT supported.
 
-Keys are stored under <DATADIR>/keystore.
+Keys are stored under <DATADIR>/keystore, or the directory given by --keystore.
 It is safe to transfer the entire directory or the individual keys therein
-between ethereum nodes by simply copying.
+between srcd nodes by simply copying.
 
 Make sure you backup your keys regularly.`,
 		Subcommands: []cli.Command{
@@ -46,7 +64,10 @@ Make sure you backup your keys regularly.`,
 					utils.KeyStoreDirFlag,
 				},
 				Description: `
-Print a short summary of all accounts`,
+    srcd account list
+
+Prints the alias, ID, address and key file of every key in the keystore. The
+ID is the hash of the key's public key, which names its key file.`,
 			},
 			{
 				Name:   "new",
@@ -55,6 +76,11 @@ Print a short summary of all accounts`,
 				Flags: []cli.Flag{
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
+					utils.AccountAliasFlag,
+					utils.PasswordFileFlag,
+					utils.MnemonicFlag,
+					utils.MnemonicLangFlag,
+					utils.MnemonicPassphraseFlag,
 				},
 				Description: `
     srcd account new
@@ -67,38 +93,501 @@ You must remember this passphrase to unlock your account in the future.
 
 For non-interactive use the passphrase can be specified with the --password flag:
 
+    srcd account new --alias <alias> --password <passwordfile>
+
 Note, this is meant to be used for testing only, it is a bad idea to save your
 password to file or expose in any other way.
+
+With --mnemonic the account key is derived from a new mnemonic phrase, which is
+printed once. Write it down: it is enough to recover the account with
+
+    srcd account recover
+
+With --mnemonicpassphrase you are also prompted for a passphrase protecting the
+phrase. Recovering the account then takes both.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import an account from a key file or a mnemonic phrase",
+				ArgsUsage: "<keyfile>",
+				Action:    utils.MigrateFlags(accountImport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.AccountAliasFlag,
+					utils.PasswordFileFlag,
+					importMnemonicFlag,
+					utils.MnemonicLangFlag,
+					utils.MnemonicPassphraseFlag,
+				},
+				Description: `
+    srcd account import <keyfile>
+    srcd account import --mnemonic
+
+Imports the key of an account into the keystore and records the account in
+the wallet, printing its address.
+
+A key file, as written by srcd account export, is unlocked with its passphrase
+and stored encrypted with a new one; with --password the file gives them on
+its first and second lines. The key keeps the alias it was exported with
+unless --alias is given.
+
+With --mnemonic the key is derived from a mnemonic phrase, which is prompted
+for, as srcd account recover does without restoring the derived addresses or
+rescanning. Give --mnemonicpassphrase if the phrase has a passphrase.`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the key of an account as an encrypted key file",
+				ArgsUsage: "<alias|id|address> <keyfile>",
+				Action:    utils.MigrateFlags(accountExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    srcd account export <alias|id|address> <keyfile>
+
+Writes the key of an account to a new key file, encrypted with a passphrase of
+its own, for srcd account import on another node. You are prompted for the
+passphrase of the key and the one of the file; with --password the file gives
+them on its first and second lines.`,
+			},
+			{
+				Name:      "update",
+				Usage:     "Change the passphrase of an account",
+				ArgsUsage: "<alias|id|address>",
+				Action:    utils.MigrateFlags(accountUpdate),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    srcd account update <alias|id|address>
+
+Unlocks the key of an account with its passphrase and encrypts it again with a
+new one. With --password the file gives the old passphrase on its first line
+and the new one on its second.`,
+			},
+			{
+				Name:   "recover",
+				Usage:  "Recover an account from its mnemonic phrase",
+				Action: utils.MigrateFlags(accountRecover),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.AccountAliasFlag,
+					utils.PasswordFileFlag,
+					utils.MnemonicLangFlag,
+					utils.MnemonicPassphraseFlag,
+				},
+				Description: `
+    srcd account recover
+
+Prompts for the mnemonic phrase of an account created with --mnemonic, puts its
+key back into the keystore, encrypted with a new passphrase, and restores the
+account in the wallet.
+
+The receive and change addresses derived from the key are restored with it, and
+the wallet rescans the chain from its birthday for the outputs paying to them
+when the node next starts. The node must not be running.
+
+The language of the phrase is detected unless given with --mnemoniclang. Give
+--mnemonicpassphrase to be prompted for the passphrase the phrase was created
+with.
 `,
 			},
 		},
 	}
 )
 
+// accountList prints the keys of the keystore and the accounts they control.
 func accountList(ctx *cli.Context) error {
-	//stack, _ := makeConfigNode(ctx)
-	//var index int
-	//for _, wallet := range stack.AccountManager().Wallets() {
-	//	for _, account := range wallet.Accounts() {
-	//		fmt.Printf("Account #%d: {%x} %s\n", index, account.Address, &account.URL)
-	//		index++
-	//	}
-	//}
-	return nil
+	ks := openKeyStore(ctx, true)
+	keys := ks.Keys()
+	if len(keys) == 0 {
+		fmt.Printf("No accounts in %s\n", ks.Dir())
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tID\tADDRESS\tFILE")
+	for _, info := range keys {
+		fmt.Fprintf(w, "%s\t%x\t%s\t%s\n", info.Alias, info.PubHash(), keyAddress(info), info.File)
+	}
+	return w.Flush()
 }
 
-
 // accountCreate creates a new account into the keystore defined by the CLI flags.
 func accountCreate(ctx *cli.Context) error {
-	cfg := config{Node: defaultNodeConfig()}
-	// Load config file.
-	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
-		if err := loadConfig(file, &cfg); err != nil {
-			utils.Fatalf("%v", err)
+	am, ks, closeAccounts := openAccounts(ctx)
+	defer closeAccounts()
+	alias := getAlias(ctx)
+	password := getNewPassword("Your new account is locked with a password. Please give a password. Do not forget this password.", 0, passwordList(ctx))
+
+	if !ctx.Bool(utils.MnemonicFlag.Name) {
+		acc, err := am.CreateAccount(alias, password)
+		if err != nil {
+			utils.Fatalf("Failed to create account: %v", err)
+		}
+		fmt.Printf("Address: %s\n", acc.Address)
+		return nil
+	}
+
+	language := ctx.String(utils.MnemonicLangFlag.Name)
+	if language == "" {
+		language = mnemonic.DefaultLanguage
+	}
+	entropy, err := mnemonic.NewEntropy(mnemonic.DefaultEntropyBits)
+	if err != nil {
+		utils.Fatalf("Failed to generate mnemonic: %v", err)
+	}
+	phrase, err := mnemonic.New(entropy, language)
+	if err != nil {
+		utils.Fatalf("Failed to generate mnemonic: %v", err)
+	}
+	xprv, err := mnemonic.RootXPrv(phrase, getMnemonicPassphrase(ctx, true), language)
+	if err != nil {
+		utils.Fatalf("Failed to derive account key: %v", err)
+	}
+	acc := importAccount(am, ks, xprv, alias, password)
+	fmt.Printf("Address: %s\n", acc.Address)
+	fmt.Println()
+	fmt.Println("Mnemonic phrase:")
+	fmt.Println()
+	fmt.Printf("    %s\n", phrase)
+	fmt.Println()
+	fmt.Println("Write the phrase down and keep it safe. Anyone who has it controls the account,")
+	fmt.Println("and it is the only way to recover the account if the keystore is lost.")
+	return nil
+}
+
+// accountImport imports the key of an account from a key file or a mnemonic
+// phrase, and records the account in the wallet.
+func accountImport(ctx *cli.Context) error {
+	if ctx.Bool(importMnemonicFlag.Name) {
+		if ctx.NArg() != 0 {
+			utils.Fatalf("Usage: srcd account import --mnemonic [options]")
 		}
+		am, ks, closeAccounts := openAccounts(ctx)
+		defer closeAccounts()
+		acc := restoreAccount(ctx, am, ks, readMnemonicKey(ctx))
+		fmt.Printf("Address: %s\n", acc.Address)
+		return nil
+	}
+
+	if ctx.NArg() != 1 {
+		utils.Fatalf("Usage: srcd account import [options] <keyfile>")
+	}
+	keyJSON, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read key file: %v", err)
+	}
+	am, ks, closeAccounts := openAccounts(ctx)
+	defer closeAccounts()
+	passwords := passwordList(ctx)
+	password := getPassword("Unlocking the key file", 0, passwords)
+	newPassword := getNewPassword("The imported account is locked with a password. Please give a password. Do not forget this password.", 1, passwords)
+	info, err := ks.ImportAs(keyJSON, password, newPassword, ctx.String(utils.AccountAliasFlag.Name))
+	switch err {
+	case nil:
+	case keystore.ErrDuplicateAlias:
+		utils.Fatalf("Failed to import key: %v; choose another with --%s", err, utils.AccountAliasFlag.Name)
+	default:
+		utils.Fatalf("Failed to import key: %v", err)
+	}
+	acc, err := am.AddAccount(info.XPub)
+	if err != nil {
+		utils.Fatalf("Failed to record account: %v", err)
+	}
+	fmt.Printf("Alias: %s\n", info.Alias)
+	fmt.Printf("Address: %s\n", acc.Address)
+	return nil
+}
+
+// accountExport writes the key of an account to a new encrypted key file.
+func accountExport(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		utils.Fatalf("Usage: srcd account export [options] <alias|id|address> <keyfile>")
+	}
+	ks := openKeyStore(ctx, false)
+	info := findKey(ks, ctx.Args().Get(0))
+	path := ctx.Args().Get(1)
+	if _, err := os.Stat(path); err == nil {
+		utils.Fatalf("Key file %s already exists", path)
 	}
 
-	utils.SetNodeConfig(ctx, &cfg.Node)
+	passwords := passwordList(ctx)
+	password := getPassword(fmt.Sprintf("Unlocking key %q", info.Alias), 0, passwords)
+	newPassword := getNewPassword("The key file is locked with a password. Please give a password. Do not forget this password.", 1, passwords)
+	keyJSON, err := ks.Export(info.PubHash(), password, newPassword)
+	if err != nil {
+		utils.Fatalf("Failed to export key %q: %v", info.Alias, err)
+	}
+	if err := ioutil.WriteFile(path, keyJSON, 0600); err != nil {
+		utils.Fatalf("Failed to write key file: %v", err)
+	}
+	fmt.Printf("Key %q written to %s\n", info.Alias, path)
 	return nil
 }
 
+// accountUpdate changes the passphrase of the key of an account.
+func accountUpdate(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("Usage: srcd account update [options] <alias|id|address>")
+	}
+	ks := openKeyStore(ctx, false)
+	info := findKey(ks, ctx.Args().First())
+
+	passwords := passwordList(ctx)
+	password := getPassword(fmt.Sprintf("Unlocking key %q", info.Alias), 0, passwords)
+	newPassword := getNewPassword("Please give a new password. Do not forget this password.", 1, passwords)
+	if err := ks.Update(info.PubHash(), password, newPassword); err != nil {
+		utils.Fatalf("Failed to update key %q: %v", info.Alias, err)
+	}
+	fmt.Printf("Passphrase of key %q changed\n", info.Alias)
+	return nil
+}
+
+// accountRecover restores the account derived from a mnemonic phrase into the
+// keystore and the wallet, along with its derived addresses, and has the
+// wallet rescan for the outputs paying to them.
+func accountRecover(ctx *cli.Context) error {
+	am, ks, closeAccounts := openAccounts(ctx)
+	defer closeAccounts()
+	acc := restoreAccount(ctx, am, ks, readMnemonicKey(ctx))
+	fmt.Printf("Address: %s\n", acc.Address)
+
+	rescanAccount(makeConfig(ctx).Node, am, acc)
+	return nil
+}
+
+// readMnemonicKey prompts for a mnemonic phrase and returns the account key
+// derived from it.
+func readMnemonicKey(ctx *cli.Context) chainkd.XPrv {
+	phrase, err := console.Stdin.PromptPassword("Mnemonic phrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read mnemonic phrase: %v", err)
+	}
+	language := ctx.String(utils.MnemonicLangFlag.Name)
+	if language == "" {
+		if language, err = mnemonic.DetectLanguage(phrase); err != nil {
+			utils.Fatalf("Failed to detect mnemonic language: %v", err)
+		}
+	}
+	xprv, err := mnemonic.RootXPrv(phrase, getMnemonicPassphrase(ctx, false), language)
+	if err != nil {
+		utils.Fatalf("Invalid mnemonic phrase: %v", err)
+	}
+	return xprv
+}
+
+// getMnemonicPassphrase prompts for the passphrase protecting a mnemonic
+// phrase if --mnemonicpassphrase is set, asking for it twice if confirmation
+// is set. Phrases have no passphrase otherwise.
+func getMnemonicPassphrase(ctx *cli.Context, confirmation bool) string {
+	if !ctx.Bool(utils.MnemonicPassphraseFlag.Name) {
+		return ""
+	}
+	return getPassPhrase("The mnemonic phrase is protected with a passphrase. Without it the phrase does not recover the account.", confirmation)
+}
+
+// restoreAccount records the account controlled by xprv in the wallet,
+// storing the key in the keystore unless it is there already.
+func restoreAccount(ctx *cli.Context, am *account.AccountManager, ks *keystore.XKeyStore, xprv chainkd.XPrv) account.Account {
+	info, err := ks.Find(ripemd160.Ripemd160(xprv.XPub().PublicKey()))
+	switch err {
+	case nil:
+		fmt.Printf("Key already in the keystore as %q\n", info.Alias)
+		acc, err := am.AddAccount(info.XPub)
+		if err != nil {
+			utils.Fatalf("Failed to restore account: %v", err)
+		}
+		return acc
+	case keystore.ErrNoMatch:
+		alias := getAlias(ctx)
+		password := getNewPassword("The recovered account is locked with a password. Please give a password. Do not forget this password.", 0, passwordList(ctx))
+		return importAccount(am, ks, xprv, alias, password)
+	default:
+		utils.Fatalf("Failed to read keystore: %v", err)
+	}
+	return account.Account{}
+}
+
+// importAccount stores xprv in the keystore and records the account it
+// controls in the wallet.
+func importAccount(am *account.AccountManager, ks *keystore.XKeyStore, xprv chainkd.XPrv, alias, password string) account.Account {
+	info, err := ks.ImportXPrv(xprv, alias, password)
+	if err != nil {
+		utils.Fatalf("Failed to store account key: %v", err)
+	}
+	acc, err := am.AddAccount(info.XPub)
+	if err != nil {
+		utils.Fatalf("Failed to record account: %v", err)
+	}
+	return acc
+}
+
+// rescanAccount records the receive and change programs of acc, so that the
+// wallet recognizes the outputs paying to them, and schedules a rescan of
+// the chain from the wallet's birthday, which the node runs when it starts.
+func rescanAccount(cfg node.Config, am *account.AccountManager, acc account.Account) {
+	if err := am.RecoverPrograms(acc.Address, 0); err != nil {
+		utils.Fatalf("Failed to derive account addresses: %v", err)
+	}
+	walletdb, err := cfg.OpenDatabase("wallet", 0, 0)
+	if err != nil {
+		utils.Fatalf("Failed to open wallet database (is the node running?): %v", err)
+	}
+	defer walletdb.Close()
+
+	from, ok, err := wallet.ScheduleRescan(walletdb, nil)
+	if err != nil {
+		utils.Fatalf("Failed to schedule wallet rescan: %v", err)
+	}
+	if ok {
+		fmt.Printf("The wallet rescans the chain from block %d when the node starts\n", from)
+	} else {
+		fmt.Println("The wallet scans the chain when the node first starts")
+	}
+}
+
+// openAccounts opens the keystore and the account records of the node
+// configured by the CLI flags without creating the node, which must not be
+// running. The returned function closes the account records.
+func openAccounts(ctx *cli.Context) (*account.AccountManager, *keystore.XKeyStore, func()) {
+	ks := openKeyStore(ctx, true)
+	cfg := makeConfig(ctx).Node
+	db, err := cfg.OpenAccountDB()
+	if err != nil {
+		utils.Fatalf("Failed to open account records (is the node running?): %v", err)
+	}
+	am := account.NewAccountManager(db)
+	am.SetKeyStore(ks)
+	return am, ks, db.Close
+}
+
+// openKeyStore opens the keystore directory of the node configured by the
+// CLI flags, creating it if create is set.
+func openKeyStore(ctx *cli.Context, create bool) *keystore.XKeyStore {
+	cfg := makeConfig(ctx).Node
+	scryptN, scryptP, keydir, err := cfg.AccountConfig()
+	if err != nil || keydir == "" {
+		utils.Fatalf("No keystore directory: give one with --%s", utils.KeyStoreDirFlag.Name)
+	}
+	if create {
+		err = os.MkdirAll(keydir, 0700)
+	} else {
+		_, err = os.Stat(keydir)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to open keystore: %v", err)
+	}
+	return keystore.NewXKeyStore(keydir, scryptN, scryptP)
+}
+
+// findKey returns the key of ks named by its alias, its ID or the address of
+// the account it controls.
+func findKey(ks *keystore.XKeyStore, name string) keystore.XKeyInfo {
+	if info, err := ks.FindAlias(name); err == nil {
+		return info
+	}
+	if id, err := hex.DecodeString(name); err == nil {
+		if info, err := ks.Find(id); err == nil {
+			return info
+		}
+	}
+	for _, info := range ks.Keys() {
+		if keyAddress(info) == name {
+			return info
+		}
+	}
+	utils.Fatalf("No key %q in %s", name, ks.Dir())
+	return keystore.XKeyInfo{}
+}
+
+// keyAddress returns the address of the account controlled by the key info.
+func keyAddress(info keystore.XKeyInfo) string {
+	program, _, err := account.CreateP2PKH(info.XPub)
+	if err != nil {
+		return ""
+	}
+	return program.Address
+}
+
+// getAlias returns the alias of the account key given by --alias, prompting
+// for it if the flag is not set.
+func getAlias(ctx *cli.Context) string {
+	if alias := ctx.String(utils.AccountAliasFlag.Name); alias != "" {
+		return alias
+	}
+	alias, err := console.Stdin.PromptInput("Alias for the account key: ")
+	if err != nil {
+		utils.Fatalf("Failed to read alias: %v", err)
+	}
+	return alias
+}
+
+// getPassPhrase requests the password of an account interactively, asking for
+// it twice if confirmation is set.
+func getPassPhrase(prompt string, confirmation bool) string {
+	if prompt != "" {
+		fmt.Println(prompt)
+	}
+	password, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		utils.Fatalf("Failed to read passphrase: %v", err)
+	}
+	if confirmation {
+		confirm, err := console.Stdin.PromptPassword("Repeat passphrase: ")
+		if err != nil {
+			utils.Fatalf("Failed to read passphrase confirmation: %v", err)
+		}
+		if password != confirm {
+			utils.Fatalf("Passphrases do not match")
+		}
+	}
+	return password
+}
+
+// getNewPassword returns password i of the --password file like getPassword,
+// or else prompts for a new password, asking for it twice.
+func getNewPassword(prompt string, i int, passwords []string) string {
+	if len(passwords) > 0 {
+		return getPassword(prompt, i, passwords)
+	}
+	return getPassPhrase(prompt, true)
+}
+
+// passwordList returns the passwords in the file given by --password, one per
+// line, or nil if the flag is not set.
+func passwordList(ctx *cli.Context) []string {
+	path := ctx.String(utils.PasswordFileFlag.Name)
+	if path == "" {
+		return nil
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to read password file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	// Sanitise DOS line endings.
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	return lines
+}
+
+// getPassword returns password i of the --password file, the last one if the
+// file has fewer, or else prompts for it.
+func getPassword(prompt string, i int, passwords []string) string {
+	if len(passwords) > 0 {
+		if i < len(passwords) {
+			return passwords[i]
+		}
+		return passwords[len(passwords)-1]
+	}
+	return getPassPhrase(prompt, false)
+}
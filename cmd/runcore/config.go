@@ -65,11 +65,14 @@ func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = "srcd"
 	cfg.Version = params.Version
+	cfg.IPCPath = "srcd.ipc"
 
 	return cfg
 }
 
-func makeConfigNode(ctx *cli.Context) (*node.Node, config) {
+// makeConfig loads the config file and applies the command line flags,
+// without creating the node.
+func makeConfig(ctx *cli.Context) config {
 	// Default config.
 	cfg := config{
 		Server: server.DefaultConfig,
@@ -85,6 +88,15 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, config) {
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node)
+	utils.SetNetworkId(ctx, &cfg.Server)
+
+	// Commands run without a node encode addresses for its network too.
+	params.ActiveNetParams = params.NetParamsForNetwork(cfg.Server.NetworkId)
+	return cfg
+}
+
+func makeConfigNode(ctx *cli.Context) (*node.Node, config) {
+	cfg := makeConfig(ctx)
 
 	node, err := node.New(&cfg.Node)
 	if err != nil {
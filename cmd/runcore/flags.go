@@ -11,10 +11,30 @@ var (
 	nodeFlags = []cli.Flag{
 		utils.IdentityFlag,
 		utils.DataDirFlag,
+		utils.TestnetFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		configFileFlag,
 	}
 
-	rpcFlags = []cli.Flag{}
+	rpcFlags = []cli.Flag{
+		utils.RPCEnabledFlag,
+		utils.RPCListenAddrFlag,
+		utils.RPCPortFlag,
+		utils.RPCCORSDomainFlag,
+		utils.RPCVirtualHostsFlag,
+		utils.RPCApiFlag,
+		utils.IPCDisabledFlag,
+		utils.IPCPathFlag,
+		utils.WSEnabledFlag,
+		utils.WSListenAddrFlag,
+		utils.WSPortFlag,
+		utils.WSApiFlag,
+		utils.WSAllowedOriginsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCBasicAuthFlag,
+		utils.RPCPublicApiFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+	}
 )
@@ -4,11 +4,12 @@ import (
 	"fmt"
 	"os"
 	"runtime"
-	"runtime/debug"
+	godebug "runtime/debug"
 	"sort"
 
 	"github.com/srchain/srcd/cmd/utils"
 	"github.com/srchain/srcd/console"
+	"github.com/srchain/srcd/internal/debug"
 
 	"gopkg.in/urfave/cli.v1"
 )
@@ -24,11 +25,17 @@ func init() {
 	app.Commands = []cli.Command{
 		initCommand,
 		accountCommand,
+		consoleCommand,
+		attachCommand,
+		txCommand,
+		vmCommand,
+		walletCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
 	app.Flags = append(app.Flags, nodeFlags...)
-	// app.Flags = append(app.Flags, rpcFlags...)
+	app.Flags = append(app.Flags, rpcFlags...)
+	app.Flags = append(app.Flags, debug.Flags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		// Use all processor cores.
@@ -38,12 +45,13 @@ func init() {
 		// limits the garbage collector from excessively overallocating during
 		// bursts.  This value was arrived at with the help of profiling live
 		// usage.
-		debug.SetGCPercent(20)
+		godebug.SetGCPercent(20)
 
-		return nil
+		return debug.Setup(ctx)
 	}
 
 	app.After = func(ctx *cli.Context) error {
+		debug.Exit()
 		// Resets terminal mode.
 		console.Stdin.Close()
 		return nil
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/cmd/utils"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/server"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	txFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Address of the account paying",
+	}
+	txToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Address being paid",
+	}
+	txAmountFlag = cli.Uint64Flag{
+		Name:  "amount",
+		Usage: "Amount paid",
+	}
+	txAssetFlag = cli.StringFlag{
+		Name:  "asset",
+		Usage: "Asset ID paid (hex, default the native asset)",
+	}
+	txActionsFlag = cli.StringFlag{
+		Name:  "actions",
+		Usage: "JSON file of build actions, instead of --from, --to and --amount",
+	}
+	txFeeRateFlag = cli.Uint64Flag{
+		Name:  "feerate",
+		Usage: "Fee paid per unit of transaction weight (default the node's rate)",
+	}
+	txStrategyFlag = cli.StringFlag{
+		Name:  "strategy",
+		Usage: "Coin selection strategy: largest_first, branch_and_bound or random",
+	}
+	txOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File the template is written to (- for standard output)",
+	}
+
+	txCommand = cli.Command{
+		Name:     "tx",
+		Usage:    "Build, sign and submit transaction templates",
+		Category: "TRANSACTION COMMANDS",
+		Description: `
+The tx commands move a transaction through an air-gapped signing flow as a
+template file: the unsigned transaction along with the keys that must sign
+each input and the signatures collected so far.
+
+    srcd tx build --from <account> --to <address> --amount 1000 --out tx.json
+    srcd tx sign --keystore /media/usb/keystore tx.json     (offline machine)
+    srcd tx inspect tx.json
+    srcd tx submit tx.json
+
+Only build and submit talk to a running node. Sign and inspect work without
+one, so signing can happen on a machine that is never networked.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "build",
+				Usage:     "Build an unsigned transaction template with a running node",
+				ArgsUsage: "[endpoint]",
+				Action:    utils.MigrateFlags(txBuild),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					txFromFlag,
+					txToFlag,
+					txAmountFlag,
+					txAssetFlag,
+					txActionsFlag,
+					txFeeRateFlag,
+					txStrategyFlag,
+					txOutFlag,
+				},
+				Description: `
+    srcd tx build --from <account> --to <address> --amount 1000 --out tx.json
+
+The node selects inputs from the account, adds change and the fee, and writes
+the unsigned template. The inputs stay reserved for the transaction for a few
+minutes. Watch-only accounts are built the same way; their keys sign offline.`,
+			},
+			{
+				Name:      "sign",
+				Usage:     "Sign a transaction template with keys from a keystore",
+				ArgsUsage: "<template file>",
+				Action:    utils.MigrateFlags(txSign),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.TestnetFlag,
+					txOutFlag,
+				},
+				Description: `
+    srcd tx sign --keystore <dir> tx.json
+
+Adds the signatures of every key in the keystore the template expects one
+from, including keys derived from them along the paths recorded in the
+template. You are prompted for the passphrase of each key used, or they are
+read in order from the --password file. The template is rewritten in place
+unless --out is given. No node is needed; --testnet selects the test network
+keystore and addresses.`,
+			},
+			{
+				Name:      "inspect",
+				Usage:     "Show the inputs, outputs, fee and signatures of a template",
+				ArgsUsage: "<template file>",
+				Action:    utils.MigrateFlags(txInspect),
+				Flags:     []cli.Flag{utils.TestnetFlag},
+				Description: `
+    srcd tx inspect [--testnet] tx.json
+
+Addresses are shown for the main network unless --testnet is given.`,
+			},
+			{
+				Name:      "submit",
+				Usage:     "Submit a fully signed template to a running node",
+				ArgsUsage: "<template file> [endpoint]",
+				Action:    utils.MigrateFlags(txSubmit),
+				Flags:     []cli.Flag{utils.DataDirFlag},
+			},
+		},
+	}
+)
+
+// txBuild builds a template with the wallet of a running node and writes it
+// to the --out file.
+func txBuild(ctx *cli.Context) error {
+	var args server.BuildArgs
+	if path := ctx.String(txActionsFlag.Name); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to read actions: %v", err)
+		}
+		if err := json.Unmarshal(data, &args.Actions); err != nil {
+			utils.Fatalf("Invalid actions file: %v", err)
+		}
+	} else {
+		from, to, amount := ctx.String(txFromFlag.Name), ctx.String(txToFlag.Name), ctx.Uint64(txAmountFlag.Name)
+		if from == "" || to == "" || amount == 0 {
+			utils.Fatalf("Either --%s or --%s, --%s and --%s are required", txActionsFlag.Name, txFromFlag.Name, txToFlag.Name, txAmountFlag.Name)
+		}
+		assetID := *transaction.SRCAssetID
+		if asset := ctx.String(txAssetFlag.Name); asset != "" {
+			if err := assetID.UnmarshalText([]byte(asset)); err != nil {
+				utils.Fatalf("Invalid asset ID %q: %v", asset, err)
+			}
+		}
+		args.Actions = []*account.Action{
+			{Type: account.ActionSpendAccount, Account: from, AssetID: assetID, Amount: amount},
+			{Type: account.ActionControlAddress, Address: to, AssetID: assetID, Amount: amount},
+		}
+	}
+	args.FeeRate = ctx.Uint64(txFeeRateFlag.Name)
+	args.Strategy = ctx.String(txStrategyFlag.Name)
+
+	client := dialNode(ctx, ctx.Args().First())
+	defer client.Close()
+
+	var res account.BuildResult
+	if err := client.Call(&res, "wallet_buildTransaction", args); err != nil {
+		utils.Fatalf("Failed to build transaction: %v", err)
+	}
+	out := ctx.String(txOutFlag.Name)
+	if out == "" {
+		out = "-"
+	}
+	writeTemplate(out, res.Template)
+	fmt.Fprintf(os.Stderr, "Built transaction %x paying fee %d for weight %d\n", res.Template.Transaction.ID.Bytes(), res.Fee, res.Weight)
+	if res.ReservedUntil != nil {
+		fmt.Fprintf(os.Stderr, "Inputs reserved until %s\n", res.ReservedUntil.Format("15:04:05"))
+	}
+	return nil
+}
+
+// txSign signs a template file with the keys of a keystore, without a node.
+func txSign(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("Usage: srcd tx sign [options] <template file>")
+	}
+	path := ctx.Args().First()
+	tpl := readTemplate(path)
+
+	ks := openKeyStore(ctx, false)
+
+	var (
+		passwords = passwordList(ctx)
+		used      int
+	)
+	for _, info := range ks.Keys() {
+		if !tpl.NeedsKey(info.XPub) {
+			continue
+		}
+		password := getPassword(fmt.Sprintf("Unlocking key %q", info.Alias), used, passwords)
+		used++
+		xprv, err := ks.XPrv(info.PubHash(), password)
+		if err != nil {
+			utils.Fatalf("Failed to unlock key %q: %v", info.Alias, err)
+		}
+		if err := transaction.SignDerived(tpl, xprv); err != nil {
+			utils.Fatalf("Failed to sign with key %q: %v", info.Alias, err)
+		}
+		fmt.Fprintf(os.Stderr, "Signed with key %q\n", info.Alias)
+	}
+	if used == 0 {
+		utils.Fatalf("No key in %s signs this transaction", ks.Dir())
+	}
+
+	out := ctx.String(txOutFlag.Name)
+	if out == "" {
+		out = path
+	}
+	writeTemplate(out, tpl)
+	if remaining := remainingSignatures(tpl); remaining > 0 {
+		fmt.Fprintf(os.Stderr, "%d more signatures needed\n", remaining)
+	} else {
+		fmt.Fprintln(os.Stderr, "Transaction fully signed")
+	}
+	return nil
+}
+
+// txInspect prints a summary of a template file.
+func txInspect(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("Usage: srcd tx inspect [options] <template file>")
+	}
+	makeConfig(ctx)
+	tpl := readTemplate(ctx.Args().First())
+	tx := &tpl.Transaction
+
+	fmt.Printf("ID:     %x\n", tx.ID.Bytes())
+	fmt.Printf("Fee:    %d\n", transaction.CalculateTxFee(&tx.TxData))
+	fmt.Printf("Weight: %d (unsigned inputs count as empty)\n", tx.TxData.Weight())
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INPUT\tSOURCE\tASSET\tAMOUNT\tADDRESS\tSIGNATURES")
+	status := make(map[uint32]*transaction.InputSignatures)
+	for _, s := range tpl.SignatureStatus() {
+		status[s.Position] = s
+	}
+	for i, in := range tx.Inputs {
+		sp, ok := in.TypedInput.(*transaction.SpendInput)
+		if !ok {
+			fmt.Fprintf(w, "%d\t(coinbase)\t\t\t\t\n", i)
+			continue
+		}
+		sigs := "-"
+		if s, ok := status[uint32(i)]; ok {
+			sigs = fmt.Sprintf("%d of %d", s.Signed, s.Quorum)
+		}
+		fmt.Fprintf(w, "%d\t%x:%d\t%x\t%d\t%s\t%s\n", i, sp.SourceID.Bytes(), sp.SourcePosition,
+			sp.AssetId.Bytes(), sp.Amount, programText(sp.ControlProgram), sigs)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "OUTPUT\tASSET\tAMOUNT\tADDRESS")
+	for i, out := range tx.Outputs {
+		fmt.Fprintf(w, "%d\t%x\t%d\t%s\n", i, out.AssetId.Bytes(), out.Amount, programText(out.ControlProgram))
+	}
+	w.Flush()
+	fmt.Println()
+
+	remaining := 0
+	for _, s := range tpl.SignatureStatus() {
+		if s.Remaining() == 0 {
+			continue
+		}
+		remaining += s.Remaining()
+		fmt.Printf("Input %d needs %d more of:\n", s.Position, s.Remaining())
+		for _, xpub := range s.Pending {
+			fmt.Printf("    %s\n", xpub)
+		}
+	}
+	if remaining == 0 {
+		fmt.Println("Fully signed")
+	}
+	return nil
+}
+
+// txSubmit sends the transaction of a fully signed template file to a
+// running node.
+func txSubmit(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		utils.Fatalf("Usage: srcd tx submit <template file> [endpoint]")
+	}
+	tpl := readTemplate(ctx.Args().First())
+	if remaining := remainingSignatures(tpl); remaining > 0 {
+		utils.Fatalf("Transaction needs %d more signatures", remaining)
+	}
+
+	client := dialNode(ctx, ctx.Args().Get(1))
+	defer client.Close()
+
+	var id transaction.Hash
+	if err := client.Call(&id, "chain_sendTransaction", tpl.Transaction); err != nil {
+		utils.Fatalf("Failed to submit transaction: %v", err)
+	}
+	fmt.Printf("Submitted transaction %x\n", id.Bytes())
+	return nil
+}
+
+// remainingSignatures returns how many signatures tpl still needs.
+func remainingSignatures(tpl *transaction.Template) int {
+	remaining := 0
+	for _, s := range tpl.SignatureStatus() {
+		remaining += s.Remaining()
+	}
+	return remaining
+}
+
+// programText returns the address prog pays to, or its hex when it has none.
+func programText(prog []byte) string {
+	if addr := transaction.ProgramAddress(prog); addr != "" {
+		return addr
+	}
+	return fmt.Sprintf("%x", prog)
+}
+
+func readTemplate(path string) *transaction.Template {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to read template: %v", err)
+	}
+	tpl := new(transaction.Template)
+	if err := json.Unmarshal(data, tpl); err != nil {
+		utils.Fatalf("Invalid template %s: %v", path, err)
+	}
+	return tpl
+}
+
+// writeTemplate writes tpl to path, or to standard output if path is "-".
+func writeTemplate(path string, tpl *transaction.Template) {
+	data, err := json.MarshalIndent(tpl, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode template: %v", err)
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		utils.Fatalf("Failed to write template: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/srchain/srcd/cmd/utils"
+	"github.com/srchain/srcd/core/vm"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	vmCommand = cli.Command{
+		Name:     "vm",
+		Usage:    "Convert control programs between hex and assembly text",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+Control programs are stored on chain as opaque byte strings. These commands
+translate between their hex encoding and the text assembly syntax, so contract
+programs can be reviewed before they are deployed.
+
+The program is taken from the command line, or read from standard input when
+no argument is given.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "asm",
+				Usage:     "Assemble program text into hex",
+				ArgsUsage: "<program text>",
+				Action:    vmAssemble,
+				Description: `
+    srcd vm asm 'JUMPIF:$refund BLOCKHEIGHT 1000 LESSTHAN $refund'
+
+Mnemonics, decimal numbers, 0x-prefixed hex data and 'quoted' strings are
+accepted. Jump targets are labelled $name and referenced as JUMP:$name or
+JUMPIF:$name.`,
+			},
+			{
+				Name:      "disasm",
+				Usage:     "Disassemble a hex program into text",
+				ArgsUsage: "<hex program>",
+				Action:    vmDisassemble,
+				Description: `
+    srcd vm disasm 0014...
+
+Prints the program in the syntax accepted by "srcd vm asm".`,
+			},
+		},
+	}
+)
+
+// vmInput returns the program given on the command line, falling back to
+// standard input.
+func vmInput(ctx *cli.Context) string {
+	if ctx.NArg() > 0 {
+		return strings.Join(ctx.Args(), " ")
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		utils.Fatalf("Failed to read program: %v", err)
+	}
+	return string(data)
+}
+
+func vmAssemble(ctx *cli.Context) error {
+	prog, err := vm.Assemble(vmInput(ctx))
+	if err != nil {
+		utils.Fatalf("Failed to assemble program: %v", err)
+	}
+	fmt.Println(hex.EncodeToString(prog))
+	return nil
+}
+
+func vmDisassemble(ctx *cli.Context) error {
+	src := strings.TrimPrefix(strings.TrimSpace(vmInput(ctx)), "0x")
+	prog, err := hex.DecodeString(src)
+	if err != nil {
+		utils.Fatalf("Invalid hex program: %v", err)
+	}
+	text, err := vm.Disassemble(prog)
+	if err != nil {
+		utils.Fatalf("Failed to disassemble program: %v", err)
+	}
+	fmt.Println(text)
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/srchain/srcd/account/wallet"
+	"github.com/srchain/srcd/cmd/utils"
+	"github.com/srchain/srcd/core/transaction"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	historyAccountFlag = cli.StringFlag{
+		Name:  "account",
+		Usage: "Only show transactions of this account address",
+	}
+	historyAssetFlag = cli.StringFlag{
+		Name:  "asset",
+		Usage: "Only show transactions moving this asset ID (hex)",
+	}
+	historyFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Only show transactions from this time on (YYYY-MM-DD, RFC 3339 or Unix seconds)",
+	}
+	historyToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Only show transactions before this time (YYYY-MM-DD, RFC 3339 or Unix seconds)",
+	}
+	historyOffsetFlag = cli.IntFlag{
+		Name:  "offset",
+		Usage: "Number of matching transactions to skip",
+	}
+	historyLimitFlag = cli.IntFlag{
+		Name:  "limit",
+		Usage: "Maximum number of transactions to show",
+		Value: wallet.DefaultHistoryLimit,
+	}
+	rescanHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Block height to rescan from (default: the wallet's birthday)",
+	}
+	rescanWaitFlag = cli.BoolFlag{
+		Name:  "wait",
+		Usage: "Report the progress of the rescan until it finishes",
+	}
+
+	walletCommand = cli.Command{
+		Name:     "wallet",
+		Usage:    "Inspect the wallet of a running node",
+		Category: "WALLET COMMANDS",
+		Description: `
+The wallet commands query the wallet of a node that is already running. The
+endpoint is an IPC socket path or an http:// or ws:// URL, and defaults to the
+IPC socket in the data directory.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "history",
+				Usage:     "List the transactions touching the node's accounts",
+				ArgsUsage: "[endpoint]",
+				Action:    utils.MigrateFlags(walletHistory),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					historyAccountFlag,
+					historyAssetFlag,
+					historyFromFlag,
+					historyToFlag,
+					historyOffsetFlag,
+					historyLimitFlag,
+				},
+				Description: `
+    srcd wallet history --account <address> --from 2018-06-01 --limit 20
+
+Prints the transactions newest first, with the net amount they moved per
+account and asset, the fee, their status and the number of confirmations.
+Pending transactions are waiting in the pool; replaced and conflicted ones
+lost their inputs to another transaction and will not confirm.`,
+			},
+			{
+				Name:      "rescan",
+				Usage:     "Scan the chain again for outputs of the node's accounts",
+				ArgsUsage: "[endpoint]",
+				Action:    utils.MigrateFlags(walletRescan),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					rescanHeightFlag,
+					rescanWaitFlag,
+				},
+				Description: `
+    srcd wallet rescan --height 120000 --wait
+
+Finds the outputs paid to keys imported after the wallet went past them, and
+rebuilds the wallet records of the blocks scanned. The node keeps processing
+new blocks during the rescan, and a rescan interrupted by a restart resumes
+where it stopped.`,
+			},
+		},
+	}
+)
+
+// walletHistory prints a page of the wallet history of a running node.
+func walletHistory(ctx *cli.Context) error {
+	filter := wallet.HistoryFilter{
+		AccountID: ctx.String(historyAccountFlag.Name),
+		Offset:    ctx.Int(historyOffsetFlag.Name),
+		Limit:     ctx.Int(historyLimitFlag.Name),
+	}
+	if asset := ctx.String(historyAssetFlag.Name); asset != "" {
+		filter.AssetID = new(transaction.AssetID)
+		if err := filter.AssetID.UnmarshalText([]byte(asset)); err != nil {
+			utils.Fatalf("Invalid asset ID %q: %v", asset, err)
+		}
+	}
+	var err error
+	if filter.From, err = parseHistoryTime(ctx.String(historyFromFlag.Name)); err != nil {
+		utils.Fatalf("Invalid --%s time: %v", historyFromFlag.Name, err)
+	}
+	if filter.To, err = parseHistoryTime(ctx.String(historyToFlag.Name)); err != nil {
+		utils.Fatalf("Invalid --%s time: %v", historyToFlag.Name, err)
+	}
+
+	client := dialNode(ctx, ctx.Args().First())
+	defer client.Close()
+
+	var page wallet.HistoryPage
+	if err := client.Call(&page, "wallet_getHistory", filter); err != nil {
+		utils.Fatalf("Failed to read the wallet history: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tID\tSTATUS\tCONF\tACCOUNT\tASSET\tAMOUNT\tFEE\tCOUNTERPARTIES")
+	for _, rec := range page.Transactions {
+		when := time.Unix(int64(rec.Timestamp), 0).UTC().Format("2006-01-02 15:04:05")
+		for i, n := range rec.Net {
+			fee := ""
+			if i == 0 {
+				fee = strconv.FormatUint(rec.Fee, 10)
+			}
+			fmt.Fprintf(w, "%s\t%x\t%s\t%d\t%s\t%x\t%+d\t%s\t%s\n", when, rec.ID.Bytes(), rec.Status, rec.Confirmations,
+				n.AccountID, n.AssetID.Bytes(), n.Amount, fee, strings.Join(rec.Counterparties, ","))
+		}
+	}
+	w.Flush()
+	fmt.Printf("Showing %d of %d transactions\n", len(page.Transactions), page.Total)
+	return nil
+}
+
+// walletRescan starts a rescan of the wallet of a running node.
+func walletRescan(ctx *cli.Context) error {
+	client := dialNode(ctx, ctx.Args().First())
+	defer client.Close()
+
+	var from *uint64
+	if ctx.IsSet(rescanHeightFlag.Name) {
+		height := ctx.Uint64(rescanHeightFlag.Name)
+		from = &height
+	}
+	if err := client.Call(nil, "wallet_rescan", from); err != nil {
+		utils.Fatalf("Failed to start the rescan: %v", err)
+	}
+	fmt.Println("Rescan started")
+	if !ctx.Bool(rescanWaitFlag.Name) {
+		return nil
+	}
+	for {
+		var progress *wallet.RescanProgress
+		if err := client.Call(&progress, "wallet_rescanStatus"); err != nil {
+			utils.Fatalf("Failed to read the rescan progress: %v", err)
+		}
+		if progress == nil {
+			fmt.Println("Rescan finished")
+			return nil
+		}
+		fmt.Printf("Rescanning block %d of %d\n", progress.Current, progress.Target)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// parseHistoryTime parses a date, an RFC 3339 time or Unix seconds into Unix
+// seconds. The empty string is zero, leaving the bound open.
+func parseHistoryTime(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return secs, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return uint64(t.Unix()), nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized time %q", s)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/srchain/srcd/common/common"
 	"github.com/srchain/srcd/node"
@@ -75,7 +76,7 @@ var (
 	}
 	TestnetFlag = cli.BoolFlag{
 		Name:  "testnet",
-		Usage: "Ropsten network: pre-configured proof-of-work test network",
+		Usage: "Test network: pre-configured proof-of-work test network",
 	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
@@ -92,6 +93,118 @@ var (
 		Usage: "Public address for block mining rewards (default = first account)",
 		Value: "0",
 	}
+	// RPC settings
+	RPCEnabledFlag = cli.BoolFlag{
+		Name:  "rpc",
+		Usage: "Enable the HTTP-RPC server",
+	}
+	RPCListenAddrFlag = cli.StringFlag{
+		Name:  "rpcaddr",
+		Usage: "HTTP-RPC server listening interface",
+		Value: node.DefaultHTTPHost,
+	}
+	RPCPortFlag = cli.IntFlag{
+		Name:  "rpcport",
+		Usage: "HTTP-RPC server listening port",
+		Value: node.DefaultHTTPPort,
+	}
+	RPCCORSDomainFlag = cli.StringFlag{
+		Name:  "rpccorsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	RPCVirtualHostsFlag = cli.StringFlag{
+		Name:  "rpcvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
+	RPCApiFlag = cli.StringFlag{
+		Name:  "rpcapi",
+		Usage: "API's offered over the HTTP-RPC interface",
+		Value: "",
+	}
+	IPCDisabledFlag = cli.BoolFlag{
+		Name:  "ipcdisable",
+		Usage: "Disable the IPC-RPC server",
+	}
+	IPCPathFlag = DirectoryFlag{
+		Name:  "ipcpath",
+		Usage: "Filename for IPC socket/pipe within the datadir (explicit paths escape it)",
+	}
+	WSEnabledFlag = cli.BoolFlag{
+		Name:  "ws",
+		Usage: "Enable the WS-RPC server",
+	}
+	WSListenAddrFlag = cli.StringFlag{
+		Name:  "wsaddr",
+		Usage: "WS-RPC server listening interface",
+		Value: node.DefaultWSHost,
+	}
+	WSPortFlag = cli.IntFlag{
+		Name:  "wsport",
+		Usage: "WS-RPC server listening port",
+		Value: node.DefaultWSPort,
+	}
+	WSApiFlag = cli.StringFlag{
+		Name:  "wsapi",
+		Usage: "API's offered over the WS-RPC interface",
+		Value: "",
+	}
+	WSAllowedOriginsFlag = cli.StringFlag{
+		Name:  "wsorigins",
+		Usage: "Origins from which to accept websockets requests",
+		Value: "",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "File holding the hex encoded secret HTTP and WS callers sign bearer tokens with",
+		Value: "",
+	}
+	RPCBasicAuthFlag = cli.StringFlag{
+		Name:  "rpcbasicauth",
+		Usage: "File of user:password lines accepted as HTTP basic auth by the HTTP and WS servers",
+		Value: "",
+	}
+	RPCPublicApiFlag = cli.StringFlag{
+		Name:  "rpcpublicapi",
+		Usage: "API's callers without credentials may use once credentials are configured",
+		Value: strings.Join(node.DefaultConfig.RPCPublicModules, ","),
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Calls per second each client IP may make to the HTTP and WS servers (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Calls a client IP may make in a burst above the rate limit",
+		Value: 1,
+	}
+	// Console
+	ExecFlag = cli.StringFlag{
+		Name:  "exec",
+		Usage: "Execute a console statement and exit",
+	}
+	// Account settings
+	AccountAliasFlag = cli.StringFlag{
+		Name:  "alias",
+		Usage: "Alias of the account key in the keystore",
+	}
+	MnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Derive the account key from a new mnemonic phrase, printed for backup",
+	}
+	MnemonicLangFlag = cli.StringFlag{
+		Name:  "mnemoniclang",
+		Usage: "Wordlist of the mnemonic phrase (english, chinese_simplified, japanese, spanish)",
+	}
+	MnemonicPassphraseFlag = cli.BoolFlag{
+		Name:  "mnemonicpassphrase",
+		Usage: "Prompt for a passphrase protecting the mnemonic phrase, needed with the phrase to recover the account",
+	}
+	PasswordFileFlag = cli.StringFlag{
+		Name:  "password",
+		Usage: "Password file to use for non-interactive password input, one password per line",
+	}
 )
 
 // MakeAddress converts an account specified directly as a hex encoded string.
@@ -123,8 +236,115 @@ func setCoinbase(ctx *cli.Context,acc account.Account , cfg *server.Config) {
 	}
 }
 
+// splitAndTrim splits input separated by a comma
+// and trims excessive white space from the substrings.
+func splitAndTrim(input string) []string {
+	result := strings.Split(input, ",")
+	for i, r := range result {
+		result[i] = strings.TrimSpace(r)
+	}
+	return result
+}
+
+// setHTTP creates the HTTP RPC listener interface string from the set
+// command line flags, returning empty if the HTTP endpoint is disabled.
+func setHTTP(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(RPCEnabledFlag.Name) && cfg.HTTPHost == "" {
+		cfg.HTTPHost = "127.0.0.1"
+		if ctx.GlobalIsSet(RPCListenAddrFlag.Name) {
+			cfg.HTTPHost = ctx.GlobalString(RPCListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(RPCPortFlag.Name) {
+		cfg.HTTPPort = ctx.GlobalInt(RPCPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCORSDomainFlag.Name) {
+		cfg.HTTPCors = splitAndTrim(ctx.GlobalString(RPCCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCApiFlag.Name) {
+		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
+		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+	}
+}
+
+// setWS creates the WebSocket RPC listener interface string from the set
+// command line flags, returning empty if the HTTP endpoint is disabled.
+func setWS(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(WSEnabledFlag.Name) && cfg.WSHost == "" {
+		cfg.WSHost = "127.0.0.1"
+		if ctx.GlobalIsSet(WSListenAddrFlag.Name) {
+			cfg.WSHost = ctx.GlobalString(WSListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(WSPortFlag.Name) {
+		cfg.WSPort = ctx.GlobalInt(WSPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSAllowedOriginsFlag.Name) {
+		cfg.WSOrigins = splitAndTrim(ctx.GlobalString(WSAllowedOriginsFlag.Name))
+	}
+	if ctx.GlobalIsSet(WSApiFlag.Name) {
+		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
+	}
+}
+
+// setRPCAccess applies the command line flags guarding the HTTP and WebSocket
+// RPC servers.
+func setRPCAccess(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBasicAuthFlag.Name) {
+		cfg.RPCBasicAuthFile = ctx.GlobalString(RPCBasicAuthFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCPublicApiFlag.Name) {
+		cfg.RPCPublicModules = splitAndTrim(ctx.GlobalString(RPCPublicApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+}
+
+// setIPC creates an IPC path configuration from the set command line flags,
+// returning an empty string if IPC was explicitly disabled, or the set path.
+func setIPC(ctx *cli.Context, cfg *node.Config) {
+	checkExclusive(ctx, IPCDisabledFlag, IPCPathFlag)
+	switch {
+	case ctx.GlobalBool(IPCDisabledFlag.Name):
+		cfg.IPCPath = ""
+	case ctx.GlobalIsSet(IPCPathFlag.Name):
+		cfg.IPCPath = ctx.GlobalString(IPCPathFlag.Name)
+	}
+}
+
+// checkExclusive verifies that only a single instance of the provided flags was
+// set by the user.
+func checkExclusive(ctx *cli.Context, flags ...cli.Flag) {
+	set := make([]string, 0, 1)
+	for _, flag := range flags {
+		name := strings.Split(flag.GetName(), ",")[0]
+		if ctx.GlobalIsSet(name) {
+			set = append(set, "--"+name)
+		}
+	}
+	if len(set) > 1 {
+		Fatalf("Flags %v can't be used at the same time", strings.Join(set, ", "))
+	}
+}
+
 // SetNodeConfig applies node-related command line flags to the config.
 func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
+	setIPC(ctx, cfg)
+	setHTTP(ctx, cfg)
+	setWS(ctx, cfg)
+	setRPCAccess(ctx, cfg)
+
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
@@ -137,6 +357,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// SetNetworkId applies the network selecting command line flags to the
+// config.
+func SetNetworkId(ctx *cli.Context, cfg *server.Config) {
+	if ctx.GlobalBool(TestnetFlag.Name) {
+		cfg.NetworkId = params.TestNetParams.NetworkID
+	}
+}
+
 // SetServerConfig applies server-related command line flags to the config.
 func SetServerConfig(ctx *cli.Context, node *node.Node, cfg *server.Config) {
 	//ks := node.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
@@ -144,9 +372,10 @@ func SetServerConfig(ctx *cli.Context, node *node.Node, cfg *server.Config) {
 	if e!=nil{
 		Fatalf("")
 	}
-	account := nodeAccounts[0]
-
-	setCoinbase(ctx, account, cfg)
+	// A node without accounts yet mines to no coinbase.
+	if len(nodeAccounts) > 0 {
+		setCoinbase(ctx, nodeAccounts[0], cfg)
+	}
 
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
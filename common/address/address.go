@@ -70,6 +70,63 @@ func (a *AddressWitnessPubKeyHash) EncodeAddress() string {
 	return str
 }
 
+// WitnessProgram returns the witness program of the AddressWitnessPubKeyHash.
+func (a *AddressWitnessPubKeyHash) WitnessProgram() []byte {
+	return a.witnessProgram[:]
+}
+
+// AddressWitnessScriptHash is an Address for a pay-to-witness-script-hash
+// (P2WSH) output, committing to the sha256 hash of the script spending it.
+type AddressWitnessScriptHash struct {
+	hrp            string
+	witnessVersion byte
+	witnessProgram [32]byte
+}
+
+// NewAddressWitnessScriptHash returns a new AddressWitnessScriptHash.
+func NewAddressWitnessScriptHash(witnessProg []byte, param *params.NetParams) (*AddressWitnessScriptHash, error) {
+	return newAddressWitnessScriptHash(param.Bech32HRPSegwit, witnessProg)
+}
+
+func newAddressWitnessScriptHash(hrp string, witnessProg []byte) (*AddressWitnessScriptHash, error) {
+	// Check for valid program length for witness version 0, which is 32
+	// for P2WSH.
+	if len(witnessProg) != 32 {
+		return nil, errors.New("witness program must be 32 bytes for p2wsh")
+	}
+
+	addr := &AddressWitnessScriptHash{
+		hrp:            strings.ToLower(hrp),
+		witnessVersion: 0x00,
+	}
+
+	copy(addr.witnessProgram[:], witnessProg)
+
+	return addr, nil
+}
+
+// String returns a human-readable string for the AddressWitnessScriptHash.
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) String() string {
+	return a.EncodeAddress()
+}
+
+// EncodeAddress returns the bech32 string encoding of an
+// AddressWitnessScriptHash.
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) EncodeAddress() string {
+	str, err := encodeSegWitAddress(a.hrp, a.witnessVersion, a.witnessProgram[:])
+	if err != nil {
+		return ""
+	}
+	return str
+}
+
+// WitnessProgram returns the witness program of the AddressWitnessScriptHash.
+func (a *AddressWitnessScriptHash) WitnessProgram() []byte {
+	return a.witnessProgram[:]
+}
+
 func DecodeAddress(addr string,param params.NetParams)(Address, error){
 	oneIndex := strings.LastIndexByte(addr, '1')
 	if oneIndex > 1 {
@@ -89,6 +146,9 @@ func DecodeAddress(addr string,param params.NetParams)(Address, error){
 			// The HRP is everything before the found '1'.
 			hrp := prefix[:len(prefix)-1]
 
+			if len(witnessProg) == 32 {
+				return newAddressWitnessScriptHash(hrp, witnessProg)
+			}
 			return newAddressWitnessPubKeyHash(hrp, witnessProg)
 		}
 	}
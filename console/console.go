@@ -0,0 +1,365 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/peterh/liner"
+	"github.com/srchain/srcd/rpc"
+)
+
+var (
+	passwordRegexp = regexp.MustCompile(`(?i)password|wallet_(sign|send)Transaction`)
+	onlyWhitespace = regexp.MustCompile(`^\s*$`)
+	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$|^\s*quit\s*;*\s*$`)
+	methodName     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*[_.][A-Za-z][A-Za-z0-9]*$`)
+)
+
+// HistoryFile is the file within the data directory to store input scrollback.
+const HistoryFile = "history"
+
+// DefaultPrompt is the default prompt line prefix to use for user input querying.
+const DefaultPrompt = "> "
+
+var errBadStatement = errors.New("expected method(arguments...)")
+
+// Config is the collection of configurations to fine tune the behavior of the
+// console.
+type Config struct {
+	DataDir  string       // Data directory to store the console history at
+	Client   *rpc.Client  // RPC client to execute node calls through
+	Prompt   string       // Input prompt prefix string (defaults to DefaultPrompt)
+	Prompter UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
+	Printer  io.Writer    // Output writer to serialize any display strings to (defaults to os.Stdout)
+}
+
+// Console is an interactive prompt issuing JSON-RPC calls to a node. A
+// statement names a method and lists its arguments as JSON values, such as
+// chain_getBlockByNumber(12, true).
+type Console struct {
+	client   *rpc.Client  // RPC client to execute node calls through
+	prompt   string       // Input prompt prefix string
+	prompter UserPrompter // Input prompter to allow interactive user feedback
+	histPath string       // Absolute path to the console scrollback history
+	history  []string     // Scroll history maintained by the console
+	printer  io.Writer    // Output writer to serialize any display strings to
+	methods  []string     // Methods offered by the node, sorted
+}
+
+// New initializes a console, loading the input history and the methods the
+// node offers.
+func New(config Config) (*Console, error) {
+	// Handle unset config values gracefully
+	if config.Prompter == nil {
+		config.Prompter = Stdin
+	}
+	if config.Prompt == "" {
+		config.Prompt = DefaultPrompt
+	}
+	if config.Printer == nil {
+		config.Printer = os.Stdout
+	}
+	console := &Console{
+		client:   config.Client,
+		prompt:   config.Prompt,
+		prompter: config.Prompter,
+		printer:  config.Printer,
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0700); err != nil {
+			return nil, err
+		}
+		console.histPath = filepath.Join(config.DataDir, HistoryFile)
+	}
+	if err := console.init(); err != nil {
+		return nil, err
+	}
+	return console, nil
+}
+
+// init retrieves the available methods and sets up the prompter.
+func (c *Console) init() error {
+	if err := c.client.Call(&c.methods, "rpc_methods"); err != nil {
+		// Nodes without method listing still tell their modules.
+		modules, err := c.client.SupportedModules()
+		if err != nil {
+			return fmt.Errorf("api modules: %v", err)
+		}
+		for module := range modules {
+			c.methods = append(c.methods, module+"_")
+		}
+		sort.Strings(c.methods)
+	}
+	c.prompter.SetWordCompleter(c.AutoCompleteInput)
+
+	if c.histPath == "" {
+		return nil
+	}
+	if content, err := ioutil.ReadFile(c.histPath); err != nil {
+		c.prompter.SetHistory(nil)
+	} else {
+		c.history = strings.Split(string(content), "\n")
+		c.prompter.SetHistory(c.history)
+	}
+	return nil
+}
+
+// AutoCompleteInput is a pre-assembled word completer to be used by the user
+// input prompter to provide hints to the user about the methods available.
+func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, string) {
+	if len(line) == 0 || pos == 0 {
+		return "", nil, ""
+	}
+	// Chunk the current line down to the method name being completed
+	start := pos - 1
+	for ; start > 0; start-- {
+		if r := line[start-1]; !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			break
+		}
+	}
+	prefix := strings.Replace(line[start:pos], ".", "_", 1)
+
+	var completions []string
+	for _, method := range append(c.methods, "exit", "help") {
+		if strings.HasPrefix(method, prefix) {
+			completions = append(completions, method)
+		}
+	}
+	// Methods called without arguments are the exception, so open the
+	// argument list of a single match.
+	if len(completions) == 1 && strings.Contains(completions[0], "_") && !strings.HasSuffix(completions[0], "_") {
+		completions[0] += "("
+	}
+	return line[:start], completions, line[pos:]
+}
+
+// Welcome shows a summary of the node the console is attached to.
+func (c *Console) Welcome() {
+	fmt.Fprintf(c.printer, "Welcome to the SilkRoad console!\n\n")
+
+	var version string
+	if err := c.client.Call(&version, "web3_clientVersion"); err == nil {
+		fmt.Fprintf(c.printer, "instance: %s\n", version)
+	}
+	modules, err := c.client.SupportedModules()
+	if err == nil {
+		var names []string
+		for name, version := range modules {
+			names = append(names, fmt.Sprintf("%s:%s", name, version))
+		}
+		sort.Strings(names)
+		fmt.Fprintf(c.printer, " modules: %s\n", strings.Join(names, " "))
+	}
+	fmt.Fprintf(c.printer, "\nType help for usage, exit or Ctrl-D to quit.\n\n")
+}
+
+// help prints the statement syntax and the methods starting with prefix.
+func (c *Console) help(prefix string) {
+	fmt.Fprintln(c.printer, "Statements call a method with JSON arguments, e.g.:")
+	fmt.Fprintln(c.printer, "  chain_getBlockByNumber(12, true)")
+	fmt.Fprintln(c.printer, "  chain.getTransaction(\"0x...\")")
+	fmt.Fprintln(c.printer, "Arguments may span lines until the parentheses balance.")
+	fmt.Fprintln(c.printer)
+	for _, method := range c.methods {
+		if strings.HasPrefix(method, prefix) {
+			fmt.Fprintln(c.printer, "  "+method)
+		}
+	}
+}
+
+// Evaluate executes a statement and pretty prints its result or error.
+func (c *Console) Evaluate(statement string) error {
+	statement = strings.TrimSpace(statement)
+	if fields := strings.Fields(statement); len(fields) > 0 && fields[0] == "help" {
+		prefix := ""
+		if len(fields) > 1 {
+			prefix = strings.Replace(fields[1], ".", "_", 1)
+		}
+		c.help(prefix)
+		return nil
+	}
+	method, args, err := parseStatement(statement)
+	if err != nil {
+		fmt.Fprintf(c.printer, "Error: %v\n", err)
+		return err
+	}
+	var result json.RawMessage
+	if err := c.client.Call(&result, method, args...); err != nil {
+		fmt.Fprintf(c.printer, "Error: %v\n", err)
+		if derr, ok := err.(rpc.DataError); ok && derr.ErrorData() != nil {
+			data, _ := json.MarshalIndent(derr.ErrorData(), "", "  ")
+			fmt.Fprintf(c.printer, "%s\n", data)
+		}
+		return err
+	}
+	fmt.Fprintf(c.printer, "%s\n", prettyPrint(result))
+	return nil
+}
+
+// parseStatement splits a statement into the method it calls and the JSON
+// arguments it passes.
+func parseStatement(statement string) (string, []interface{}, error) {
+	statement = strings.TrimRight(statement, "; \t\n")
+	method, argList := statement, ""
+	if open := strings.IndexByte(statement, '('); open >= 0 {
+		if !strings.HasSuffix(statement, ")") {
+			return "", nil, errBadStatement
+		}
+		method, argList = strings.TrimSpace(statement[:open]), statement[open+1:len(statement)-1]
+	}
+	if !methodName.MatchString(method) {
+		return "", nil, errBadStatement
+	}
+	method = strings.Replace(method, ".", "_", 1)
+
+	dec := json.NewDecoder(strings.NewReader("[" + argList + "]"))
+	dec.UseNumber()
+	var args []interface{}
+	if err := dec.Decode(&args); err != nil {
+		return "", nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	return method, args, nil
+}
+
+// prettyPrint indents a JSON result for display.
+func prettyPrint(result json.RawMessage) string {
+	if len(result) == 0 {
+		return "null"
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		return string(result)
+	}
+	return out.String()
+}
+
+// countIndents returns the number of brackets input leaves open, ignoring
+// those inside strings.
+func countIndents(input string) int {
+	var (
+		indents  = 0
+		inString = false
+		escaped  = false
+	)
+	for _, c := range input {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = inString
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			indents++
+		case c == ')' || c == ']' || c == '}':
+			indents--
+		}
+	}
+	return indents
+}
+
+// promptResult is a line read by the prompter.
+type promptResult struct {
+	line    string
+	aborted bool // the user pressed Ctrl-C
+}
+
+// Interactive starts an interactive user session, where input is prompted from
+// the configured user prompter.
+func (c *Console) Interactive() {
+	var (
+		prompt    = c.prompt          // Current prompt line (used for multi-line inputs)
+		indents   = 0                 // Current number of input indents (used for multi-line inputs)
+		input     = ""                // Current user input
+		scheduler = make(chan string) // Channel to send the next prompt on
+		lines     = make(chan promptResult)
+	)
+	// Start a goroutine to listen for prompt requests and send back inputs
+	go func() {
+		for prompt := range scheduler {
+			line, err := c.prompter.PromptInput(prompt)
+			if err == liner.ErrPromptAborted {
+				lines <- promptResult{aborted: true}
+				continue
+			}
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- promptResult{line: line}
+		}
+	}()
+	defer close(scheduler)
+
+	// Monitor Ctrl-C too in case the input is empty and we need to bail
+	abort := make(chan os.Signal, 1)
+	signal.Notify(abort, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(abort)
+
+	// Start sending prompts to the user and reading back inputs
+	for {
+		scheduler <- prompt
+		select {
+		case <-abort:
+			// User forcefully quit the console
+			fmt.Fprintln(c.printer, "caught interrupt, exiting")
+			return
+
+		case res, ok := <-lines:
+			// User input was returned by the prompter, handle special cases
+			if !ok || (indents <= 0 && exit.MatchString(res.line)) {
+				return
+			}
+			if res.aborted {
+				// Ctrl-C discards a half typed statement
+				prompt, indents, input = c.prompt, 0, ""
+				continue
+			}
+			if onlyWhitespace.MatchString(res.line) {
+				continue
+			}
+			// Append the line to the input and check for multi-line interpretation
+			input += res.line + "\n"
+
+			indents = countIndents(input)
+			if indents > 0 {
+				prompt = strings.Repeat(".", indents*3) + " "
+				continue
+			}
+			prompt = c.prompt
+
+			// The statement is complete, save it on a single line and run
+			// it. Statements carrying passwords are left out of the history.
+			command := strings.Replace(strings.TrimSpace(input), "\n", " ", -1)
+			if !passwordRegexp.MatchString(command) && (len(c.history) == 0 || command != c.history[len(c.history)-1]) {
+				c.history = append(c.history, command)
+				c.prompter.AppendHistory(command)
+			}
+			c.Evaluate(input)
+			input = ""
+		}
+	}
+}
+
+// Stop cleans up the console and saves its history.
+func (c *Console) Stop() error {
+	if c.histPath == "" {
+		return nil
+	}
+	if err := ioutil.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(c.histPath, 0600) // Force 0600, even if it was different previously
+}
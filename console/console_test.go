@@ -0,0 +1,201 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/srchain/srcd/rpc"
+)
+
+// hookedPrompter implements UserPrompter to simulate user input via channels.
+type hookedPrompter struct {
+	scheduler chan string
+}
+
+func (p *hookedPrompter) PromptInput(prompt string) (string, error) {
+	// Send the prompt to the tester
+	select {
+	case p.scheduler <- prompt:
+	}
+	// Retrieve the response and feed to the console
+	select {
+	case input, ok := <-p.scheduler:
+		if !ok {
+			return "", errors.New("closed")
+		}
+		return input, nil
+	}
+}
+
+func (p *hookedPrompter) PromptPassword(prompt string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (p *hookedPrompter) PromptConfirm(prompt string) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (p *hookedPrompter) SetHistory(history []string)              {}
+func (p *hookedPrompter) AppendHistory(command string)             {}
+func (p *hookedPrompter) ClearHistory()                            {}
+func (p *hookedPrompter) SetWordCompleter(completer WordCompleter) {}
+
+type TestService struct{}
+
+type TestArgs struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (s *TestService) Echo(args TestArgs, flag bool) TestArgs { return args }
+func (s *TestService) Height() uint64                         { return 42 }
+func (s *TestService) Fail() error                            { return errors.New("failure") }
+
+// tester is a console test environment for the console tests to operate on.
+type tester struct {
+	workspace string
+	server    *rpc.Server
+	console   *Console
+	input     *hookedPrompter
+	output    *bytes.Buffer
+}
+
+func newTester(t *testing.T) *tester {
+	workspace, err := ioutil.TempDir("", "console-tester-")
+	if err != nil {
+		t.Fatalf("failed to create temporary keystore: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", new(TestService)); err != nil {
+		t.Fatal(err)
+	}
+	prompter := &hookedPrompter{scheduler: make(chan string)}
+	printer := new(bytes.Buffer)
+
+	console, err := New(Config{
+		DataDir:  workspace,
+		Client:   rpc.DialInProc(server),
+		Prompter: prompter,
+		Printer:  printer,
+	})
+	if err != nil {
+		t.Fatalf("failed to create console: %v", err)
+	}
+	return &tester{workspace: workspace, server: server, console: console, input: prompter, output: printer}
+}
+
+func (env *tester) Close(t *testing.T) {
+	if err := env.console.Stop(); err != nil {
+		t.Errorf("failed to stop console: %v", err)
+	}
+	env.server.Stop()
+	os.RemoveAll(env.workspace)
+}
+
+func TestEvaluate(t *testing.T) {
+	tester := newTester(t)
+	defer tester.Close(t)
+
+	tests := []struct {
+		statement string
+		output    string
+	}{
+		{"test_height", "42\n"},
+		{"test.height();", "42\n"},
+		{`test_echo({"name": "x", "count": 2}, true)`, "{\n  \"name\": \"x\",\n  \"count\": 2\n}\n"},
+		{"test_fail()", "Error: failure\n"},
+		{"test_height(", "Error: " + errBadStatement.Error() + "\n"},
+		{"height", "Error: " + errBadStatement.Error() + "\n"},
+	}
+	for i, test := range tests {
+		tester.output.Reset()
+		tester.console.Evaluate(test.statement)
+		if output := tester.output.String(); output != test.output {
+			t.Errorf("test %d: output mismatch: have %q, want %q", i, output, test.output)
+		}
+	}
+}
+
+func TestAutoComplete(t *testing.T) {
+	tester := newTester(t)
+	defer tester.Close(t)
+
+	tests := []struct {
+		line, head  string
+		completions []string
+	}{
+		{"test_", "", []string{"test_echo", "test_fail", "test_height"}},
+		{"test.h", "", []string{"test_height("}},
+		{"x = rpc_mod", "x = ", []string{"rpc_modules("}},
+		{"he", "", []string{"help"}},
+	}
+	for i, test := range tests {
+		head, completions, tail := tester.console.AutoCompleteInput(test.line, len(test.line))
+		if head != test.head || tail != "" || !reflect.DeepEqual(completions, test.completions) {
+			t.Errorf("test %d: have (%q, %q, %q), want (%q, %q, \"\")", i, head, completions, tail, test.head, test.completions)
+		}
+	}
+}
+
+func TestInteractiveMultiLine(t *testing.T) {
+	tester := newTester(t)
+
+	done := make(chan struct{})
+	go func() {
+		tester.console.Interactive()
+		close(done)
+	}()
+	respond := func(input string) string {
+		prompt := <-tester.input.scheduler
+		tester.input.scheduler <- input
+		return prompt
+	}
+	if prompt := respond(`test_echo({"name": "x",`); prompt != DefaultPrompt {
+		t.Errorf("prompt mismatch: have %q, want %q", prompt, DefaultPrompt)
+	}
+	if prompt := respond(`"count": 1}, false)`); prompt != "...... " {
+		t.Errorf("continuation prompt mismatch: have %q", prompt)
+	}
+	respond(`wallet_sendTransaction({}, "password")`)
+	if prompt := respond("exit"); prompt != DefaultPrompt {
+		t.Errorf("prompt mismatch: have %q, want %q", prompt, DefaultPrompt)
+	}
+	<-done
+
+	if !strings.Contains(tester.output.String(), "\"count\": 1") {
+		t.Errorf("multi-line statement not evaluated: %q", tester.output.String())
+	}
+	// The statement carrying a password stays out of the history.
+	want := []string{`test_echo({"name": "x", "count": 1}, false)`}
+	if !reflect.DeepEqual(tester.console.history, want) {
+		t.Errorf("history mismatch: have %q, want %q", tester.console.history, want)
+	}
+	tester.Close(t)
+}
+
+func TestHistory(t *testing.T) {
+	tester := newTester(t)
+	defer tester.Close(t)
+
+	tester.console.history = []string{`test_echo({"name": "x", "count": 1}, false)`}
+	if err := tester.console.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := New(Config{
+		DataDir:  tester.workspace,
+		Client:   tester.console.client,
+		Prompter: tester.input,
+		Printer:  tester.output,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.history, tester.console.history) {
+		t.Errorf("history mismatch: have %q, want %q", reloaded.history, tester.console.history)
+	}
+}
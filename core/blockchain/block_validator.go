@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/srchain/srcd/consensus"
+	"github.com/srchain/srcd/core/transaction"
 	"github.com/srchain/srcd/core/types"
 )
 
@@ -36,6 +37,14 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	var weight uint64
+	for _, tx := range block.Transactions() {
+		w := tx.Tx.Weight()
+		if w > transaction.MaxBlockWeight-weight {
+			return ErrBlockTooHeavy
+		}
+		weight += w
+	}
 
 	return nil
 }
@@ -55,6 +55,7 @@ type BlockChain struct {
 	// chainFeed     event.Feed
 	// chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	chainReorgFeed event.Feed
 	// logsFeed      event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
@@ -535,19 +536,87 @@ Error: %v
 `, block.Number(), block.Hash(), err))
 }
 
-// WriteBlock writes the block to the database.
+// WriteBlock writes the block to the database and makes it the new head of
+// the canonical chain. If the block doesn't extend the current head, the chain
+// is reorganised onto the block's branch and a ChainReorgEvent is posted.
 func (bc *BlockChain) WriteBlock(block *types.Block) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
+	if reorg := bc.writeBlock(block); reorg != nil {
+		bc.chainReorgFeed.Send(*reorg)
+	}
+}
+
+func (bc *BlockChain) writeBlock(block *types.Block) *core.ChainReorgEvent {
 	// Make sure no inconsistent state is leaked during insertion
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
+	var reorg *core.ChainReorgEvent
+	if head := bc.CurrentBlock(); block.ParentHash() != head.Hash() {
+		reorg = bc.reorg(head, block)
+	}
 	rawdb.WriteBlock(bc.db, block)
+	rawdb.WriteTxLookupEntries(bc.db, block)
 
 	bc.insert(block)
 	bc.futureBlocks.Remove(block.Hash())
+	return reorg
+}
+
+// reorg moves the canonical chain from the branch of oldBlock onto the branch
+// of newBlock, which the caller is about to insert as the new head. It fixes
+// up the canonical number mappings and transaction indexes of the ancestors
+// changing sides and returns the event describing the switch, or nil if no
+// block leaves the canonical chain.
+func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) *core.ChainReorgEvent {
+	var (
+		head           = newBlock
+		dropped, added []*types.Block
+	)
+	// Reduce the longer chain to the same number as the shorter one
+	for oldBlock != nil && newBlock != nil && oldBlock.NumberU64() > newBlock.NumberU64() {
+		dropped = append(dropped, oldBlock)
+		oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1)
+	}
+	for oldBlock != nil && newBlock != nil && newBlock.NumberU64() > oldBlock.NumberU64() {
+		added = append(added, newBlock)
+		newBlock = bc.GetBlock(newBlock.ParentHash(), newBlock.NumberU64()-1)
+	}
+	// Step back on both chains until the common ancestor is found
+	for oldBlock != nil && newBlock != nil && oldBlock.Hash() != newBlock.Hash() {
+		dropped = append(dropped, oldBlock)
+		added = append(added, newBlock)
+		oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1)
+		newBlock = bc.GetBlock(newBlock.ParentHash(), newBlock.NumberU64()-1)
+	}
+	if oldBlock == nil || newBlock == nil {
+		log.Error("Invalid reorg chain", "head", head.Hash())
+		return nil
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	log.Warn("Chain split detected", "number", newBlock.Number(), "hash", newBlock.Hash(),
+		"drop", len(dropped), "dropfrom", dropped[0].Hash(), "add", len(added), "addfrom", head.Hash())
+
+	event := &core.ChainReorgEvent{}
+	for _, block := range dropped {
+		rawdb.DeleteTxLookupEntries(bc.db, block)
+		if block.NumberU64() > head.NumberU64() {
+			rawdb.DeleteCanonicalHash(bc.db, block.NumberU64())
+		}
+		event.Dropped = append(event.Dropped, block.Hash())
+	}
+	for _, block := range added {
+		if block.Hash() != head.Hash() {
+			rawdb.WriteCanonicalHash(bc.db, block.Hash(), block.NumberU64())
+			rawdb.WriteTxLookupEntries(bc.db, block)
+		}
+		event.Added = append(event.Added, block.Hash())
+	}
+	return event
 }
 
 
@@ -588,8 +657,8 @@ func (bc *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (i
 // chain. If an error is returned it will return the index number of the failing
 // block as well an error describing what went wrong.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
-	n, _, err := bc.insertChain(chain)
-	// bc.PostChainEvents(events)
+	n, events, err := bc.insertChain(chain)
+	bc.PostChainEvents(events)
 	return n, err
 }
 
@@ -750,3 +819,8 @@ func (bc *BlockChain) CurrentFastBlock() *types.Block {
 func (bc *BlockChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
 	return bc.scope.Track(bc.chainHeadFeed.Subscribe(ch))
 }
+
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.chainReorgFeed.Subscribe(ch))
+}
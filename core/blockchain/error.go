@@ -12,4 +12,8 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrBlockTooHeavy is returned if the transactions of a block to import
+	// weigh more than the block weight limit.
+	ErrBlockTooHeavy = errors.New("block exceeds weight limit")
 )
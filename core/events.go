@@ -20,3 +20,11 @@ type ChainEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainReorgEvent is posted when the canonical chain switches to a different
+// branch. Dropped and Added hold the block hashes leaving and joining the
+// canonical chain, highest block first.
+type ChainReorgEvent struct {
+	Dropped []common.Hash
+	Added   []common.Hash
+}
@@ -7,10 +7,14 @@ import (
 	"time"
 
 	"github.com/srchain/srcd/common/common"
-	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
 	"github.com/srchain/srcd/core/types"
 )
 
+// txChanSize is the size of channel listening to the transactions entering
+// the pool.
+const txChanSize = 4096
+
 // blockChain provides the state of blockchain and current gas limit to do
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
@@ -47,7 +51,7 @@ type TxPoolConfig struct {
 type TxPool struct {
 	config  TxPoolConfig
 	chain   blockChain
-	pool    *transaction.TxPool
+	pool    *txpool.TxPool
 	pending types.Transactions
 
 	mu sync.RWMutex
@@ -71,7 +75,7 @@ func (pool *TxPool) SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscrip
 func NewTxPool(config TxPoolConfig, chain blockChain) *TxPool {
 	// Create the transaction pool with its initial settings
 	pool := &TxPool{
-		pool:    transaction.NewTxPool(),
+		pool:    txpool.NewTxPool(nil, nil),
 		config:  config,
 		chain:   chain,
 		pending: make(types.Transactions, 1024),
@@ -90,7 +94,9 @@ func NewTxPool(config TxPoolConfig, chain blockChain) *TxPool {
 func (pool *TxPool) loop() {
 	defer pool.wg.Done()
 
-	txCh := pool.pool.GetMsgCh()
+	txCh := make(chan *txpool.TxPoolMsg, txChanSize)
+	txSub := pool.pool.SubscribeNewTxs(txCh)
+	defer txSub.Unsubscribe()
 
 	for {
 		select {
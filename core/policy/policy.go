@@ -0,0 +1,101 @@
+// Package policy implements the standardness rules a node applies before
+// accepting a transaction into its pool and relaying it. They are stricter
+// than consensus: a non-standard transaction may still be valid in a block.
+package policy
+
+import (
+	"fmt"
+
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/errors"
+)
+
+// Reject reasons. Every error returned by Config.CheckTx wraps exactly one
+// of these.
+var (
+	ErrCoinbase           = errors.New("coinbase transaction")
+	ErrTxWeight           = errors.New("transaction weight exceeds maximum")
+	ErrNonStandardProgram = errors.New("non-standard control program")
+	ErrDust               = errors.New("dust output")
+	ErrWitnessArgCount    = errors.New("too many witness arguments")
+	ErrWitnessArgSize     = errors.New("witness argument too large")
+)
+
+// Config holds the standardness limits.
+type Config struct {
+	// MaxTxWeight is the largest weight of a standard transaction.
+	MaxTxWeight uint64
+
+	// MaxWitnessArgs is the most witness arguments a standard input may
+	// carry, and MaxWitnessArgSize the largest any one of them may be.
+	MaxWitnessArgs    int
+	MaxWitnessArgSize int
+
+	// DustThresholds holds, per asset, the smallest amount a standard output
+	// may carry. Assets without an entry use DefaultDustThreshold.
+	DustThresholds       map[transaction.AssetID]uint64
+	DefaultDustThreshold uint64
+}
+
+// DefaultConfig contains the standardness limits used by default.
+var DefaultConfig = Config{
+	MaxTxWeight:       transaction.MaxBlockWeight / 10,
+	MaxWitnessArgs:    20,
+	MaxWitnessArgSize: 520,
+	DustThresholds: map[transaction.AssetID]uint64{
+		*transaction.SRCAssetID: 1000,
+	},
+	DefaultDustThreshold: 1,
+}
+
+// CheckTx returns an error wrapping the reason tx is non-standard, or nil if
+// it may be accepted into the pool.
+func (c *Config) CheckTx(tx *transaction.Tx) error {
+	if weight := tx.TxData.Weight(); weight > c.MaxTxWeight {
+		return fmt.Errorf("%w: %d > %d", ErrTxWeight, weight, c.MaxTxWeight)
+	}
+
+	for i, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*transaction.CoinbaseInput); ok {
+			return ErrCoinbase
+		}
+		args := in.Arguments()
+		if len(args) > c.MaxWitnessArgs {
+			return fmt.Errorf("%w: input %d has %d, maximum is %d", ErrWitnessArgCount, i, len(args), c.MaxWitnessArgs)
+		}
+		for j, arg := range args {
+			if len(arg) > c.MaxWitnessArgSize {
+				return fmt.Errorf("%w: input %d argument %d is %d bytes, maximum is %d", ErrWitnessArgSize, i, j, len(arg), c.MaxWitnessArgSize)
+			}
+		}
+	}
+
+	for i, out := range tx.Outputs {
+		if !IsStandardProgram(out.ControlProgram) {
+			return fmt.Errorf("%w: output %d", ErrNonStandardProgram, i)
+		}
+		if out.AssetId == nil {
+			return fmt.Errorf("%w: output %d has no asset", ErrNonStandardProgram, i)
+		}
+		if dust := c.DustThreshold(*out.AssetId); out.Amount < dust {
+			return fmt.Errorf("%w: output %d amount %d is below %d", ErrDust, i, out.Amount, dust)
+		}
+	}
+	return nil
+}
+
+// DustThreshold returns the smallest amount of assetID a standard output may
+// carry.
+func (c *Config) DustThreshold(assetID transaction.AssetID) uint64 {
+	if threshold, ok := c.DustThresholds[assetID]; ok {
+		return threshold
+	}
+	return c.DefaultDustThreshold
+}
+
+// IsStandardProgram reports whether prog is one of the known control program
+// templates.
+func IsStandardProgram(prog []byte) bool {
+	return vm.IsP2WPKHProgram(prog) || vm.IsP2WSHProgram(prog) || vm.IsHTLCProgram(prog)
+}
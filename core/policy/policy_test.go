@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/srchain/srcd/core/transaction"
+)
+
+func TestCheckTx(t *testing.T) {
+	prog := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	spend := func(args ...[]byte) *transaction.TxInput {
+		return transaction.NewSpendInput(args, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 100000, 0, prog)
+	}
+	output := func(amount uint64, prog []byte) *transaction.TxOutput {
+		return transaction.NewTxOutput(*transaction.SRCAssetID, amount, prog)
+	}
+
+	cases := []struct {
+		desc string
+		data transaction.TxData
+		want error
+	}{
+		{
+			desc: "standard",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend(make([]byte, 64))}, Outputs: []*transaction.TxOutput{output(5000, prog)}},
+		},
+		{
+			desc: "coinbase",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{transaction.NewCoinbaseInput(nil)}, Outputs: []*transaction.TxOutput{output(5000, prog)}},
+			want: ErrCoinbase,
+		},
+		{
+			desc: "unknown program",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend()}, Outputs: []*transaction.TxOutput{output(5000, []byte{0x51})}},
+			want: ErrNonStandardProgram,
+		},
+		{
+			desc: "dust",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend()}, Outputs: []*transaction.TxOutput{output(999, prog)}},
+			want: ErrDust,
+		},
+		{
+			desc: "too many witness arguments",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend(make([][]byte, 21)...)}, Outputs: []*transaction.TxOutput{output(5000, prog)}},
+			want: ErrWitnessArgCount,
+		},
+		{
+			desc: "witness argument too large",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend(make([]byte, 521))}, Outputs: []*transaction.TxOutput{output(5000, prog)}},
+			want: ErrWitnessArgSize,
+		},
+		{
+			desc: "too heavy",
+			data: transaction.TxData{Inputs: []*transaction.TxInput{spend()}, Outputs: []*transaction.TxOutput{output(5000, prog), output(5000, prog)}},
+			want: ErrTxWeight,
+		},
+	}
+
+	for _, c := range cases {
+		config := DefaultConfig
+		if c.want == ErrTxWeight {
+			config.MaxTxWeight = c.data.Weight() - 1
+		}
+		tx := transaction.NewTx(c.data)
+		if err := config.CheckTx(&tx); !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v want %v", c.desc, err, c.want)
+		}
+	}
+
+	if IsStandardProgram(bytes.Repeat([]byte{0x51}, 22)) {
+		t.Error("arbitrary program reported as standard")
+	}
+}
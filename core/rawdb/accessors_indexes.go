@@ -0,0 +1,264 @@
+package rawdb
+
+import (
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/log"
+	"github.com/srchain/srcd/rlp"
+)
+
+// TxLookupEntry is a positional metadata to help looking up the data content of
+// a transaction given only its ID.
+type TxLookupEntry struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64
+}
+
+// OutputLookupEntry is a positional metadata to help looking up a transaction
+// output given only its ID.
+type OutputLookupEntry struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64 // position of the transaction within the block
+	Position   uint64 // position of the output within the transaction
+}
+
+// ReadTxLookupEntry retrieves the positional metadata associated with a
+// transaction ID to allow retrieving the transaction by ID.
+func ReadTxLookupEntry(db DatabaseReader, id transaction.Hash) (common.Hash, uint64, uint64) {
+	data, _ := db.Get(txLookupKey(id))
+	if len(data) == 0 {
+		return common.Hash{}, 0, 0
+	}
+	var entry TxLookupEntry
+	if err := rlp.DecodeBytes(data, &entry); err != nil {
+		log.Error("Invalid transaction lookup entry RLP", "id", id.Bytes(), "err", err)
+		return common.Hash{}, 0, 0
+	}
+	return entry.BlockHash, entry.BlockIndex, entry.Index
+}
+
+// ReadOutputLookupEntry retrieves the positional metadata associated with an
+// output ID, or nil if the output is unknown.
+func ReadOutputLookupEntry(db DatabaseReader, id transaction.Hash) *OutputLookupEntry {
+	data, _ := db.Get(outputLookupKey(id))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(OutputLookupEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid output lookup entry RLP", "id", id.Bytes(), "err", err)
+		return nil
+	}
+	return entry
+}
+
+// ReadOutputSpender retrieves the ID of the transaction spending an output.
+// Spenders in blocks that are no longer canonical are not returned.
+func ReadOutputSpender(db DatabaseReader, id transaction.Hash) (transaction.Hash, bool) {
+	data, _ := db.Get(outputSpentKey(id))
+	if len(data) != 32 {
+		return transaction.Hash{}, false
+	}
+	var b32 [32]byte
+	copy(b32[:], data)
+	spender := transaction.NewHash(b32)
+
+	blockHash, blockNumber, _ := ReadTxLookupEntry(db, spender)
+	if blockHash == (common.Hash{}) || ReadCanonicalHash(db, blockNumber) != blockHash {
+		return transaction.Hash{}, false
+	}
+	return spender, true
+}
+
+// utxoEntry is the stored form of an unspent output in the program index.
+type utxoEntry struct {
+	SourceID  []byte
+	AssetID   []byte
+	Amount    uint64
+	SourcePos uint64
+}
+
+// WriteTxLookupEntries stores the positional metadata of every transaction in
+// a block, of the outputs they create and of the outputs they spend, and moves
+// the unspent output index forward over the block.
+func WriteTxLookupEntries(db DatabaseWriteDeleter, block *types.Block) {
+	for i, t := range block.Transactions() {
+		tx := transaction.NewTx(t.Tx)
+
+		data, err := rlp.EncodeToBytes(TxLookupEntry{
+			BlockHash:  block.Hash(),
+			BlockIndex: block.NumberU64(),
+			Index:      uint64(i),
+		})
+		if err != nil {
+			log.Crit("Failed to encode transaction lookup entry", "err", err)
+		}
+		if err := db.Put(txLookupKey(tx.ID), data); err != nil {
+			log.Crit("Failed to store transaction lookup entry", "err", err)
+		}
+
+		for pos, id := range tx.ResultIds {
+			data, err := rlp.EncodeToBytes(OutputLookupEntry{
+				BlockHash:  block.Hash(),
+				BlockIndex: block.NumberU64(),
+				Index:      uint64(i),
+				Position:   uint64(pos),
+			})
+			if err != nil {
+				log.Crit("Failed to encode output lookup entry", "err", err)
+			}
+			if err := db.Put(outputLookupKey(*id), data); err != nil {
+				log.Crit("Failed to store output lookup entry", "err", err)
+			}
+		}
+		for _, utxo := range createdOutputs(&tx) {
+			writeUnspentOutput(db, utxo)
+		}
+		for _, utxo := range spentOutputs(&tx) {
+			if err := db.Put(outputSpentKey(utxo.OutputID), tx.ID.Bytes()); err != nil {
+				log.Crit("Failed to store output spender", "err", err)
+			}
+			db.Delete(utxoKey(utxo.ControlProgram, utxo.OutputID))
+		}
+	}
+}
+
+// DeleteTxLookupEntries removes the lookup metadata written for a block and
+// returns the outputs it spent to the unspent output index.
+func DeleteTxLookupEntries(db DatabaseWriteDeleter, block *types.Block) {
+	for _, t := range block.Transactions() {
+		tx := transaction.NewTx(t.Tx)
+		db.Delete(txLookupKey(tx.ID))
+		for _, id := range tx.ResultIds {
+			db.Delete(outputLookupKey(*id))
+		}
+		for _, utxo := range createdOutputs(&tx) {
+			db.Delete(utxoKey(utxo.ControlProgram, utxo.OutputID))
+		}
+		for _, utxo := range spentOutputs(&tx) {
+			db.Delete(outputSpentKey(utxo.OutputID))
+			writeUnspentOutput(db, utxo)
+		}
+	}
+}
+
+// ReadUnspentOutputs retrieves the outputs paying to program that no canonical
+// transaction has spent yet, ordered by output ID.
+func ReadUnspentOutputs(db DatabaseIteratee, program []byte) []*transaction.UTXO {
+	prefix := utxoKey(program, transaction.Hash{})[:len(utxoPrefix)+32]
+	it := db.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var utxos []*transaction.UTXO
+	for it.Next() {
+		var entry utxoEntry
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil {
+			log.Error("Invalid unspent output entry RLP", "key", it.Key(), "err", err)
+			continue
+		}
+		var id, sourceID, assetID [32]byte
+		copy(id[:], it.Key()[len(prefix):])
+		copy(sourceID[:], entry.SourceID)
+		copy(assetID[:], entry.AssetID)
+
+		utxos = append(utxos, &transaction.UTXO{
+			OutputID:       transaction.NewHash(id),
+			SourceID:       transaction.NewHash(sourceID),
+			AssetID:        transaction.AssetID(transaction.NewHash(assetID)),
+			Amount:         entry.Amount,
+			SourcePos:      entry.SourcePos,
+			ControlProgram: common.CopyBytes(program),
+			Address:        transaction.ProgramAddress(program),
+		})
+	}
+	return utxos
+}
+
+// HasUnspentOutput reports whether the output id paying to program is created
+// by a canonical transaction and spent by none.
+func HasUnspentOutput(db DatabaseReader, program []byte, id transaction.Hash) bool {
+	ok, _ := db.Has(utxoKey(program, id))
+	return ok
+}
+
+func writeUnspentOutput(db DatabaseWriter, utxo *transaction.UTXO) {
+	data, err := rlp.EncodeToBytes(utxoEntry{
+		SourceID:  utxo.SourceID.Bytes(),
+		AssetID:   utxo.AssetID.Bytes(),
+		Amount:    utxo.Amount,
+		SourcePos: utxo.SourcePos,
+	})
+	if err != nil {
+		log.Crit("Failed to encode unspent output", "err", err)
+	}
+	if err := db.Put(utxoKey(utxo.ControlProgram, utxo.OutputID), data); err != nil {
+		log.Crit("Failed to store unspent output", "err", err)
+	}
+}
+
+// createdOutputs returns the outputs tx creates.
+func createdOutputs(tx *transaction.Tx) []*transaction.UTXO {
+	var utxos []*transaction.UTXO
+	for i, out := range tx.Outputs {
+		if i >= len(tx.ResultIds) || out.AssetId == nil {
+			continue
+		}
+		entry, err := tx.Output(*tx.ResultIds[i])
+		if err != nil {
+			continue
+		}
+		utxos = append(utxos, &transaction.UTXO{
+			OutputID:       *tx.ResultIds[i],
+			SourceID:       *entry.Source.Ref,
+			AssetID:        *out.AssetId,
+			Amount:         out.Amount,
+			SourcePos:      entry.Source.Position,
+			ControlProgram: out.ControlProgram,
+		})
+	}
+	return utxos
+}
+
+// spentOutputs returns the outputs the spend inputs of tx consume, as their
+// spend commitments describe them.
+func spentOutputs(tx *transaction.Tx) []*transaction.UTXO {
+	var utxos []*transaction.UTXO
+	for i, in := range tx.Inputs {
+		sp, ok := in.TypedInput.(*transaction.SpendInput)
+		if !ok || sp.AssetId == nil {
+			continue
+		}
+		spend, ok := tx.Entries[tx.InputIDs[i]].(*transaction.Spend)
+		if !ok || spend.SpentOutputId == nil {
+			continue
+		}
+		utxos = append(utxos, &transaction.UTXO{
+			OutputID:       *spend.SpentOutputId,
+			SourceID:       sp.SourceID,
+			AssetID:        *sp.AssetId,
+			Amount:         sp.Amount,
+			SourcePos:      sp.SourcePosition,
+			ControlProgram: sp.ControlProgram,
+		})
+	}
+	return utxos
+}
+
+// ReadTransaction retrieves a specific transaction from the database, along with
+// its added positional metadata. Transactions of blocks that are no longer
+// canonical are not returned.
+func ReadTransaction(db DatabaseReader, id transaction.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
+	blockHash, blockNumber, txIndex := ReadTxLookupEntry(db, id)
+	if blockHash == (common.Hash{}) || ReadCanonicalHash(db, blockNumber) != blockHash {
+		return nil, common.Hash{}, 0, 0
+	}
+	body := ReadBody(db, blockHash, blockNumber)
+	if body == nil || len(body.Transactions) <= int(txIndex) {
+		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash, "index", txIndex)
+		return nil, common.Hash{}, 0, 0
+	}
+	return body.Transactions[txIndex], blockHash, blockNumber, txIndex
+}
@@ -0,0 +1,94 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/database"
+)
+
+// Tests that positional lookup metadata can be stored and retrieved.
+func TestLookupStorage(t *testing.T) {
+	db := database.NewMemDatabase()
+
+	prog := []byte{0x51}
+	data := transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 1000, 0, prog),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, 600, prog),
+			transaction.NewTxOutput(*transaction.SRCAssetID, 390, prog),
+		},
+	}
+	tx := transaction.NewTx(data)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, []*types.Transaction{{Tx: data}})
+
+	if hash, _, _ := ReadTxLookupEntry(db, tx.ID); hash != (common.Hash{}) {
+		t.Fatalf("lookup entry present before write: %x", hash)
+	}
+	WriteTxLookupEntries(db, block)
+
+	hash, number, index := ReadTxLookupEntry(db, tx.ID)
+	if hash != block.Hash() || number != block.NumberU64() || index != 0 {
+		t.Fatalf("tx lookup mismatch: have %x/%d/%d, want %x/%d/0", hash, number, index, block.Hash(), block.NumberU64())
+	}
+	for pos, id := range tx.ResultIds {
+		entry := ReadOutputLookupEntry(db, *id)
+		if entry == nil {
+			t.Fatalf("output %d: lookup entry missing", pos)
+		}
+		if entry.BlockHash != block.Hash() || entry.Index != 0 || entry.Position != uint64(pos) {
+			t.Errorf("output %d: lookup mismatch %+v", pos, entry)
+		}
+	}
+	spent := tx.SpentOutputIDs[0]
+	if _, ok := ReadOutputSpender(db, spent); ok {
+		t.Errorf("spender returned from a block that is not canonical")
+	}
+	WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	if spender, ok := ReadOutputSpender(db, spent); !ok || spender != tx.ID {
+		t.Errorf("spender mismatch: have %x (%v), want %x", spender.Bytes(), ok, tx.ID.Bytes())
+	}
+
+	utxos := ReadUnspentOutputs(db, prog)
+	if len(utxos) != 2 {
+		t.Fatalf("unspent output count mismatch: have %d, want 2", len(utxos))
+	}
+	for _, utxo := range utxos {
+		if utxo.OutputID != *tx.ResultIds[0] && utxo.OutputID != *tx.ResultIds[1] {
+			t.Errorf("unexpected unspent output %x", utxo.OutputID.Bytes())
+		}
+		if utxo.OutputID == spent {
+			t.Errorf("spent output %x still unspent", spent.Bytes())
+		}
+	}
+
+	if !HasUnspentOutput(db, prog, *tx.ResultIds[0]) {
+		t.Errorf("created output %x not unspent", tx.ResultIds[0].Bytes())
+	}
+	if HasUnspentOutput(db, prog, spent) {
+		t.Errorf("spent output %x still unspent", spent.Bytes())
+	}
+
+	DeleteTxLookupEntries(db, block)
+	if hash, _, _ := ReadTxLookupEntry(db, tx.ID); hash != (common.Hash{}) {
+		t.Errorf("tx lookup entry still present after delete")
+	}
+	if entry := ReadOutputLookupEntry(db, *tx.ResultIds[0]); entry != nil {
+		t.Errorf("output lookup entry still present after delete")
+	}
+	if _, ok := ReadOutputSpender(db, spent); ok {
+		t.Errorf("spender still present after delete")
+	}
+	if utxos := ReadUnspentOutputs(db, prog); len(utxos) != 1 || utxos[0].OutputID != spent || utxos[0].Amount != 1000 {
+		t.Errorf("spent output not restored after delete: %+v", utxos)
+	}
+	if !HasUnspentOutput(db, prog, spent) || HasUnspentOutput(db, prog, *tx.ResultIds[0]) {
+		t.Errorf("unspent outputs not rolled back after delete")
+	}
+}
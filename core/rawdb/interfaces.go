@@ -1,5 +1,7 @@
 package rawdb
 
+import "github.com/syndtr/goleveldb/leveldb/iterator"
+
 // DatabaseReader wraps the Has and Get method of a backing data store.
 type DatabaseReader interface {
 	Has(key []byte) (bool, error)
@@ -15,3 +17,14 @@ type DatabaseWriter interface {
 type DatabaseDeleter interface {
 	Delete(key []byte) error
 }
+
+// DatabaseWriteDeleter wraps the Put and Delete methods of a backing data store.
+type DatabaseWriteDeleter interface {
+	DatabaseWriter
+	DatabaseDeleter
+}
+
+// DatabaseIteratee wraps the NewIteratorWithPrefix method of a backing data store.
+type DatabaseIteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
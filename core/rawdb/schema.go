@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 
 	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/crypto/sha3pool"
 )
 
 var (
@@ -19,6 +21,11 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 
+	txLookupPrefix     = []byte("l") // txLookupPrefix + tx id -> transaction lookup metadata
+	outputLookupPrefix = []byte("o") // outputLookupPrefix + output id -> output lookup metadata
+	outputSpentPrefix  = []byte("s") // outputSpentPrefix + output id -> spending tx id
+	utxoPrefix         = []byte("u") // utxoPrefix + program hash + output id -> unspent output
+
 	// headFastBlockKey tracks the latest known incomplete block's hash during fast sync.
 	headFastBlockKey = []byte("LastFast")
 
@@ -36,6 +43,28 @@ func configKey(hash common.Hash) []byte {
 
 
 
+// txLookupKey = txLookupPrefix + tx id
+func txLookupKey(id transaction.Hash) []byte {
+	return append(txLookupPrefix, id.Bytes()...)
+}
+
+// outputLookupKey = outputLookupPrefix + output id
+func outputLookupKey(id transaction.Hash) []byte {
+	return append(outputLookupPrefix, id.Bytes()...)
+}
+
+// outputSpentKey = outputSpentPrefix + output id
+func outputSpentKey(id transaction.Hash) []byte {
+	return append(outputSpentPrefix, id.Bytes()...)
+}
+
+// utxoKey = utxoPrefix + sha3(program) + output id
+func utxoKey(program []byte, id transaction.Hash) []byte {
+	var hash [32]byte
+	sha3pool.Sum256(hash[:], program)
+	return append(append(append([]byte{}, utxoPrefix...), hash[:]...), id.Bytes()...)
+}
+
 // encodeBlockNumber encodes a block number as big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
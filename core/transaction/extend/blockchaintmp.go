@@ -151,10 +151,10 @@ func ReadExtensibleString(r *Reader, f func(*Reader) error) (suffix []byte, err
 	}
 
 	sr := NewReader(s)
-	//err = f(sr)
-	//if err != nil {
-	//	return nil, err
-	//}
+	err = f(sr)
+	if err != nil {
+		return nil, err
+	}
 	return sr.buf, nil
 }
 func WriteExtensibleString(w io.Writer, suffix []byte, f func(writer io.Writer) error) (int, error) {
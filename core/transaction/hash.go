@@ -0,0 +1,37 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// MarshalText satisfies the TextMarshaler interface.
+// It returns the bytes of h encoded in hex,
+// for formats that can't hold arbitrary binary data.
+// It never returns an error.
+func (h Hash) MarshalText() ([]byte, error) {
+	b := h.Byte32()
+	v := make([]byte, 64)
+	hex.Encode(v, b[:])
+	return v, nil
+}
+
+// UnmarshalText satisfies the TextUnmarshaler interface.
+// It decodes hex data from b into h.
+func (h *Hash) UnmarshalText(v []byte) error {
+	var b32 [32]byte
+	if len(v) != 64 {
+		return fmt.Errorf("bad length hash string %d", len(v))
+	}
+	if _, err := hex.Decode(b32[:], v); err != nil {
+		return err
+	}
+	*h = NewHash(b32)
+	return nil
+}
+
+// MarshalText satisfies the TextMarshaler interface.
+func (a AssetID) MarshalText() ([]byte, error) { return Hash(a).MarshalText() }
+
+// UnmarshalText satisfies the TextUnmarshaler interface.
+func (a *AssetID) UnmarshalText(b []byte) error { return (*Hash)(a).UnmarshalText(b) }
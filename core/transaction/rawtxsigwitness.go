@@ -12,12 +12,48 @@ type RawTxSigWitness struct {
 	Quorum int                  `json:"quorum"`
 	Keys   []keyID              `json:"keys"`
 	Sigs   []HexBytes `json:"signatures"`
+
+	// SigHashType, when set, is appended to each signature and selects
+	// the parts of the transaction it commits to.
+	SigHashType SigHashType `json:"sighash_type,omitempty"`
 }
 type keyID struct {
 	XPub           chainkd.XPub         `json:"xpub"`
 	DerivationPath []HexBytes `json:"derivation_path"`
 }
 
+// path returns the derivation path of the key below its root key.
+func (k keyID) path() [][]byte {
+	path := make([][]byte, len(k.DerivationPath))
+	for i, sel := range k.DerivationPath {
+		path[i] = sel
+	}
+	return path
+}
+
+// NewRawTxSigWitness creates a witness component expecting quorum signatures
+// from the given keys.
+func NewRawTxSigWitness(quorum int, xpubs []chainkd.XPub) *RawTxSigWitness {
+	sw := &RawTxSigWitness{Quorum: quorum}
+	for _, xpub := range xpubs {
+		sw.Keys = append(sw.Keys, keyID{XPub: xpub})
+	}
+	return sw
+}
+
+// NewDerivedRawTxSigWitness is like NewRawTxSigWitness for keys derived
+// from root keys along the selectors of path. The path is recorded with each
+// key so that a signer holding only the root keys can derive them.
+func NewDerivedRawTxSigWitness(quorum int, xpubs []chainkd.XPub, path [][]byte) *RawTxSigWitness {
+	sw := NewRawTxSigWitness(quorum, xpubs)
+	for i := range sw.Keys {
+		for _, sel := range path {
+			sw.Keys[i].DerivationPath = append(sw.Keys[i].DerivationPath, HexBytes(sel))
+		}
+	}
+	return sw
+}
+
 func (sw RawTxSigWitness) materialize(args *[][]byte) error {
 	var nsigs int
 	for i := 0; i < len(sw.Sigs) && nsigs < sw.Quorum; i++ {
@@ -36,11 +72,13 @@ func (sw RawTxSigWitness) MarshalJSON() ([]byte, error) {
 		Quorum int                  `json:"quorum"`
 		Keys   []keyID              `json:"keys"`
 		Sigs   []HexBytes `json:"signatures"`
+		SigHashType SigHashType `json:"sighash_type,omitempty"`
 	}{
 		Type:   "raw_tx_signature",
 		Quorum: sw.Quorum,
 		Keys:   sw.Keys,
 		Sigs:   sw.Sigs,
+		SigHashType: sw.SigHashType,
 	}
 	return json.Marshal(obj)
 }
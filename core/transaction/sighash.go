@@ -0,0 +1,79 @@
+package transaction
+
+import (
+	"io"
+
+	"github.com/srchain/srcd/crypto/sha3pool"
+	"github.com/srchain/srcd/errors"
+)
+
+// SigHashType selects the parts of a transaction a signature commits to. It
+// travels in the witness as a byte appended to the signature; a signature
+// without one commits to the whole transaction through SigHash.
+type SigHashType byte
+
+const (
+	// SigHashAll commits to every input and every output.
+	SigHashAll SigHashType = 0x01
+	// SigHashSingle commits to every input and to the output at the same
+	// index as the signed input.
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay is combined with one of the above to commit to
+	// the signed input alone, so that others may add inputs of their own.
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+var (
+	ErrSigHashType   = errors.New("invalid sighash type")
+	ErrSigHashSingle = errors.New("no output at the index of the signed input")
+)
+
+// SigHashWithType returns the digest signed by a signature of type t on
+// input n. Outputs are committed to by content rather than by entry ID, since
+// output IDs change whenever an input is added.
+func (tx *TxWrap) SigHashWithType(n uint32, t SigHashType) (hash Hash, err error) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write([]byte{byte(t)})
+	tx.InputIDs[n].WriteTo(hasher)
+	mustWriteForHash(hasher, tx.Version)
+	mustWriteForHash(hasher, tx.TimeRange)
+
+	if t&SigHashAnyoneCanPay == 0 {
+		mustWriteForHash(hasher, tx.InputIDs)
+	}
+
+	switch t &^ SigHashAnyoneCanPay {
+	case SigHashAll:
+		for _, id := range tx.ResultIds {
+			if err := tx.writeOutputForSigHash(hasher, *id); err != nil {
+				return hash, err
+			}
+		}
+
+	case SigHashSingle:
+		if int(n) >= len(tx.ResultIds) {
+			return hash, ErrSigHashSingle
+		}
+		if err := tx.writeOutputForSigHash(hasher, *tx.ResultIds[n]); err != nil {
+			return hash, err
+		}
+
+	default:
+		return hash, ErrSigHashType
+	}
+
+	hash.ReadFrom(hasher)
+	return hash, nil
+}
+
+func (tx *TxWrap) writeOutputForSigHash(w io.Writer, id Hash) error {
+	o, err := tx.Output(id)
+	if err != nil {
+		return err
+	}
+	mustWriteForHash(w, o.Source.Value)
+	mustWriteForHash(w, o.ControlProgram)
+	return nil
+}
@@ -0,0 +1,73 @@
+package transaction
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+)
+
+func TestSigHashTypes(t *testing.T) {
+	xprv, xpub, err := chainkd.NewXKeys(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := vm.P2WSHProgram(ripemd160.Ripemd160(xpub.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := func(sourcePos uint64, sigHashType SigHashType) InputAndSigInst {
+		sw := NewRawTxSigWitness(1, []chainkd.XPub{xpub})
+		sw.SigHashType = sigHashType
+		sigInst := &SigningInstruction{}
+		sigInst.WitnessComponents = append(sigInst.WitnessComponents, sw, DataWitness(xpub.PublicKey()))
+		return NewInputAndSigInst(NewSpendInput(nil, Hash{V0: 1}, *SRCAssetID, 100, sourcePos, prog), sigInst)
+	}
+
+	cases := []struct {
+		sigHashType SigHashType
+		wantValid   bool // after another party adds an input and an output
+	}{
+		{0, false},
+		{SigHashAll, false},
+		{SigHashSingle, false},
+		{SigHashAll | SigHashAnyoneCanPay, false},
+		{SigHashSingle | SigHashAnyoneCanPay, true},
+	}
+	for _, c := range cases {
+		tpl, _, err := BuildUtxoTemplate([]InputAndSigInst{input(0, c.sigHashType)}, []*TxOutput{NewTxOutput(*SRCAssetID, 100, prog)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Sign(tpl, xprv); err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyTx(&tpl.Transaction.TxWrap, 1); err != nil {
+			t.Errorf("sighash type %#x: %v", c.sigHashType, err)
+			continue
+		}
+
+		// Another party extends the transaction with an input and output
+		// of its own, keeping the signed witness.
+		data := tpl.Transaction.TxData
+		data.Inputs = append(data.Inputs, NewSpendInput(nil, Hash{V0: 2}, *SRCAssetID, 50, 0, prog))
+		data.Outputs = append(data.Outputs, NewTxOutput(*SRCAssetID, 50, prog))
+		extended := NewTx(data)
+
+		ctx, err := NewTxVMContext(&extended.TxWrap, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Verify(ctx, vm.DefaultRunLimit); (err == nil) != c.wantValid {
+			t.Errorf("sighash type %#x after extension: got err %v, want valid %v", c.sigHashType, err, c.wantValid)
+		}
+	}
+
+	tpl, _, _ := BuildUtxoTemplate([]InputAndSigInst{input(0, 0x42)}, []*TxOutput{NewTxOutput(*SRCAssetID, 100, prog)})
+	if err := Sign(tpl, xprv); err != ErrSigHashType {
+		t.Errorf("signing with bad sighash type: got %v want %v", err, ErrSigHashType)
+	}
+}
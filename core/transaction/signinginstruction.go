@@ -1,5 +1,10 @@
 package transaction
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type SigningInstruction struct {
 	Position          uint32             `json:"position"`
 	WitnessComponents []witnessComponent `json:"witness_components,omitempty"`
@@ -17,3 +22,50 @@ func (sw RawWitness) materialize(args *[][]byte) error {
 	return nil
 }
 
+
+// UnmarshalJSON decodes a signing instruction, choosing the type of each
+// witness component from its "type" field.
+func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
+	var pre struct {
+		Position          uint32            `json:"position"`
+		WitnessComponents []json.RawMessage `json:"witness_components"`
+	}
+	if err := json.Unmarshal(b, &pre); err != nil {
+		return err
+	}
+
+	si.Position = pre.Position
+	si.WitnessComponents = make([]witnessComponent, 0, len(pre.WitnessComponents))
+	for i, raw := range pre.WitnessComponents {
+		var t struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+
+		var component witnessComponent
+		switch t.Type {
+		case "data":
+			var d struct {
+				Value HexBytes `json:"value"`
+			}
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+			component = DataWitness(d.Value)
+
+		case "raw_tx_signature":
+			sw := new(RawTxSigWitness)
+			if err := json.Unmarshal(raw, sw); err != nil {
+				return err
+			}
+			component = sw
+
+		default:
+			return fmt.Errorf("unknown type %q in witness component %d", t.Type, i)
+		}
+		si.WitnessComponents = append(si.WitnessComponents, component)
+	}
+	return nil
+}
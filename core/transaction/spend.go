@@ -1,7 +1,11 @@
 package transaction
 
 import (
+	"fmt"
 	"io"
+
+	"github.com/srchain/srcd/core/transaction/extend"
+	"github.com/srchain/srcd/errors"
 )
 
 const (
@@ -52,8 +56,58 @@ type SpendCommitment struct {
 
 
 func (sc *SpendCommitment) writeExtensibleString(w io.Writer, suffix []byte, assetVersion uint64) error {
-	//_, err := blockchain.WriteExtensibleString(w, suffix, func(w io.Writer) error {
-	//	return sc.writeContents(w, suffix, assetVersion)
-	//})
-	return nil
+	_, err := extend.WriteExtensibleString(w, suffix, func(w io.Writer) error {
+		return sc.writeContents(w, suffix, assetVersion)
+	})
+	return err
+}
+
+func (sc *SpendCommitment) writeContents(w io.Writer, suffix []byte, assetVersion uint64) (err error) {
+	if assetVersion == 1 {
+		if _, err = sc.SourceID.WriteTo(w); err != nil {
+			return errors.New("writing source id")
+		}
+		if _, err = sc.AssetAmount.WriteTo(w); err != nil {
+			return errors.New("writing asset amount")
+		}
+		if _, err = extend.WriteVarint63(w, sc.SourcePosition); err != nil {
+			return errors.New("writing source position")
+		}
+		if _, err = extend.WriteVarint63(w, sc.VMVersion); err != nil {
+			return errors.New("writing vm version")
+		}
+		if _, err = extend.WriteVarstr31(w, sc.ControlProgram); err != nil {
+			return errors.New("writing control program")
+		}
+	}
+	if len(suffix) > 0 {
+		_, err = w.Write(suffix)
+	}
+	return err
+}
+
+func (sc *SpendCommitment) readFrom(r *extend.Reader, assetVersion uint64) (suffix []byte, err error) {
+	return extend.ReadExtensibleString(r, func(r *extend.Reader) error {
+		if assetVersion == 1 {
+			if _, err := sc.SourceID.ReadFrom(r); err != nil {
+				return errors.New("reading source id")
+			}
+			if err = sc.AssetAmount.ReadFrom(r); err != nil {
+				return errors.New("reading asset+amount")
+			}
+			if sc.SourcePosition, err = extend.ReadVarint63(r); err != nil {
+				return errors.New("reading source position")
+			}
+			if sc.VMVersion, err = extend.ReadVarint63(r); err != nil {
+				return errors.New("reading VM version")
+			}
+			if sc.VMVersion != 1 {
+				return fmt.Errorf("unrecognized VM version %d for asset version 1", sc.VMVersion)
+			}
+			if sc.ControlProgram, err = extend.ReadVarstr31(r); err != nil {
+				return errors.New("reading control program")
+			}
+		}
+		return nil
+	})
 }
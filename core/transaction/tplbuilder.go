@@ -23,9 +23,9 @@ func BuildUtxoTemplate(inputs []InputAndSigInst, outputs []*TxOutput) (*Template
 	tx.Outputs = append(tx.Outputs, outputs...)
 
 	// Add all the built inputs and their corresponding signing instructions.
-	for _, in := range inputs {
+	for i, in := range inputs {
 		// Empty signature arrays should be serialized as empty arrays, not null.
-		in.sigInst.Position = uint32(len(inputs))
+		in.sigInst.Position = uint32(i)
 		if in.sigInst.WitnessComponents == nil {
 			in.sigInst.WitnessComponents = []witnessComponent{}
 		}
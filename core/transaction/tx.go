@@ -114,7 +114,7 @@ func (tx *TxData) readFrom(r *extend.Reader) (err error) {
 	for ; n > 0; n-- {
 		ti := new(TxInput)
 		if err = ti.readFrom(r); err != nil {
-			return errors.New("reading input ")
+			return fmt.Errorf("reading input %d: %v", len(tx.Inputs), err)
 		}
 		tx.Inputs = append(tx.Inputs, ti)
 	}
@@ -127,7 +127,7 @@ func (tx *TxData) readFrom(r *extend.Reader) (err error) {
 	for ; n > 0; n-- {
 		to := new(TxOutput)
 		if err = to.readFrom(r); err != nil {
-			//return errors.Wrapf(err, "reading output %d", len(tx.Outputs))
+			return fmt.Errorf("reading output %d: %v", len(tx.Outputs), err)
 		}
 		tx.Outputs = append(tx.Outputs, to)
 	}
@@ -169,7 +169,7 @@ func (t *TxInput) SetArguments(args [][]byte) {
 func (tx *TxData) MarshalText() ([]byte, error) {
 	var buf bytes.Buffer
 	if _, err := tx.WriteTo(&buf); err != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	b := make([]byte, hex.EncodedLen(buf.Len()))
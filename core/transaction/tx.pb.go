@@ -385,6 +385,8 @@ func NewMux(sources []*ValueSource, program *Program) *Mux {
 }
 func (Mux) typ() string { return "mux1" }
 func (m *Mux) writeForHash(w io.Writer) {
+	mustWriteForHash(w, m.Sources)
+	mustWriteForHash(w, m.Program)
 }
 func (m *Mux) Reset()         { *m = Mux{} }
 func (m *Mux) String() string { return proto.CompactTextString(m) }
@@ -439,6 +441,8 @@ func NewOutput(source *ValueSource, controlProgram *Program, ordinal uint64) *Ou
 }
 func (Output) typ() string { return "output1" }
 func (o *Output) writeForHash(w io.Writer) {
+	mustWriteForHash(w, o.Source)
+	mustWriteForHash(w, o.ControlProgram)
 }
 func (m *Output) Reset()         { *m = Output{} }
 func (m *Output) String() string { return proto.CompactTextString(m) }
@@ -479,6 +483,7 @@ type Spend struct {
 
 func (Spend) typ() string { return "spend1" }
 func (s *Spend) writeForHash(w io.Writer) {
+	mustWriteForHash(w, s.SpentOutputId)
 }
 
 // SetDestination will link the spend to the output
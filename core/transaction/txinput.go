@@ -22,6 +22,22 @@ type (
 	}
 )
 
+// ControlProgram return the control program of the spend input
+func (t *TxInput) ControlProgram() []byte {
+	if si, ok := t.TypedInput.(*SpendInput); ok {
+		return si.ControlProgram
+	}
+	return nil
+}
+
+// Arguments get the args for the input
+func (t *TxInput) Arguments() [][]byte {
+	if si, ok := t.TypedInput.(*SpendInput); ok {
+		return si.Arguments
+	}
+	return nil
+}
+
 func (t *TxInput) writeTo(w io.Writer) error {
 	if _, err := extend.WriteVarint63(w, t.AssetVersion); err != nil {
 		return errors.New("write byte error")
@@ -43,6 +59,14 @@ func (t *TxInput) writeInputCommitment(w io.Writer) (err error) {
 			return err
 		}
 		return inp.SpendCommitment.writeExtensibleString(w, inp.SpendCommitmentSuffix, t.AssetVersion)
+
+	case *CoinbaseInput:
+		if _, err = w.Write([]byte{CoinbaseInputType}); err != nil {
+			return err
+		}
+		if _, err = extend.WriteVarstr31(w, inp.Arbitrary); err != nil {
+			return errors.New("writing coinbase arbitrary")
+		}
 	}
 
 	return nil
@@ -68,16 +92,23 @@ func (t *TxInput) readFrom(r *extend.Reader) (err error) {
 		}
 		var icType [1]byte
 		if _, err = io.ReadFull(r, icType[:]); err != nil {
-			return errors.New("aaa")
+			return errors.New("reading input commitment type")
 		}
 		switch icType[0] {
 
 		case SpendInputType:
 			si := new(SpendInput)
 			t.TypedInput = si
-			//if si.SpendCommitmentSuffix, err = si.SpendCommitment.readFrom(r, 1); err != nil {
-			//	return err
-			//}
+			if si.SpendCommitmentSuffix, err = si.SpendCommitment.readFrom(r, 1); err != nil {
+				return err
+			}
+
+		case CoinbaseInputType:
+			ci := new(CoinbaseInput)
+			t.TypedInput = ci
+			if ci.Arbitrary, err = extend.ReadVarstr31(r); err != nil {
+				return errors.New("reading coinbase arbitrary")
+			}
 
 		default:
 			return fmt.Errorf("unsupported input type %d", icType[0])
@@ -0,0 +1,264 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/srchain/srcd/common/address"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/params"
+)
+
+// Input types as they appear in the JSON form of a transaction.
+const (
+	SpendInputJSONType    = "spend"
+	CoinbaseInputJSONType = "coinbase"
+)
+
+// TxJSON is the decoded, human-readable form of a transaction. It is what a
+// Tx marshals to, and TxData recovers the transaction it was built from.
+type TxJSON struct {
+	ID        Hash          `json:"tx_id"`
+	Version   uint64        `json:"version"`
+	Size      uint64        `json:"size"`
+	TimeRange uint64        `json:"time_range"`
+	Fee       uint64        `json:"fee"`
+	Inputs    []*InputJSON  `json:"inputs"`
+	Outputs   []*OutputJSON `json:"outputs"`
+}
+
+// InputJSON describes a single transaction input.
+type InputJSON struct {
+	Type             string     `json:"type"`
+	InputID          Hash       `json:"input_id"`
+	SpentOutputID    *Hash      `json:"spent_output_id,omitempty"`
+	SourceID         *Hash      `json:"source_id,omitempty"`
+	SourcePosition   uint64     `json:"source_position"`
+	AssetID          *AssetID   `json:"asset_id,omitempty"`
+	Amount           uint64     `json:"amount"`
+	ControlProgram   HexBytes   `json:"control_program,omitempty"`
+	Address          string     `json:"address,omitempty"`
+	WitnessArguments []HexBytes `json:"witness_arguments,omitempty"`
+	Arbitrary        HexBytes   `json:"arbitrary,omitempty"`
+}
+
+// OutputJSON describes a single transaction output.
+type OutputJSON struct {
+	ID             Hash     `json:"id"`
+	Position       int      `json:"position"`
+	AssetID        AssetID  `json:"asset_id"`
+	Amount         uint64   `json:"amount"`
+	ControlProgram HexBytes `json:"control_program"`
+	Address        string   `json:"address,omitempty"`
+}
+
+// NewTxJSON builds the JSON form of tx.
+func NewTxJSON(tx *Tx) *TxJSON {
+	wrap := tx.TxWrap
+	if wrap.TxHeader == nil {
+		wrap = MapTxWrap(tx.TxData)
+	}
+
+	j := &TxJSON{
+		ID:        wrap.ID,
+		Version:   tx.TxData.Version,
+		Size:      tx.TxData.SerializedSize,
+		TimeRange: tx.TxData.TimeRange,
+		Fee:       CalculateTxFee(&tx.TxData),
+		Inputs:    make([]*InputJSON, 0, len(tx.Inputs)),
+		Outputs:   make([]*OutputJSON, 0, len(tx.Outputs)),
+	}
+
+	for i, in := range tx.Inputs {
+		inJSON := &InputJSON{InputID: wrap.InputIDs[i]}
+		switch inp := in.TypedInput.(type) {
+		case *SpendInput:
+			inJSON.Type = SpendInputJSONType
+			if spend, ok := wrap.Entries[wrap.InputIDs[i]].(*Spend); ok {
+				inJSON.SpentOutputID = spend.SpentOutputId
+			}
+			sourceID := inp.SourceID
+			inJSON.SourceID = &sourceID
+			inJSON.SourcePosition = inp.SourcePosition
+			inJSON.AssetID = inp.AssetId
+			inJSON.Amount = inp.Amount
+			inJSON.ControlProgram = inp.ControlProgram
+			inJSON.Address = ProgramAddress(inp.ControlProgram)
+			for _, arg := range inp.Arguments {
+				inJSON.WitnessArguments = append(inJSON.WitnessArguments, arg)
+			}
+
+		case *CoinbaseInput:
+			inJSON.Type = CoinbaseInputJSONType
+			inJSON.Arbitrary = inp.Arbitrary
+		}
+		j.Inputs = append(j.Inputs, inJSON)
+	}
+
+	for i, out := range tx.Outputs {
+		outJSON := &OutputJSON{
+			Position:       i,
+			Amount:         out.Amount,
+			ControlProgram: out.ControlProgram,
+			Address:        ProgramAddress(out.ControlProgram),
+		}
+		if i < len(wrap.ResultIds) {
+			outJSON.ID = *wrap.ResultIds[i]
+		}
+		if out.AssetId != nil {
+			outJSON.AssetID = *out.AssetId
+		}
+		j.Outputs = append(j.Outputs, outJSON)
+	}
+	return j
+}
+
+// TxData converts the JSON form back into the transaction it describes.
+// Derived fields (IDs, fee and addresses) are not needed; when an input or
+// output has no control program, it is recovered from the address. The size
+// is recomputed from the decoded transaction, as UnmarshalText does, unless
+// it is zero: a transaction built in memory has no size, and its ID and
+// signatures commit to that.
+func (j *TxJSON) TxData() (*TxData, error) {
+	data := &TxData{
+		Version:   j.Version,
+		TimeRange: j.TimeRange,
+	}
+
+	for i, in := range j.Inputs {
+		switch in.Type {
+		case SpendInputJSONType:
+			if in.SourceID == nil || in.AssetID == nil {
+				return nil, fmt.Errorf("input %d: missing source or asset id", i)
+			}
+			prog, err := programOrAddress(in.ControlProgram, in.Address)
+			if err != nil {
+				return nil, fmt.Errorf("input %d: %v", i, err)
+			}
+			var args [][]byte
+			for _, arg := range in.WitnessArguments {
+				args = append(args, arg)
+			}
+			data.Inputs = append(data.Inputs, NewSpendInput(args, *in.SourceID, *in.AssetID, in.Amount, in.SourcePosition, prog))
+
+		case CoinbaseInputJSONType:
+			data.Inputs = append(data.Inputs, NewCoinbaseInput(in.Arbitrary))
+
+		default:
+			return nil, fmt.Errorf("input %d: unknown type %q", i, in.Type)
+		}
+	}
+
+	for i, out := range j.Outputs {
+		prog, err := programOrAddress(out.ControlProgram, out.Address)
+		if err != nil {
+			return nil, fmt.Errorf("output %d: %v", i, err)
+		}
+		data.Outputs = append(data.Outputs, NewTxOutput(out.AssetID, out.Amount, prog))
+	}
+	if j.Size != 0 {
+		size, err := data.size()
+		if err != nil {
+			return nil, err
+		}
+		data.SerializedSize = size
+	}
+	return data, nil
+}
+
+// MarshalJSON fulfills the json.Marshaler interface.
+func (tx Tx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewTxJSON(&tx))
+}
+
+// UnmarshalJSON fulfills the json.Unmarshaler interface. It accepts the
+// decoded form produced by MarshalJSON as well as a hex-encoded raw
+// transaction string.
+func (tx *Tx) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '"' {
+		var raw string
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+		return tx.UnmarshalText([]byte(raw))
+	}
+
+	var j TxJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	data, err := j.TxData()
+	if err != nil {
+		return err
+	}
+
+	*tx = NewTx(*data)
+	if j.ID != (Hash{}) && j.ID != tx.ID {
+		return errors.New("tx_id does not match transaction contents")
+	}
+	return nil
+}
+
+// CalculateTxFee returns the amount of the native asset spent by tx's inputs
+// and not paid to its outputs. Coinbase transactions pay no fee.
+func CalculateTxFee(tx *TxData) uint64 {
+	var totalIn, totalOut uint64
+	for _, in := range tx.Inputs {
+		switch inp := in.TypedInput.(type) {
+		case *CoinbaseInput:
+			return 0
+		case *SpendInput:
+			if inp.AssetId != nil && *inp.AssetId == *SRCAssetID {
+				totalIn += inp.Amount
+			}
+		}
+	}
+	for _, out := range tx.Outputs {
+		if out.AssetId != nil && *out.AssetId == *SRCAssetID {
+			totalOut += out.Amount
+		}
+	}
+	if totalOut > totalIn {
+		return 0
+	}
+	return totalIn - totalOut
+}
+
+// ProgramAddress returns the address a control program pays to on the active
+// network, or the empty string if the program has no address form.
+func ProgramAddress(prog []byte) string {
+	var (
+		addr address.Address
+		err  error
+	)
+	switch {
+	case vm.IsP2WPKHProgram(prog):
+		addr, err = address.NewAddressWitnessPubKeyHash(prog[2:], params.ActiveNetParams)
+	case vm.IsP2WSHProgram(prog):
+		addr, err = address.NewAddressWitnessScriptHash(prog[2:], params.ActiveNetParams)
+	default:
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	return addr.EncodeAddress()
+}
+
+func programOrAddress(prog []byte, addr string) ([]byte, error) {
+	if len(prog) > 0 || addr == "" {
+		return prog, nil
+	}
+	decoded, err := address.DecodeAddress(addr, *params.ActiveNetParams)
+	if err != nil {
+		return nil, err
+	}
+	// P2WSHProgram builds the witness program of either kind of address.
+	witness, ok := decoded.(interface{ WitnessProgram() []byte })
+	if !ok {
+		return nil, fmt.Errorf("unsupported address %s", addr)
+	}
+	return vm.P2WSHProgram(witness.WitnessProgram())
+}
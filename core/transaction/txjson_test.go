@@ -0,0 +1,126 @@
+package transaction
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/srchain/srcd/params"
+)
+
+func TestTxJSONRoundTrip(t *testing.T) {
+	prog := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	tx := NewTx(TxData{
+		Version:   1,
+		TimeRange: 100,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{{1, 2}, {3}}, Hash{V0: 1}, *SRCAssetID, 1000, 2, prog),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(*SRCAssetID, 600, prog),
+			NewTxOutput(*SRCAssetID, 390, []byte{0x51}),
+		},
+	})
+
+	b, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var j TxJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != tx.ID || j.Fee != 10 || len(j.Inputs) != 1 || len(j.Outputs) != 2 {
+		t.Fatalf("unexpected json form %s", b)
+	}
+	if j.Inputs[0].Address == "" || j.Outputs[1].Address != "" {
+		t.Errorf("unexpected addresses in %s", b)
+	}
+
+	var got Tx
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tx.ID || !reflect.DeepEqual(got.TxData, tx.TxData) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got.TxData, tx.TxData)
+	}
+
+	// The control program can be recovered from the address alone.
+	j.Inputs[0].ControlProgram = nil
+	data, err := j.TxData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data.Inputs[0], tx.Inputs[0]) {
+		t.Errorf("input from address: got %+v want %+v", data.Inputs[0], tx.Inputs[0])
+	}
+
+	j.ID = Hash{V0: 42}
+	b, _ = json.Marshal(j)
+	if err := json.Unmarshal(b, &got); err == nil {
+		t.Error("expected error for mismatched tx_id")
+	}
+}
+
+func TestTxJSONSize(t *testing.T) {
+	data := TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{{1, 2}}, Hash{V0: 1}, *SRCAssetID, 1000, 0, []byte{0x51}),
+		},
+		Outputs: []*TxOutput{NewTxOutput(*SRCAssetID, 900, []byte{0x51})},
+	}
+	raw, err := data.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx Tx
+	if err := tx.UnmarshalText(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewTxJSON(&tx)
+	if j.Size != tx.SerializedSize || j.Size == 0 {
+		t.Fatalf("json size %d, want %d", j.Size, tx.SerializedSize)
+	}
+	j.Size = 5
+	decoded, err := j.TxData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SerializedSize != tx.SerializedSize {
+		t.Errorf("size %d taken from the json, want %d", decoded.SerializedSize, tx.SerializedSize)
+	}
+	b, _ := json.Marshal(j)
+	if err := json.Unmarshal(b, new(Tx)); err != nil {
+		t.Errorf("tx with a wrong json size rejected: %v", err)
+	}
+}
+
+func TestProgramAddressNetwork(t *testing.T) {
+	defer func(active *params.NetParams) { params.ActiveNetParams = active }(params.ActiveNetParams)
+
+	prog := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	params.ActiveNetParams = &params.MainNetParams
+	mainAddr := ProgramAddress(prog)
+	params.ActiveNetParams = &params.TestNetParams
+	testAddr := ProgramAddress(prog)
+	if !strings.HasPrefix(mainAddr, "sr1") || !strings.HasPrefix(testAddr, "tsr1") {
+		t.Fatalf("addresses %s and %s not encoded for their networks", mainAddr, testAddr)
+	}
+
+	// A node takes addresses of its own network, and a testnet node those
+	// encoded with the prefix the test network used before.
+	if got, err := programOrAddress(nil, testAddr); err != nil || !reflect.DeepEqual(got, prog) {
+		t.Errorf("testnet address on testnet: %x, %v", got, err)
+	}
+	if got, err := programOrAddress(nil, mainAddr); err != nil || !reflect.DeepEqual(got, prog) {
+		t.Errorf("legacy testnet address on testnet: %x, %v", got, err)
+	}
+	params.ActiveNetParams = &params.MainNetParams
+	if _, err := programOrAddress(nil, testAddr); err == nil {
+		t.Error("testnet address accepted on mainnet")
+	}
+}
@@ -15,6 +15,21 @@ type TxOutput struct {
 	CommitmentSuffix []byte
 }
 
+// NewTxOutput create a new output struct
+func NewTxOutput(assetID AssetID, amount uint64, controlProgram []byte) *TxOutput {
+	return &TxOutput{
+		AssetVersion: 1,
+		OutputCommitment: OutputCommitment{
+			AssetAmount: AssetAmount{
+				AssetId: &assetID,
+				Amount:  amount,
+			},
+			VMVersion:      1,
+			ControlProgram: controlProgram,
+		},
+	}
+}
+
 type OutputCommitment struct {
 	AssetAmount
 	VMVersion      uint64
@@ -34,8 +49,9 @@ func (oc *OutputCommitment) readFrom(r *extend.Reader, assetVersion uint64) (suf
 			if oc.VMVersion != 1 {
 				return fmt.Errorf("unrecognized VM version %d for asset version 1", oc.VMVersion)
 			}
-			oc.ControlProgram, err = extend.ReadVarstr31(r)
-			return errors.New("reading control program")
+			if oc.ControlProgram, err = extend.ReadVarstr31(r); err != nil {
+				return errors.New("reading control program")
+			}
 		}
 		return nil
 	})
@@ -46,9 +62,9 @@ func (to *TxOutput) writeTo(w io.Writer) error {
 		return errors.New("writing asset version")
 	}
 
-	//if err := to.writeCommitment(w); err != nil {
-	//	return  errors.New("writing output commitment")
-	//}
+	if err := to.writeCommitment(w); err != nil {
+		return errors.New("writing output commitment")
+	}
 
 	if _, err := extend.WriteVarstr31(w, nil); err != nil {
 		return errors.New("writing witness")
@@ -82,7 +98,7 @@ func (oc *OutputCommitment) writeContents(w io.Writer, suffix []byte, assetVersi
 	if len(suffix) > 0 {
 		_, err = w.Write(suffix)
 	}
-	return errors.New("writing suffix")
+	return err
 }
 
 func (to *TxOutput) readFrom(r *extend.Reader) (err error) {
@@ -95,6 +111,8 @@ func (to *TxOutput) readFrom(r *extend.Reader) (err error) {
 	}
 
 	// read and ignore the (empty) output witness
-	_, err = extend.ReadVarstr31(r)
-	return errors.New("reading output witness")
+	if _, err = extend.ReadVarstr31(r); err != nil {
+		return errors.New("reading output witness")
+	}
+	return nil
 }
@@ -24,6 +24,135 @@ func TxSign(tpl *Template,xprv chainkd.XPrv,xpub chainkd.XPub) error{
 	return materializeWitnesses(tpl)
 }
 
+// Sign adds xprv's signature to every signature component of tpl that
+// expects one from its public key, then rebuilds the input witnesses.
+func Sign(tpl *Template, xprv chainkd.XPrv) error {
+	xpub := xprv.XPub()
+	return signKeys(tpl, func(key keyID) (chainkd.XPrv, bool) {
+		return xprv, key.XPub == xpub
+	})
+}
+
+// SignDerived is like Sign for a root key held by an offline signer: it also
+// signs for the keys derived from root along the derivation paths recorded
+// in tpl.
+func SignDerived(tpl *Template, root chainkd.XPrv) error {
+	return signKeys(tpl, func(key keyID) (chainkd.XPrv, bool) {
+		xprv := root.Derive(key.path())
+		return xprv, xprv.XPub() == key.XPub
+	})
+}
+
+// NeedsKey reports whether tpl still expects a signature from root, or from
+// a key derived from it along a recorded derivation path.
+func (tpl *Template) NeedsKey(root chainkd.XPub) bool {
+	for _, sigInst := range tpl.SigningInstructions {
+		for _, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(*RawTxSigWitness)
+			if !ok {
+				continue
+			}
+			for i, key := range sw.Keys {
+				if i < len(sw.Sigs) && len(sw.Sigs[i]) > 0 {
+					continue
+				}
+				if root.Derive(key.path()) == key.XPub {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// signKeys signs, in every signature component of tpl, for each key that
+// keyFor returns a private key for, then rebuilds the input witnesses.
+func signKeys(tpl *Template, keyFor func(key keyID) (chainkd.XPrv, bool)) error {
+	for _, sigInst := range tpl.SigningInstructions {
+		for _, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(*RawTxSigWitness)
+			if !ok {
+				continue
+			}
+			for len(sw.Sigs) < len(sw.Keys) {
+				sw.Sigs = append(sw.Sigs, nil)
+			}
+			for i, key := range sw.Keys {
+				if len(sw.Sigs[i]) > 0 {
+					continue
+				}
+				xprv, ok := keyFor(key)
+				if !ok {
+					continue
+				}
+				sig, err := signInput(tpl, sigInst.Position, sw.SigHashType, xprv)
+				if err != nil {
+					return err
+				}
+				sw.Sigs[i] = sig
+			}
+		}
+	}
+	return materializeWitnesses(tpl)
+}
+
+// InputSignatures is how far the signing of one input of a template got.
+type InputSignatures struct {
+	Position uint32 `json:"position"`
+	Quorum   int    `json:"quorum"`
+	Signed   int    `json:"signed"`
+
+	// Pending are the keys that may still sign.
+	Pending []chainkd.XPub `json:"pending"`
+}
+
+// Remaining returns how many more signatures the input needs.
+func (s *InputSignatures) Remaining() int {
+	if s.Signed >= s.Quorum {
+		return 0
+	}
+	return s.Quorum - s.Signed
+}
+
+// SignatureStatus reports the signatures collected for each input of tpl.
+func (tpl *Template) SignatureStatus() []*InputSignatures {
+	var status []*InputSignatures
+	for _, sigInst := range tpl.SigningInstructions {
+		s := &InputSignatures{Position: sigInst.Position, Pending: []chainkd.XPub{}}
+		for _, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(*RawTxSigWitness)
+			if !ok {
+				continue
+			}
+			s.Quorum += sw.Quorum
+			for i, key := range sw.Keys {
+				if i < len(sw.Sigs) && len(sw.Sigs[i]) > 0 {
+					s.Signed++
+				} else {
+					s.Pending = append(s.Pending, key.XPub)
+				}
+			}
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// signInput signs input n of tpl, committing to the parts of the transaction
+// selected by t. A zero t commits to the whole transaction.
+func signInput(tpl *Template, n uint32, t SigHashType, xprv chainkd.XPrv) ([]byte, error) {
+	if t == 0 {
+		h := tpl.Hash(n).Byte32()
+		return xprv.Sign(h[:]), nil
+	}
+	h, err := tpl.Transaction.SigHashWithType(n, t)
+	if err != nil {
+		return nil, err
+	}
+	b := h.Byte32()
+	return append(xprv.Sign(b[:]), byte(t)), nil
+}
+
 func materializeWitnesses(txTemplate *Template) error {
 	msg := txTemplate.Transaction
 	for i, sigInst := range txTemplate.SigningInstructions {
@@ -0,0 +1,72 @@
+package transaction
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+)
+
+func TestSignDerived(t *testing.T) {
+	root, rootPub, err := chainkd.NewXKeys(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, _ := chainkd.NewXKeys(rand.Reader)
+	path := chainkd.IndexPath(0, 7).Selectors()
+	child := rootPub.Derive(path)
+	prog, err := vm.P2WSHProgram(ripemd160.Ripemd160(child.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigInst := &SigningInstruction{}
+	sigInst.WitnessComponents = append(sigInst.WitnessComponents,
+		NewDerivedRawTxSigWitness(1, []chainkd.XPub{child}, path), DataWitness(child.PublicKey()))
+	built, _, err := BuildUtxoTemplate(
+		[]InputAndSigInst{NewInputAndSigInst(NewSpendInput(nil, Hash{V0: 1}, *SRCAssetID, 100, 0, prog), sigInst)},
+		[]*TxOutput{NewTxOutput(*SRCAssetID, 100, prog)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The template travels to the signer as a file.
+	data, err := json.Marshal(built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := new(Template)
+	if err := json.Unmarshal(data, tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	status := tpl.SignatureStatus()
+	if len(status) != 1 || status[0].Remaining() != 1 || len(status[0].Pending) != 1 || status[0].Pending[0] != child {
+		t.Fatalf("unsigned status: %+v", status)
+	}
+	if !tpl.NeedsKey(rootPub) || tpl.NeedsKey(other) {
+		t.Errorf("NeedsKey(root) = %v, NeedsKey(other) = %v", tpl.NeedsKey(rootPub), tpl.NeedsKey(other))
+	}
+
+	if err := Sign(tpl, root); err != nil {
+		t.Fatal(err)
+	}
+	if status := tpl.SignatureStatus(); status[0].Signed != 0 {
+		t.Error("Sign signed for a derived key")
+	}
+	if err := SignDerived(tpl, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTx(&tpl.Transaction.TxWrap, 1); err != nil {
+		t.Fatalf("transaction signed with the root key does not verify: %v", err)
+	}
+	if status := tpl.SignatureStatus(); status[0].Remaining() != 0 || len(status[0].Pending) != 0 {
+		t.Errorf("signed status: %+v", status[0])
+	}
+	if tpl.NeedsKey(rootPub) {
+		t.Error("signed template still needs the root key")
+	}
+}
@@ -1,16 +1,17 @@
 package transaction
 
 import (
-	 "github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
 )
 
 type UTXO struct {
-	SourceID       Hash
-	AssetID        AssetID
-	Amount         uint64
-	SourcePos      uint64	//utxo sourece index
-	ControlProgram []byte  //receipt program
-	Address        string  //receipt address
+	OutputID       Hash     `json:"output_id"`
+	SourceID       Hash     `json:"source_id"`
+	AssetID        AssetID  `json:"asset_id"`
+	Amount         uint64   `json:"amount"`
+	SourcePos      uint64   `json:"source_pos"`        //utxo sourece index
+	ControlProgram HexBytes `json:"control_program"`   //receipt program
+	Address        string   `json:"address,omitempty"` //receipt address
 }
 
 // UtxoToInputs convert an utxo to the txinput
@@ -28,14 +29,15 @@ func UtxoInputs(xpubs []chainkd.XPub, u *UTXO) (InputAndSigInst, error) {
 	//}
 
 	derivedPK := xpubs[0].PublicKey()
+	sigInst.WitnessComponents = append(sigInst.WitnessComponents, NewRawTxSigWitness(1, xpubs[:1]))
 	sigInst.WitnessComponents = append(sigInst.WitnessComponents, DataWitness([]byte(derivedPK)))
 
-	return InputAndSigInst{txInput,sigInst},nil
+	return InputAndSigInst{txInput, sigInst}, nil
 	//return txInput, sigInst, nil
 }
 
-//convert an utxo to th txoutput
-func UtxoOutputs(assetID AssetID,amount uint64,controlProgram []byte)TxOutput  {
+// convert an utxo to th txoutput
+func UtxoOutputs(assetID AssetID, amount uint64, controlProgram []byte) TxOutput {
 
 	return TxOutput{
 		AssetVersion: 1,
@@ -49,4 +51,3 @@ func UtxoOutputs(assetID AssetID,amount uint64,controlProgram []byte)TxOutput  {
 		},
 	}
 }
-
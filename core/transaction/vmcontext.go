@@ -0,0 +1,74 @@
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/errors"
+)
+
+// NewTxVMContext generates the vm.Context for the spend at input n of tx, to
+// be evaluated at the given block height.
+func NewTxVMContext(tx *TxWrap, n uint32, blockHeight uint64) (*vm.Context, error) {
+	if int(n) >= len(tx.InputIDs) {
+		return nil, errors.New("input index out of range")
+	}
+	spend, ok := tx.Entries[tx.InputIDs[n]].(*Spend)
+	if !ok {
+		return nil, errors.New("input is not a spend")
+	}
+	prevout, ok := tx.Entries[*spend.SpentOutputId].(*Output)
+	if !ok {
+		return nil, errors.New("spent output is missing")
+	}
+
+	var (
+		entryID       = tx.InputIDs[n].Bytes()
+		spentOutputID = spend.SpentOutputId.Bytes()
+		assetID       = prevout.Source.Value.AssetId.Bytes()
+		amount        = prevout.Source.Value.Amount
+	)
+	return &vm.Context{
+		VMVersion: prevout.ControlProgram.VmVersion,
+		Code:      prevout.ControlProgram.Code,
+		Arguments: spend.WitnessArguments,
+
+		EntryID:     entryID,
+		TxVersion:   &tx.Version,
+		BlockHeight: &blockHeight,
+
+		SpentOutputID: &spentOutputID,
+		AssetID:       &assetID,
+		Amount:        &amount,
+
+		TxSigHash: func() []byte {
+			h := tx.SigHash(n).Byte32()
+			return h[:]
+		},
+		SigHash: func(sigHashType byte) ([]byte, error) {
+			h, err := tx.SigHashWithType(n, SigHashType(sigHashType))
+			if err != nil {
+				return nil, err
+			}
+			return h.Bytes(), nil
+		},
+	}, nil
+}
+
+// VerifyTx runs the control program of every output spent by tx against the
+// witness of the input spending it.
+func VerifyTx(tx *TxWrap, blockHeight uint64) error {
+	for n, id := range tx.InputIDs {
+		if _, ok := tx.Entries[id].(*Spend); !ok {
+			continue
+		}
+		ctx, err := NewTxVMContext(tx, uint32(n), blockHeight)
+		if err != nil {
+			return err
+		}
+		if err := vm.Verify(ctx, vm.DefaultRunLimit); err != nil {
+			return fmt.Errorf("validating input %d: %v", n, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package transaction
+
+import (
+	"io/ioutil"
+	"math"
+
+	"github.com/srchain/srcd/core/transaction/extend"
+)
+
+const (
+	// WitnessScaleFactor is how many times more a non-witness byte counts
+	// towards a transaction's weight than a witness byte does.
+	WitnessScaleFactor = 4
+
+	// MaxBlockWeight is the largest total weight of the transactions in a
+	// single block.
+	MaxBlockWeight = 4000000
+)
+
+// Size returns the length of tx's serialized form, or math.MaxUint64 if tx
+// does not serialize.
+func (tx *TxData) Size() uint64 {
+	size, err := tx.size()
+	if err != nil {
+		return math.MaxUint64
+	}
+	return size
+}
+
+func (tx *TxData) size() (uint64, error) {
+	ew := extend.NewWriter(ioutil.Discard)
+	if err := tx.writeTo(ew, serRequired); err != nil {
+		return 0, err
+	}
+	return uint64(ew.Written()), nil
+}
+
+// WitnessSize returns how many bytes of tx's serialized form are taken by the
+// input witnesses.
+func (tx *TxData) WitnessSize() uint64 {
+	var n uint64
+	for _, in := range tx.Inputs {
+		ew := extend.NewWriter(ioutil.Discard)
+		extend.WriteExtensibleString(ew, in.WitnessSuffix, in.writeInputWitness)
+		n += uint64(ew.Written())
+	}
+	return n
+}
+
+// Weight returns tx's weight, in which witness bytes are discounted by
+// WitnessScaleFactor relative to the rest of the transaction. A transaction
+// that does not serialize weighs math.MaxUint64, more than any limit allows.
+func (tx *TxData) Weight() uint64 {
+	size, err := tx.size()
+	if err != nil {
+		return math.MaxUint64
+	}
+	witness := tx.WitnessSize()
+	return (size-witness)*WitnessScaleFactor + witness
+}
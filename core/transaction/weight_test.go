@@ -0,0 +1,62 @@
+package transaction
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTxSerializationAndWeight(t *testing.T) {
+	prog := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	data := TxData{
+		Version:   1,
+		TimeRange: 7,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{make([]byte, 64), make([]byte, 32)}, Hash{V0: 1}, *SRCAssetID, 1000, 2, prog),
+			NewCoinbaseInput([]byte("arbitrary")),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(*SRCAssetID, 990, prog),
+		},
+	}
+
+	text, err := data.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got TxData
+	if err := got.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got.SerializedSize != data.Size() {
+		t.Errorf("serialized size %d, Size() %d", got.SerializedSize, data.Size())
+	}
+	if gotText, _ := got.MarshalText(); !bytes.Equal(gotText, text) {
+		t.Errorf("round trip mismatch:\ngot  %s\nwant %s", gotText, text)
+	}
+	if !reflect.DeepEqual(got.Inputs[0].Arguments(), data.Inputs[0].Arguments()) || !reflect.DeepEqual(got.Outputs[0].OutputCommitment, data.Outputs[0].OutputCommitment) {
+		t.Errorf("decoded contents differ:\ngot  %+v\nwant %+v", got, data)
+	}
+
+	// Each witness byte counts once, every other byte WitnessScaleFactor times.
+	size, witness := data.Size(), data.WitnessSize()
+	if witness < 96 || witness >= size {
+		t.Fatalf("implausible witness size %d of %d", witness, size)
+	}
+	if want := (size-witness)*WitnessScaleFactor + witness; data.Weight() != want {
+		t.Errorf("weight %d want %d", data.Weight(), want)
+	}
+
+	data.Inputs[0].SetArguments(nil)
+	if data.WitnessSize() >= witness || data.Weight() != (size-witness)*WitnessScaleFactor+data.WitnessSize() {
+		t.Error("dropping witness arguments should only reduce the discounted part of the weight")
+	}
+}
+
+func TestWeightUnserializable(t *testing.T) {
+	data := TxData{Version: 1, TimeRange: math.MaxUint64}
+	if data.Size() != math.MaxUint64 || data.Weight() != math.MaxUint64 {
+		t.Errorf("transaction failing to serialize: size %d, weight %d, want %d", data.Size(), data.Weight(), uint64(math.MaxUint64))
+	}
+}
@@ -0,0 +1,371 @@
+package txpool
+
+import (
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core"
+	"github.com/srchain/srcd/core/rawdb"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/event"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/log"
+)
+
+const (
+	MsgNewTx = iota
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// chainReorgChanSize is the size of channel listening to ChainReorgEvent.
+	chainReorgChanSize = 10
+)
+
+var (
+	// ErrKnownTx is returned for a transaction already in the pool.
+	ErrKnownTx = errors.New("transaction already in the pool")
+
+	// ErrDoubleSpend is returned for a transaction spending an output a
+	// pool transaction already spends.
+	ErrDoubleSpend = errors.New("output already spent by a pool transaction")
+
+	// ErrMissingInput is returned for a transaction spending an output that
+	// neither the chain nor the pool holds unspent.
+	ErrMissingInput = errors.New("output missing or already spent")
+)
+
+// Chain is the blockchain whose head a pool follows.
+type Chain interface {
+	CurrentBlock() *types.Block
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
+}
+
+type TxPool struct {
+	Utxo   map[transaction.Hash]transaction.Tx
+	Tx     transaction.Tx
+	Weight uint64
+	Height uint64 // height of the chain head, transactions are verified for the next block
+	Fee    uint64
+	Mtx    sync.RWMutex
+	Pool   map[transaction.Hash]*TxPoolMsg
+
+	// spent maps the outputs spent by pool transactions to the ID of the
+	// transaction spending them.
+	spent map[transaction.Hash]transaction.Hash
+
+	// db is the chain database the inputs of transactions are looked up
+	// in. A pool without one does not check that they exist.
+	db rawdb.DatabaseReader
+
+	// Policy, if set, rejects transactions that are valid but not
+	// standard enough to be accepted into the pool and relayed.
+	Policy func(tx *transaction.Tx) error
+
+	msgFeed event.Feed
+
+	chain    Chain
+	headSub  event.Subscription
+	reorgSub event.Subscription
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+//TODO: 有请张先生现身说法
+func (tp *TxPool) AddRemotes([]*types.Transaction) []error {
+	panic("implement me")
+}
+
+func (tp *TxPool) Pending() (map[common.Address]types.Transactions, error) {
+	panic("implement me")
+}
+
+func (tp *TxPool) SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription {
+	panic("implement me")
+}
+
+type TxPoolMsg struct {
+	Tx      transaction.Tx
+	Added   time.Time
+	Weight  uint64
+	Fee     uint64
+	MsgType int
+}
+
+// NewTxPool creates a pool accepting transactions that spend outputs unspent
+// in the chain database db, or created by other pool transactions, and that
+// verify at the height of the block after the head of chain. As the head
+// moves, transactions that no longer apply on top of it, among them those the
+// chain now holds, leave the pool. Without a chain the pool verifies
+// transactions for block 1.
+func NewTxPool(chain Chain, db rawdb.DatabaseReader) *TxPool {
+	tp := &TxPool{
+		Utxo:   make(map[transaction.Hash]transaction.Tx),
+		Tx:     transaction.Tx{},
+		Weight: uint64(0),
+		Height: uint64(0),
+		Fee:    uint64(0),
+		Pool:   make(map[transaction.Hash]*TxPoolMsg),
+		spent:  make(map[transaction.Hash]transaction.Hash),
+		db:     db,
+		chain:  chain,
+		quit:   make(chan struct{}),
+	}
+	if chain != nil {
+		if head := chain.CurrentBlock(); head != nil {
+			tp.Height = head.NumberU64()
+		}
+		headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+		reorgCh := make(chan core.ChainReorgEvent, chainReorgChanSize)
+		tp.headSub = chain.SubscribeChainHeadEvent(headCh)
+		tp.reorgSub = chain.SubscribeChainReorgEvent(reorgCh)
+		tp.wg.Add(1)
+		go tp.loop(headCh, reorgCh)
+	}
+	return tp
+}
+
+// loop follows the head of the chain until Stop is called.
+func (tp *TxPool) loop(headCh <-chan core.ChainHeadEvent, reorgCh <-chan core.ChainReorgEvent) {
+	defer tp.wg.Done()
+	defer tp.headSub.Unsubscribe()
+	defer tp.reorgSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-headCh:
+			if ev.Block != nil {
+				tp.reset(ev.Block)
+			}
+		case <-reorgCh:
+			if head := tp.chain.CurrentBlock(); head != nil {
+				tp.reset(head)
+			}
+		case <-tp.headSub.Err():
+			return
+		case <-tp.reorgSub.Err():
+			return
+		case <-tp.quit:
+			return
+		}
+	}
+}
+
+// reset moves the pool on top of head: every pool transaction is checked
+// again, parents first, and those spending outputs that are no longer unspent
+// or failing verification for the block after head are dropped, together
+// with the transactions spending their outputs.
+func (tp *TxPool) reset(head *types.Block) {
+	tp.Mtx.Lock()
+	defer tp.Mtx.Unlock()
+
+	msgs := tp.orderTransactions(math.MaxUint64)
+	tp.Height = head.NumberU64()
+	tp.Utxo = make(map[transaction.Hash]transaction.Tx)
+	tp.Pool = make(map[transaction.Hash]*TxPoolMsg)
+	tp.spent = make(map[transaction.Hash]transaction.Hash)
+	tp.Weight, tp.Fee = 0, 0
+
+	for _, msg := range msgs {
+		if err := tp.checkTransaction(&msg.Tx); err != nil {
+			log.Debug("Dropped pool transaction", "tx_id", msg.Tx.ID.String(), "err", err)
+			continue
+		}
+		tp.insert(msg)
+	}
+}
+
+// Stop stops following the chain.
+func (tp *TxPool) Stop() {
+	close(tp.quit)
+	tp.wg.Wait()
+}
+
+// SubscribeNewTxs registers a subscription of the transactions entering the
+// pool.
+func (tp *TxPool) SubscribeNewTxs(ch chan<- *TxPoolMsg) event.Subscription {
+	return tp.msgFeed.Subscribe(ch)
+}
+
+func (tp *TxPool) AddTransaction(tx transaction.Tx, fee uint64) error {
+	msg, err := tp.addTransaction(tx, fee)
+	if err != nil {
+		return err
+	}
+	tp.msgFeed.Send(msg)
+	return nil
+}
+
+// CheckTransaction reports whether tx would be accepted into the pool,
+// without adding it.
+func (tp *TxPool) CheckTransaction(tx *transaction.Tx) error {
+	tp.Mtx.RLock()
+	defer tp.Mtx.RUnlock()
+
+	return tp.checkTransaction(tx)
+}
+
+func (tp *TxPool) checkTransaction(tx *transaction.Tx) error {
+	if _, ok := tp.Pool[tx.ID]; ok {
+		return ErrKnownTx
+	}
+	for _, sp := range spentOutputs(tx) {
+		if _, ok := tp.spent[sp.id]; ok {
+			return ErrDoubleSpend
+		}
+		if _, ok := tp.Utxo[sp.id]; ok {
+			continue
+		}
+		if tp.db != nil && !rawdb.HasUnspentOutput(tp.db, sp.program, sp.id) {
+			return ErrMissingInput
+		}
+	}
+	if tp.Policy != nil {
+		if err := tp.Policy(tx); err != nil {
+			return err
+		}
+	}
+	return transaction.VerifyTx(&tx.TxWrap, tp.Height+1)
+}
+
+// spentOutput is an output spent by a transaction.
+type spentOutput struct {
+	id      transaction.Hash
+	program []byte
+}
+
+// spentOutputs returns the outputs the spend inputs of tx consume.
+func spentOutputs(tx *transaction.Tx) []spentOutput {
+	var outputs []spentOutput
+	for i, in := range tx.Inputs {
+		sp, ok := in.TypedInput.(*transaction.SpendInput)
+		if !ok {
+			continue
+		}
+		spend, ok := tx.Entries[tx.InputIDs[i]].(*transaction.Spend)
+		if !ok || spend.SpentOutputId == nil {
+			continue
+		}
+		outputs = append(outputs, spentOutput{*spend.SpentOutputId, sp.ControlProgram})
+	}
+	return outputs
+}
+
+func (tp *TxPool) addTransaction(tx transaction.Tx, fee uint64) (*TxPoolMsg, error) {
+	tp.Mtx.Lock()
+	defer tp.Mtx.Unlock()
+
+	if err := tp.checkTransaction(&tx); err != nil {
+		return nil, err
+	}
+
+	msg := &TxPoolMsg{tx, time.Now(), tx.TxData.Weight(), fee, MsgNewTx}
+	tp.insert(msg)
+	log.Info("add txpool ", "tx_id", tx.ID.String())
+	return msg, nil
+}
+
+// insert adds a checked transaction to the pool. The caller holds Mtx.
+func (tp *TxPool) insert(msg *TxPoolMsg) {
+	tx := msg.Tx
+	for _, id := range tx.ResultIds {
+		tp.Utxo[*id] = tx
+	}
+	for _, id := range tx.SpentOutputIDs {
+		tp.spent[id] = tx.ID
+	}
+	tp.Pool[tx.ID] = msg
+	tp.Weight += msg.Weight
+	tp.Fee += msg.Fee
+}
+
+func (tp *TxPool) GetTransaction(hash *transaction.Hash) (*TxPoolMsg, error) {
+	tp.Mtx.RLock()
+	defer tp.Mtx.RUnlock()
+
+	msg := tp.Pool[*hash]
+	if msg != nil {
+		return msg, nil
+	}
+	return &TxPoolMsg{}, errors.New("txpool has no this tx")
+}
+
+// OutputSpender returns the ID of the pool transaction spending the output
+// id, if there is one.
+func (tp *TxPool) OutputSpender(id transaction.Hash) (transaction.Hash, bool) {
+	tp.Mtx.RLock()
+	defer tp.Mtx.RUnlock()
+
+	spender, ok := tp.spent[id]
+	return spender, ok
+}
+
+// SelectTransactions returns pool transactions for a new block, whose total
+// weight stays within maxWeight. Transactions paying the highest fee per unit
+// of weight come first, but never before the pool transactions whose outputs
+// they spend.
+func (tp *TxPool) SelectTransactions(maxWeight uint64) []*TxPoolMsg {
+	tp.Mtx.RLock()
+	defer tp.Mtx.RUnlock()
+
+	return tp.orderTransactions(maxWeight)
+}
+
+// orderTransactions implements SelectTransactions. The caller holds Mtx.
+func (tp *TxPool) orderTransactions(maxWeight uint64) []*TxPoolMsg {
+	msgs := make([]*TxPoolMsg, 0, len(tp.Pool))
+	for _, msg := range tp.Pool {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		// Compare fee rates without dividing: a.Fee/a.Weight > b.Fee/b.Weight.
+		return msgs[i].Fee*msgs[j].Weight > msgs[j].Fee*msgs[i].Weight
+	})
+
+	var (
+		selected []*TxPoolMsg
+		weight   uint64
+		// done holds the transactions selected, true, or left out, false.
+		done = make(map[transaction.Hash]bool, len(msgs))
+	)
+	// Each pass takes the transactions whose pool parents are all selected,
+	// so that a child waits for the pass after its parent's.
+	for progress := true; progress; {
+		progress = false
+	next:
+		for _, msg := range msgs {
+			if _, ok := done[msg.Tx.ID]; ok {
+				continue
+			}
+			for _, id := range msg.Tx.SpentOutputIDs {
+				parent, ok := tp.Utxo[id]
+				if !ok {
+					continue
+				}
+				if in, ok := done[parent.ID]; !ok {
+					continue next
+				} else if !in {
+					done[msg.Tx.ID] = false
+					progress = true
+					continue next
+				}
+			}
+			if msg.Weight > maxWeight-weight {
+				done[msg.Tx.ID] = false
+			} else {
+				done[msg.Tx.ID] = true
+				selected = append(selected, msg)
+				weight += msg.Weight
+			}
+			progress = true
+		}
+	}
+	return selected
+}
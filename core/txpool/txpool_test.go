@@ -0,0 +1,225 @@
+package txpool
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srchain/srcd/core"
+	"github.com/srchain/srcd/core/rawdb"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/core/vm"
+	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/event"
+)
+
+var trueProgram = []byte{0x51}
+
+// spendTx returns a transaction spending output pos of parent to outputs of
+// the given amounts.
+func spendTx(parent *transaction.Tx, pos int, amounts ...uint64) transaction.Tx {
+	out := parent.Outputs[pos]
+	source, _ := parent.Output(*parent.ResultIds[pos])
+	data := transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, *source.Source.Ref, *out.AssetId, out.Amount, source.Source.Position, out.ControlProgram),
+		},
+	}
+	for _, amount := range amounts {
+		data.Outputs = append(data.Outputs, transaction.NewTxOutput(*transaction.SRCAssetID, amount, trueProgram))
+	}
+	return transaction.NewTx(data)
+}
+
+// testChain is a chain whose head is moved by hand.
+type testChain struct {
+	head      *types.Block
+	headFeed  event.Feed
+	reorgFeed event.Feed
+}
+
+func (c *testChain) CurrentBlock() *types.Block { return c.head }
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+func (c *testChain) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return c.reorgFeed.Subscribe(ch)
+}
+
+func (c *testChain) setHead(number int64) {
+	c.head = types.NewBlock(&types.Header{Number: big.NewInt(number)}, nil)
+	c.headFeed.Send(core.ChainHeadEvent{Block: c.head})
+}
+
+// waitHeight waits for tp to move on top of the block at height.
+func waitHeight(tp *TxPool, height uint64) {
+	for i := 0; i < 100; i++ {
+		tp.Mtx.RLock()
+		done := tp.Height == height
+		tp.Mtx.RUnlock()
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fundedPool returns a pool following chain over a chain database holding
+// the outputs, paying to program, of a funding transaction, that transaction
+// and the database.
+func fundedPool(chain Chain, program []byte) (*TxPool, *transaction.Tx, database.Database) {
+	data := transaction.TxData{
+		Version: 1,
+		Inputs: []*transaction.TxInput{
+			transaction.NewSpendInput(nil, transaction.Hash{V0: 1}, *transaction.SRCAssetID, 20000, 0, trueProgram),
+		},
+		Outputs: []*transaction.TxOutput{
+			transaction.NewTxOutput(*transaction.SRCAssetID, 10000, program),
+			transaction.NewTxOutput(*transaction.SRCAssetID, 10000, program),
+		},
+	}
+	fund := transaction.NewTx(data)
+	db := database.NewMemDatabase()
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{{Tx: data}})
+	rawdb.WriteTxLookupEntries(db, block)
+	return NewTxPool(chain, db), &fund, db
+}
+
+func TestAddTransactionInputs(t *testing.T) {
+	tp, fund, _ := fundedPool(nil, trueProgram)
+
+	spend := spendTx(fund, 0, 9000)
+	if err := tp.AddTransaction(spend, 1000); err != nil {
+		t.Fatalf("spend of a chain output rejected: %v", err)
+	}
+	if err := tp.AddTransaction(spend, 1000); err != ErrKnownTx {
+		t.Errorf("spend added twice: err = %v, want %v", err, ErrKnownTx)
+	}
+	if err := tp.AddTransaction(spendTx(fund, 0, 8000), 2000); err != ErrDoubleSpend {
+		t.Errorf("conflicting spend: err = %v, want %v", err, ErrDoubleSpend)
+	}
+	if err := tp.AddTransaction(spendTx(&spend, 0, 8000), 1000); err != nil {
+		t.Errorf("spend of a pool output rejected: %v", err)
+	}
+
+	unknown := spendTx(fund, 1, 9000)
+	if err := tp.AddTransaction(spendTx(&unknown, 0, 8000), 1000); err != ErrMissingInput {
+		t.Errorf("spend of an unknown output: err = %v, want %v", err, ErrMissingInput)
+	}
+	if len(tp.Pool) != 2 {
+		t.Errorf("pool size mismatch: have %d, want 2", len(tp.Pool))
+	}
+}
+
+func TestAddTransactionHeight(t *testing.T) {
+	program, err := vm.Assemble("BLOCKHEIGHT 5 GREATERTHANOREQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &testChain{head: types.NewBlock(&types.Header{Number: big.NewInt(3)}, nil)}
+	tp, fund, _ := fundedPool(chain, program)
+	defer tp.Stop()
+
+	spend := spendTx(fund, 0, 9000)
+	if err := tp.AddTransaction(spend, 1000); err == nil {
+		t.Fatal("spend locked until block 5 accepted for block 4")
+	}
+	chain.setHead(4)
+	waitHeight(tp, 4)
+	if err := tp.AddTransaction(spend, 1000); err != nil {
+		t.Fatalf("spend locked until block 5 rejected for block 5: %v", err)
+	}
+}
+
+func TestSelectTransactions(t *testing.T) {
+	tp, fund, _ := fundedPool(nil, trueProgram)
+
+	parent := spendTx(fund, 0, 4900, 5000)
+	child := spendTx(&parent, 0, 1000)
+	other := spendTx(fund, 1, 9000)
+	for _, c := range []struct {
+		tx  transaction.Tx
+		fee uint64
+	}{{parent, 100}, {child, 4900}, {other, 1000}} {
+		if err := tp.AddTransaction(c.tx, c.fee); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []transaction.Hash
+	for _, msg := range tp.SelectTransactions(math.MaxUint64) {
+		ids = append(ids, msg.Tx.ID)
+	}
+	if want := []transaction.Hash{other.ID, parent.ID, child.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("selection order mismatch: have %x, want %x", ids, want)
+	}
+
+	// A child whose parent does not fit is left out with it.
+	limit := tp.Pool[child.ID].Weight + tp.Pool[other.ID].Weight
+	ids = nil
+	for _, msg := range tp.SelectTransactions(limit) {
+		ids = append(ids, msg.Tx.ID)
+	}
+	if want := []transaction.Hash{other.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("selection within %d mismatch: have %x, want %x", limit, ids, want)
+	}
+}
+
+func TestResetOnHead(t *testing.T) {
+	chain := &testChain{head: types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil)}
+	tp, fund, db := fundedPool(chain, trueProgram)
+	defer tp.Stop()
+
+	parent := spendTx(fund, 0, 9900)
+	child := spendTx(&parent, 0, 5000)
+	spend := spendTx(fund, 1, 9000)
+	for _, tx := range []transaction.Tx{parent, child, spend} {
+		if err := tp.AddTransaction(tx, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Block 2 holds the parent and a spend conflicting with the pool's.
+	conflict := spendTx(fund, 1, 8000)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(2)}, []*types.Transaction{{Tx: parent.TxData}, {Tx: conflict.TxData}})
+	rawdb.WriteTxLookupEntries(db, block)
+	chain.setHead(2)
+	waitHeight(tp, 2)
+
+	tp.Mtx.RLock()
+	defer tp.Mtx.RUnlock()
+	if len(tp.Pool) != 1 || tp.Pool[child.ID] == nil {
+		t.Fatalf("pool after block 2 mismatch: have %d transactions, want the child only", len(tp.Pool))
+	}
+	if tp.Weight != tp.Pool[child.ID].Weight || tp.Fee != 100 {
+		t.Errorf("pool totals mismatch: weight %d, fee %d", tp.Weight, tp.Fee)
+	}
+	if _, ok := tp.spent[fund.SpentOutputIDs[0]]; ok || len(tp.spent) != 1 {
+		t.Errorf("spent outputs of dropped transactions kept: %d", len(tp.spent))
+	}
+}
+
+func TestTxSubmitError(t *testing.T) {
+	tp, fund, _ := fundedPool(nil, trueProgram)
+	raw, err := json.Marshal(map[string]interface{}{"raw_transaction": spendTx(fund, 0, 9000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tp.TxSubmit(string(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tp.TxSubmit(string(raw)); !errors.Is(err, ErrKnownTx) {
+		t.Errorf("resubmission error %q does not wrap %q", err, ErrKnownTx)
+	}
+	if _, err := tp.TxSubmit("{"); err == nil {
+		t.Error("malformed submission accepted")
+	}
+}
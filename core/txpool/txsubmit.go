@@ -1,9 +1,10 @@
-package transaction
+package txpool
 
 import (
 	"encoding/json"
+	"fmt"
 
-	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/core/transaction"
 )
 
 type TxSubmitResponse struct {
@@ -19,15 +20,17 @@ const (
 func (tp *TxPool)TxSubmit(raw_transaction string)(TxSubmitResponse,error)  {
 
 	var entity= struct {
-		Tx Tx `json:"raw_transaction"`
+		Tx transaction.Tx `json:"raw_transaction"`
 	}{}
 
 	err := json.Unmarshal([]byte(raw_transaction), &entity)
-	if err != nil {}
+	if err != nil {
+		return TxSubmitResponse{nil,FAIL},fmt.Errorf("decode raw transaction: %v", err)
+	}
 
-	err = tp.AddTransaction(entity.Tx, 2)
+	err = tp.AddTransaction(entity.Tx, transaction.CalculateTxFee(&entity.Tx.TxData))
 	if err != nil {
-		return TxSubmitResponse{nil,FAIL},errors.New("add tx to pool fail")
+		return TxSubmitResponse{nil,FAIL},fmt.Errorf("add tx to pool: %w", err)
 	}
 	return TxSubmitResponse{entity.Tx.ID.Bytes(),SUCCESS},nil
 }
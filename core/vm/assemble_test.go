@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"2 3 ADD 5 NUMEQUAL", "525393559c"},
+		{"0x02 0x03 ADD 0x05 NUMEQUAL", "010201039301059c"},
+		{"'abc' SIZE", "0361626382"},
+		{"'a\\'b' DROP", "0361276275"},
+		{"0 TRUE FALSE", "005100"},
+		{"JUMP:$end 1 $end", "630600000051"},
+		{"$top 1 JUMPIF:$top", "516400000000"},
+		{"JUMP:7", "6307000000"},
+		{"PUSHDATA1:0x61 PUSHDATA2:0x PUSHDATA4:0x62", "4c01614d00004e0100000062"},
+	}
+	for _, c := range cases {
+		got, err := Assemble(c.src)
+		if err != nil {
+			t.Errorf("Assemble(%q) error: %v", c.src, err)
+			continue
+		}
+		if hex.EncodeToString(got) != c.want {
+			t.Errorf("Assemble(%q) = %x want %s", c.src, got, c.want)
+		}
+	}
+
+	for _, src := range []string{"FOO", "PUSHDATA1", "DATA_2", "JUMP", "JUMP:$nowhere", "$a $a", "'unterminated", "PUSHDATA3:0x61", "DATA_1:0x61", "PUSHDATA1:0xzz"} {
+		if _, err := Assemble(src); err == nil {
+			t.Errorf("Assemble(%q) succeeded, want error", src)
+		}
+	}
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	contract := &HTLC{
+		SecretHash: bytes.Repeat([]byte{1}, 32),
+		Recipient:  bytes.Repeat([]byte{2}, 32),
+		Sender:     bytes.Repeat([]byte{3}, 32),
+		Timeout:    1000,
+	}
+	prog, err := HTLCProgram(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := Disassemble(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("Assemble(%q) error: %v", text, err)
+	}
+	if !bytes.Equal(got, prog) {
+		t.Errorf("round trip of %q: got %x want %x", text, got, prog)
+	}
+
+	for _, src := range []string{"2 3 ADD 5 NUMEQUAL", "0x02 'x' 0 NOPxff"} {
+		prog, _ := Assemble(src)
+		text, err := Disassemble(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := Assemble(text); !bytes.Equal(got, prog) {
+			t.Errorf("round trip of %q via %q: got %x want %x", src, text, got, prog)
+		}
+	}
+
+	// Programs Assemble would not produce keep their bytes too.
+	for _, src := range []string{
+		"4c00",           // empty push with a length byte
+		"4c0161",         // one byte pushed with a length byte
+		"4d01006175",     // one byte pushed with two length bytes
+		"6302000000",     // jump into its own destination
+		"63ffffff0051",   // jump past the end of the program
+		"63070000004c00", // jump to the end, after a non-minimal push
+	} {
+		prog, _ := hex.DecodeString(src)
+		text, err := Disassemble(prog)
+		if err != nil {
+			t.Errorf("Disassemble(%s) error: %v", src, err)
+			continue
+		}
+		if got, err := Assemble(text); err != nil || !bytes.Equal(got, prog) {
+			t.Errorf("round trip of %s via %q: got %x, %v", src, text, got, err)
+		}
+	}
+
+	if _, err := Disassemble([]byte{byte(OP_DATA_1 + 4), 1}); err != ErrShortProgram {
+		t.Errorf("Disassemble of truncated program: got %v want %v", err, ErrShortProgram)
+	}
+}
@@ -2,8 +2,13 @@ package vm
 
 import (
 	"encoding/binary"
+	"errors"
 )
 
+// ErrUnresolvedJump is returned by Build when a jump refers to a target that
+// was never set.
+var ErrUnresolvedJump = errors.New("jump target is never set")
+
 type  Builder struct {
 	program     []byte
 	jumpCounter int
@@ -23,11 +28,12 @@ func NewBuilder() *Builder {
 	}
 }
 
+// Build fills in the address of every jump and returns the finished program.
 func (b *Builder) Build() ([]byte, error) {
 	for target, placeholders := range b.jumpPlaceholders {
 		addr, ok := b.jumpAddr[target]
 		if !ok {
-			return nil, nil
+			return nil, ErrUnresolvedJump
 		}
 		for _, placeholder := range placeholders {
 			binary.LittleEndian.PutUint32(b.program[placeholder:placeholder+4], addr)
@@ -48,3 +54,55 @@ func (b *Builder) AddData(data []byte) *Builder {
 	return b
 }
 
+// AddRawBytes simply appends the given bytes to the program. (It does
+// not introduce a pushdata opcode.)
+func (b *Builder) AddRawBytes(data []byte) *Builder {
+	b.program = append(b.program, data...)
+	return b
+}
+
+// AddOp adds the given opcode to the program.
+func (b *Builder) AddOp(op Op) *Builder {
+	b.program = append(b.program, byte(op))
+	return b
+}
+
+// NewJumpTarget allocates a number that can be used as a jump target
+// in AddJump and AddJumpIf. Call SetJumpTarget to associate the
+// number with a program location.
+func (b *Builder) NewJumpTarget() int {
+	b.jumpCounter++
+	return b.jumpCounter
+}
+
+// AddJump adds a JUMP opcode whose target is the given target
+// number. The actual program location of the target does not need to
+// be known yet, as long as SetJumpTarget is called before Build.
+func (b *Builder) AddJump(target int) *Builder {
+	return b.addJump(OP_JUMP, target)
+}
+
+// AddJump adds a JUMPIF opcode whose target is the given target
+// number. The actual program location of the target does not need to
+// be known yet, as long as SetJumpTarget is called before Build.
+func (b *Builder) AddJumpIf(target int) *Builder {
+	return b.addJump(OP_JUMPIF, target)
+}
+
+func (b *Builder) addJump(op Op, target int) *Builder {
+	b.AddOp(op)
+	b.jumpPlaceholders[target] = append(b.jumpPlaceholders[target], len(b.program))
+	b.AddRawBytes([]byte{0, 0, 0, 0})
+	return b
+}
+
+// SetJumpTarget associates the given jump-target number with the
+// current position in the program - namely, the program's length,
+// such that the first instruction executed by a jump using this
+// target will be whatever instruction is added next. It is legal for
+// SetJumpTarget to be called at the same place in the program more
+// than once with different target numbers.
+func (b *Builder) SetJumpTarget(target int) *Builder {
+	b.jumpAddr[target] = uint32(len(b.program))
+	return b
+}
@@ -0,0 +1,33 @@
+package vm
+
+// Context contains the execution context for the virtual machine.
+//
+// Most fields are pointers and are not required to be present in all
+// cases. A nil pointer means the value is absent in that context. If
+// an opcode executes that requires an absent field to be present, it
+// will return ErrContext.
+type Context struct {
+	VMVersion uint64
+	Code      []byte
+	Arguments [][]byte
+
+	EntryID []byte
+
+	// TxVersion must be present when verifying transaction components
+	// (such as spends and issuances).
+	TxVersion   *uint64
+	BlockHeight *uint64
+
+	// Fields below this point are required by particular opcodes when
+	// verifying transaction components.
+
+	SpentOutputID *[]byte
+	AssetID       *[]byte
+	Amount        *uint64
+
+	TxSigHash func() []byte
+
+	// SigHash returns the digest selected by a signature's trailing
+	// sighash type byte.
+	SigHash func(sigHashType byte) ([]byte, error)
+}
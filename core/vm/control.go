@@ -0,0 +1,35 @@
+package vm
+
+import "encoding/binary"
+
+func opVerify(vm *virtualMachine) error {
+	p, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if AsBool(p) {
+		return nil
+	}
+	return ErrVerifyFailed
+}
+
+func opFail(vm *virtualMachine) error {
+	return ErrReturn
+}
+
+func opJump(vm *virtualMachine) error {
+	address := binary.LittleEndian.Uint32(vm.data)
+	vm.nextPC = address
+	return nil
+}
+
+func opJumpIf(vm *virtualMachine) error {
+	p, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if AsBool(p) {
+		return opJump(vm)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/srchain/srcd/crypto/ed25519"
+	"github.com/srchain/srcd/crypto/ripemd160"
+	"github.com/srchain/srcd/crypto/sha3pool"
+)
+
+func opSha256(vm *virtualMachine) error {
+	data, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if err = vm.applyCost(int64(len(data))); err != nil {
+		return err
+	}
+	h := sha256.Sum256(data)
+	return vm.push(h[:])
+}
+
+func opSha3(vm *virtualMachine) error {
+	data, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if err = vm.applyCost(int64(len(data))); err != nil {
+		return err
+	}
+	h := make([]byte, 32)
+	sha3pool.Sum256(h, data)
+	return vm.push(h)
+}
+
+func opHash160(vm *virtualMachine) error {
+	data, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if err = vm.applyCost(int64(len(data))); err != nil {
+		return err
+	}
+	return vm.push(ripemd160.Ripemd160(data))
+}
+
+func opCheckSig(vm *virtualMachine) error {
+	if err := vm.applyCost(1024); err != nil {
+		return err
+	}
+	pubkeyBytes, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	msg, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	sig, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if len(msg) != 32 {
+		return ErrBadValue
+	}
+	if len(pubkeyBytes) != ed25519.PublicKeySize {
+		return vm.pushBool(false)
+	}
+	digest, sig, err := vm.sigDigest(msg, sig)
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(ed25519.Verify(ed25519.PublicKey(pubkeyBytes), digest, sig))
+}
+
+func opCheckMultiSig(vm *virtualMachine) error {
+	numPubkeys, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	pubCost := 1024 * numPubkeys
+	if pubCost < 0 {
+		return ErrBadValue
+	}
+	if err = vm.applyCost(pubCost); err != nil {
+		return err
+	}
+	numSigs, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if numSigs < 0 || numSigs > numPubkeys || (numPubkeys > 0 && numSigs == 0) {
+		return ErrBadValue
+	}
+	pubkeyByteses := make([][]byte, 0, numPubkeys)
+	for i := int64(0); i < numPubkeys; i++ {
+		pubkeyBytes, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		pubkeyByteses = append(pubkeyByteses, pubkeyBytes)
+	}
+	msg, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if len(msg) != 32 {
+		return ErrBadValue
+	}
+	sigs := make([][]byte, 0, numSigs)
+	for i := int64(0); i < numSigs; i++ {
+		sig, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		sigs = append(sigs, sig)
+	}
+
+	pubkeys := make([]ed25519.PublicKey, 0, numPubkeys)
+	for _, p := range pubkeyByteses {
+		if len(p) != ed25519.PublicKeySize {
+			return vm.pushBool(false)
+		}
+		pubkeys = append(pubkeys, ed25519.PublicKey(p))
+	}
+
+	for len(sigs) > 0 && len(pubkeys) > 0 {
+		digest, sig, err := vm.sigDigest(msg, sigs[0])
+		if err != nil {
+			return err
+		}
+		if ed25519.Verify(pubkeys[0], digest, sig) {
+			sigs = sigs[1:]
+		}
+		pubkeys = pubkeys[1:]
+	}
+	return vm.pushBool(len(sigs) == 0)
+}
+
+// sigDigest returns the message sig is to be checked against, and the
+// signature itself. A plain signature signs msg. A signature followed by a
+// sighash type byte signs the digest of whichever parts of the transaction
+// that type selects; msg must then be the transaction's TXSIGHASH.
+func (vm *virtualMachine) sigDigest(msg, sig []byte) ([]byte, []byte, error) {
+	if len(sig) != ed25519.SignatureSize+1 {
+		return msg, sig, nil
+	}
+	if vm.context.TxSigHash == nil || vm.context.SigHash == nil {
+		return nil, nil, ErrContext
+	}
+	if !bytes.Equal(msg, vm.context.TxSigHash()) {
+		return nil, nil, ErrBadValue
+	}
+	digest, err := vm.context.SigHash(sig[ed25519.SignatureSize])
+	if err != nil {
+		return nil, nil, err
+	}
+	return digest, sig[:ed25519.SignatureSize], nil
+}
+
+func opTxSigHash(vm *virtualMachine) error {
+	if err := vm.applyCost(256); err != nil {
+		return err
+	}
+	if vm.context.TxSigHash == nil {
+		return ErrContext
+	}
+	return vm.push(vm.context.TxSigHash())
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/srchain/srcd/crypto/ed25519"
+)
+
+// Witness clause selectors for HTLC programs. The selector is the last
+// witness argument and so sits on top of the stack when the program starts.
+var (
+	HTLCRedeemClause = []byte{}
+	HTLCRefundClause = []byte{1}
+)
+
+// HTLCPreimageSize is the size of HTLC preimages. Requiring it keeps a swap
+// from being locked on a preimage that the program on the other chain
+// cannot take.
+const HTLCPreimageSize = 32
+
+// ErrNotHTLCProgram is returned when a program does not match the HTLC
+// template.
+var ErrNotHTLCProgram = errors.New("program is not an HTLC program")
+
+// HTLC holds the parameters of a hash time-locked contract.
+type HTLC struct {
+	SecretHash []byte            // sha256 of the secret revealed by the redeemer
+	Recipient  ed25519.PublicKey // may redeem with the secret before Timeout
+	Sender     ed25519.PublicKey // may refund at or after Timeout
+	Timeout    uint64            // block height at which the refund clause opens
+}
+
+// HTLCProgram returns the control program for a hash time-locked contract.
+//
+// Redeem witness: <recipient sig> <preimage> HTLCRedeemClause, where the
+// preimage is HTLCPreimageSize bytes long.
+// Refund witness: <sender sig> HTLCRefundClause
+func HTLCProgram(c *HTLC) ([]byte, error) {
+	if len(c.SecretHash) != sha256.Size {
+		return nil, ErrBadValue
+	}
+	if len(c.Recipient) != ed25519.PublicKeySize || len(c.Sender) != ed25519.PublicKeySize {
+		return nil, ErrBadValue
+	}
+	if c.Timeout > 1<<63-1 {
+		return nil, ErrRange
+	}
+
+	builder := NewBuilder()
+	refund := builder.NewJumpTarget()
+	end := builder.NewJumpTarget()
+
+	builder.AddJumpIf(refund)
+	builder.AddOp(OP_BLOCKHEIGHT).AddInt64(int64(c.Timeout)).AddOp(OP_LESSTHAN).AddOp(OP_VERIFY)
+	builder.AddOp(OP_SIZE).AddInt64(HTLCPreimageSize).AddOp(OP_EQUALVERIFY)
+	builder.AddOp(OP_SHA256).AddData(c.SecretHash).AddOp(OP_EQUALVERIFY)
+	builder.AddOp(OP_TXSIGHASH).AddData(c.Recipient).AddOp(OP_CHECKSIG)
+	builder.AddJump(end)
+
+	builder.SetJumpTarget(refund)
+	builder.AddOp(OP_BLOCKHEIGHT).AddInt64(int64(c.Timeout)).AddOp(OP_GREATERTHANOREQUAL).AddOp(OP_VERIFY)
+	builder.AddOp(OP_TXSIGHASH).AddData(c.Sender).AddOp(OP_CHECKSIG)
+	builder.SetJumpTarget(end)
+
+	return builder.Build()
+}
+
+// ParseHTLCProgram extracts the contract parameters from an HTLC program.
+func ParseHTLCProgram(prog []byte) (*HTLC, error) {
+	insts, err := ParseProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+	if len(insts) != 22 {
+		return nil, ErrNotHTLCProgram
+	}
+
+	timeout, err := AsInt64(insts[2].Data)
+	if err != nil || timeout < 0 {
+		return nil, ErrNotHTLCProgram
+	}
+	c := &HTLC{
+		SecretHash: insts[9].Data,
+		Recipient:  ed25519.PublicKey(insts[12].Data),
+		Sender:     ed25519.PublicKey(insts[20].Data),
+		Timeout:    uint64(timeout),
+	}
+
+	// Anything that doesn't rebuild to the very same bytes isn't ours.
+	expected, err := HTLCProgram(c)
+	if err != nil || !bytes.Equal(expected, prog) {
+		return nil, ErrNotHTLCProgram
+	}
+	return c, nil
+}
+
+// IsHTLCProgram reports whether prog matches the HTLC template.
+func IsHTLCProgram(prog []byte) bool {
+	_, err := ParseHTLCProgram(prog)
+	return err == nil
+}
+
+// HTLCPreimage returns the secret revealed by the witness arguments of an
+// input spending the HTLC through its redeem clause.
+func HTLCPreimage(c *HTLC, args [][]byte) ([]byte, error) {
+	if len(args) != 3 || AsBool(args[2]) {
+		return nil, ErrNotHTLCProgram
+	}
+	if len(args[1]) != HTLCPreimageSize {
+		return nil, ErrVerifyFailed
+	}
+	h := sha256.Sum256(args[1])
+	if !bytes.Equal(h[:], c.SecretHash) {
+		return nil, ErrVerifyFailed
+	}
+	return args[1], nil
+}
@@ -0,0 +1,83 @@
+package vm
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+)
+
+func TestHTLCProgram(t *testing.T) {
+	recipientPrv, recipientPub, _ := chainkd.NewXKeys(nil)
+	senderPrv, senderPub, _ := chainkd.NewXKeys(nil)
+	secret := []byte("atomic swap secret of 32 bytes..")
+	hash := sha256.Sum256(secret)
+	short := []byte("atomic swap secret")
+	shortHash := sha256.Sum256(short)
+
+	contract := &HTLC{
+		SecretHash: hash[:],
+		Recipient:  recipientPub.PublicKey(),
+		Sender:     senderPub.PublicKey(),
+		Timeout:    100,
+	}
+	prog, err := HTLCProgram(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseHTLCProgram(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Timeout != contract.Timeout || string(parsed.SecretHash) != string(contract.SecretHash) {
+		t.Errorf("ParseHTLCProgram = %+v, want %+v", parsed, contract)
+	}
+
+	sigHash := sha256.Sum256([]byte("tx"))
+	recipientSig := recipientPrv.Sign(sigHash[:])
+	senderSig := senderPrv.Sign(sigHash[:])
+
+	cases := []struct {
+		name   string
+		height uint64
+		args   [][]byte
+		ok     bool
+	}{
+		{"redeem", 99, [][]byte{recipientSig, secret, HTLCRedeemClause}, true},
+		{"redeem after timeout", 100, [][]byte{recipientSig, secret, HTLCRedeemClause}, false},
+		{"redeem with wrong secret", 99, [][]byte{recipientSig, []byte("guess"), HTLCRedeemClause}, false},
+		{"redeem by sender", 99, [][]byte{senderSig, secret, HTLCRedeemClause}, false},
+		{"redeem with short secret", 99, [][]byte{recipientSig, short, HTLCRedeemClause}, false},
+		{"refund", 100, [][]byte{senderSig, HTLCRefundClause}, true},
+		{"refund before timeout", 99, [][]byte{senderSig, HTLCRefundClause}, false},
+		{"refund by recipient", 100, [][]byte{recipientSig, HTLCRefundClause}, false},
+	}
+	for _, c := range cases {
+		height := c.height
+		ctx := &Context{
+			VMVersion:   1,
+			Code:        prog,
+			Arguments:   c.args,
+			BlockHeight: &height,
+			TxSigHash:   func() []byte { return sigHash[:] },
+		}
+		err := Verify(ctx, DefaultRunLimit)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: Verify() error = %v, want success %v", c.name, err, c.ok)
+		}
+	}
+
+	preimage, err := HTLCPreimage(contract, [][]byte{recipientSig, secret, HTLCRedeemClause})
+	if err != nil || string(preimage) != string(secret) {
+		t.Errorf("HTLCPreimage = %q, %v; want %q", preimage, err, secret)
+	}
+	if _, err := HTLCPreimage(contract, [][]byte{senderSig, HTLCRefundClause}); err == nil {
+		t.Error("HTLCPreimage on refund witness should fail")
+	}
+	shortContract := *contract
+	shortContract.SecretHash = shortHash[:]
+	if _, err := HTLCPreimage(&shortContract, [][]byte{recipientSig, short, HTLCRedeemClause}); err == nil {
+		t.Error("HTLCPreimage with a short preimage should fail")
+	}
+}
@@ -0,0 +1,33 @@
+package vm
+
+func opAsset(vm *virtualMachine) error {
+	if vm.context.AssetID == nil {
+		return ErrContext
+	}
+	return vm.push(*vm.context.AssetID)
+}
+
+func opAmount(vm *virtualMachine) error {
+	if vm.context.Amount == nil {
+		return ErrContext
+	}
+	return vm.pushInt64(int64(*vm.context.Amount))
+}
+
+func opEntryID(vm *virtualMachine) error {
+	return vm.push(vm.context.EntryID)
+}
+
+func opOutputID(vm *virtualMachine) error {
+	if vm.context.SpentOutputID == nil {
+		return ErrContext
+	}
+	return vm.push(*vm.context.SpentOutputID)
+}
+
+func opBlockHeight(vm *virtualMachine) error {
+	if vm.context.BlockHeight == nil {
+		return ErrContext
+	}
+	return vm.pushInt64(int64(*vm.context.BlockHeight))
+}
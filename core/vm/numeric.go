@@ -0,0 +1,282 @@
+package vm
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// AsBool interprets a stack item as a boolean: false if it is empty or
+// contains only zero bytes, true otherwise.
+func AsBool(bytes []byte) bool {
+	for _, b := range bytes {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// BoolBytes returns the stack encoding of b.
+func BoolBytes(b bool) (result []byte) {
+	if b {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// AsInt64 interprets a stack item as a little-endian signed integer of at
+// most eight bytes.
+func AsInt64(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if len(b) > 8 {
+		return 0, ErrBadValue
+	}
+
+	var padded [8]byte
+	copy(padded[:], b)
+
+	res := binary.LittleEndian.Uint64(padded[:])
+	// converting uint64 to int64 is a safe operation that
+	// preserves all data
+	return int64(res), nil
+}
+
+func op1Add(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n == math.MaxInt64 {
+		return ErrRange
+	}
+	return vm.pushInt64(n + 1)
+}
+
+func op1Sub(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n == math.MinInt64 {
+		return ErrRange
+	}
+	return vm.pushInt64(n - 1)
+}
+
+func opNegate(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n == math.MinInt64 {
+		return ErrRange
+	}
+	return vm.pushInt64(-n)
+}
+
+func opAbs(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n == math.MinInt64 {
+		return ErrRange
+	}
+	if n < 0 {
+		n = -n
+	}
+	return vm.pushInt64(n)
+}
+
+func opNot(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(n == 0)
+}
+
+func op0NotEqual(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(n != 0)
+}
+
+func opAdd(vm *virtualMachine) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if (y > 0 && x > math.MaxInt64-y) || (y < 0 && x < math.MinInt64-y) {
+		return ErrRange
+	}
+	return vm.pushInt64(x + y)
+}
+
+func opSub(vm *virtualMachine) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if (y < 0 && x > math.MaxInt64+y) || (y > 0 && x < math.MinInt64+y) {
+		return ErrRange
+	}
+	return vm.pushInt64(x - y)
+}
+
+func opBoolAnd(vm *virtualMachine) error {
+	b, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	a, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(AsBool(a) && AsBool(b))
+}
+
+func opBoolOr(vm *virtualMachine) error {
+	b, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	a, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(AsBool(a) || AsBool(b))
+}
+
+const (
+	cmpLess = iota
+	cmpLessEqual
+	cmpGreater
+	cmpGreaterEqual
+	cmpEqual
+	cmpNotEqual
+)
+
+func opNumEqual(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpEqual)
+}
+
+func opNumEqualVerify(vm *virtualMachine) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if x == y {
+		return nil
+	}
+	return ErrVerifyFailed
+}
+
+func opNumNotEqual(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpNotEqual)
+}
+
+func opLessThan(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpLess)
+}
+
+func opGreaterThan(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpGreater)
+}
+
+func opLessThanOrEqual(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpLessEqual)
+}
+
+func opGreaterThanOrEqual(vm *virtualMachine) error {
+	return doNumCompare(vm, cmpGreaterEqual)
+}
+
+func doNumCompare(vm *virtualMachine, op int) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	var res bool
+	switch op {
+	case cmpLess:
+		res = x < y
+	case cmpLessEqual:
+		res = x <= y
+	case cmpGreater:
+		res = x > y
+	case cmpGreaterEqual:
+		res = x >= y
+	case cmpEqual:
+		res = x == y
+	case cmpNotEqual:
+		res = x != y
+	}
+	return vm.pushBool(res)
+}
+
+func opMin(vm *virtualMachine) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if x > y {
+		x = y
+	}
+	return vm.pushInt64(x)
+}
+
+func opMax(vm *virtualMachine) error {
+	y, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if x < y {
+		x = y
+	}
+	return vm.pushInt64(x)
+}
+
+func opWithin(vm *virtualMachine) error {
+	max, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	min, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	x, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(x >= min && x < max)
+}
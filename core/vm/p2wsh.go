@@ -1,5 +1,11 @@
 package vm
 
+import (
+	"crypto/sha256"
+
+	"github.com/srchain/srcd/crypto/ed25519"
+)
+
 func P2WSHProgram(hash []byte)([]byte,error){
 	builder := NewBuilder()
 	builder.AddInt64(0)
@@ -8,6 +14,59 @@ func P2WSHProgram(hash []byte)([]byte,error){
 	return builder.Build()
 }
 
+// IsP2WPKHProgram reports whether prog is a version 0 witness program
+// committing to a 20-byte public key hash.
+func IsP2WPKHProgram(prog []byte) bool {
+	return len(prog) == 22 && prog[0] == byte(OP_0) && prog[1] == byte(OP_DATA_1)+19
+}
+
+// P2PKHSigProgram returns the program a witness pubkey hash stands for: the
+// spender supplies a signature and the public key hashing to pubkeyHash.
+func P2PKHSigProgram(pubkeyHash []byte) ([]byte, error) {
+	builder := NewBuilder()
+	builder.AddOp(OP_DUP)
+	builder.AddOp(OP_HASH160)
+	builder.AddData(pubkeyHash)
+	builder.AddOp(OP_EQUALVERIFY)
+	builder.AddOp(OP_TXSIGHASH)
+	builder.AddOp(OP_SWAP)
+	builder.AddOp(OP_CHECKSIG)
+	return builder.Build()
+}
+
+// P2WSHScriptProgram returns the version 0 witness program committing to the
+// sha256 hash of script. It is spent by the arguments of script followed by
+// script itself.
+func P2WSHScriptProgram(script []byte) ([]byte, error) {
+	h := sha256.Sum256(script)
+	return P2WSHProgram(h[:])
+}
+
+// IsP2WSHProgram reports whether prog is a version 0 witness program
+// committing to a 32-byte script hash.
+func IsP2WSHProgram(prog []byte) bool {
+	return len(prog) == 34 && prog[0] == byte(OP_0) && prog[1] == byte(OP_DATA_1)+31
+}
+
+// MultisigProgram returns a script checking quorum signatures of the
+// transaction by distinct keys of pubkeys. The spender supplies the
+// signatures in the order of their keys in pubkeys.
+func MultisigProgram(pubkeys []ed25519.PublicKey, quorum int) ([]byte, error) {
+	if quorum < 1 || quorum > len(pubkeys) {
+		return nil, ErrBadValue
+	}
+	builder := NewBuilder()
+	builder.AddOp(OP_TXSIGHASH)
+	for _, pub := range pubkeys {
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, ErrBadValue
+		}
+		builder.AddData(pub)
+	}
+	builder.AddInt64(int64(quorum)).AddInt64(int64(len(pubkeys))).AddOp(OP_CHECKMULTISIG)
+	return builder.Build()
+}
+
 //func ProgramScriptBind(address common.Address)([]byte,error){
 //
-//}
\ No newline at end of file
+//}
@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/srchain/srcd/crypto/ed25519"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/crypto/ripemd160"
+)
+
+func TestP2WPKHProgram(t *testing.T) {
+	xprv, xpub, _ := chainkd.NewXKeys(nil)
+	pub := xpub.PublicKey()
+	sigHash := sha256.Sum256([]byte("tx"))
+	sig := xprv.Sign(sigHash[:])
+
+	prog, err := P2WSHProgram(ripemd160.Ripemd160(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsP2WPKHProgram(prog) {
+		t.Fatalf("IsP2WPKHProgram(%x) = false", prog)
+	}
+
+	for _, c := range []struct {
+		args [][]byte
+		ok   bool
+	}{
+		{[][]byte{sig, pub}, true},
+		{[][]byte{sig[:63], pub}, false},
+		{[][]byte{pub, sig}, false},
+	} {
+		ctx := &Context{
+			VMVersion: 1,
+			Code:      prog,
+			Arguments: c.args,
+			TxSigHash: func() []byte { return sigHash[:] },
+		}
+		if err := Verify(ctx, DefaultRunLimit); (err == nil) != c.ok {
+			t.Errorf("Verify(%x) error = %v, want success %v", c.args, err, c.ok)
+		}
+	}
+}
+
+func TestMultisigWitnessScript(t *testing.T) {
+	var (
+		xprvs   []chainkd.XPrv
+		pubkeys []ed25519.PublicKey
+	)
+	for i := 0; i < 3; i++ {
+		xprv, xpub, _ := chainkd.NewXKeys(nil)
+		xprvs = append(xprvs, xprv)
+		pubkeys = append(pubkeys, xpub.PublicKey())
+	}
+	script, err := MultisigProgram(pubkeys, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := P2WSHScriptProgram(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsP2WSHProgram(prog) || IsP2WPKHProgram(prog) {
+		t.Fatalf("%x is not a witness script program", prog)
+	}
+
+	sigHash := sha256.Sum256([]byte("tx"))
+	sigs := make([][]byte, len(xprvs))
+	for i, xprv := range xprvs {
+		sigs[i] = xprv.Sign(sigHash[:])
+	}
+	other, err := MultisigProgram(pubkeys[:2], 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		args [][]byte
+		ok   bool
+	}{
+		{"first two keys", [][]byte{sigs[0], sigs[1], script}, true},
+		{"outer keys", [][]byte{sigs[0], sigs[2], script}, true},
+		{"out of order", [][]byte{sigs[2], sigs[0], script}, false},
+		{"one signature", [][]byte{sigs[1], script}, false},
+		{"same signature twice", [][]byte{sigs[1], sigs[1], script}, false},
+		{"wrong script", [][]byte{sigs[0], sigs[1], other}, false},
+		{"no script", [][]byte{sigs[0], sigs[1]}, false},
+	} {
+		ctx := &Context{
+			VMVersion: 1,
+			Code:      prog,
+			Arguments: c.args,
+			TxSigHash: func() []byte { return sigHash[:] },
+		}
+		if err := Verify(ctx, DefaultRunLimit); (err == nil) != c.ok {
+			t.Errorf("%s: Verify() error = %v, want success %v", c.name, err, c.ok)
+		}
+	}
+
+	if _, err := MultisigProgram(pubkeys, 0); err == nil {
+		t.Error("MultisigProgram accepted a zero quorum")
+	}
+	if _, err := MultisigProgram(pubkeys, 4); err == nil {
+		t.Error("MultisigProgram accepted a quorum above the number of keys")
+	}
+}
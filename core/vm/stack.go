@@ -0,0 +1,222 @@
+package vm
+
+import "bytes"
+
+func opFalse(vm *virtualMachine) error {
+	return vm.pushBool(false)
+}
+
+func opPushdata(vm *virtualMachine) error {
+	d := make([]byte, len(vm.data))
+	copy(d, vm.data)
+	return vm.push(d)
+}
+
+func op1Negate(vm *virtualMachine) error {
+	return vm.pushInt64(-1)
+}
+
+func opNop(vm *virtualMachine) error {
+	return nil
+}
+
+func opToAltStack(vm *virtualMachine) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	vm.altStack = append(vm.altStack, v)
+	return nil
+}
+
+func opFromAltStack(vm *virtualMachine) error {
+	if len(vm.altStack) == 0 {
+		return ErrAltStackUnderflow
+	}
+	v := vm.altStack[len(vm.altStack)-1]
+	vm.altStack = vm.altStack[:len(vm.altStack)-1]
+	return vm.push(v)
+}
+
+func op2Drop(vm *virtualMachine) error {
+	for i := 0; i < 2; i++ {
+		if _, err := vm.pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func op2Dup(vm *virtualMachine) error {
+	return nDup(vm, 2)
+}
+
+func nDup(vm *virtualMachine, n int) error {
+	if len(vm.dataStack) < n {
+		return ErrDataStackUnderflow
+	}
+	for i := 0; i < n; i++ {
+		if err := vm.push(vm.dataStack[len(vm.dataStack)-n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func opDepth(vm *virtualMachine) error {
+	return vm.pushInt64(int64(len(vm.dataStack)))
+}
+
+func opDrop(vm *virtualMachine) error {
+	_, err := vm.pop()
+	return err
+}
+
+func opDup(vm *virtualMachine) error {
+	return nDup(vm, 1)
+}
+
+func opNip(vm *virtualMachine) error {
+	top, err := vm.top()
+	if err != nil {
+		return err
+	}
+	// temporarily pop off the top value with no standard memory accounting
+	vm.dataStack = vm.dataStack[:len(vm.dataStack)-1]
+	if _, err = vm.pop(); err != nil {
+		return err
+	}
+	// now put the top item back
+	vm.dataStack = append(vm.dataStack, top)
+	return nil
+}
+
+func opOver(vm *virtualMachine) error {
+	if len(vm.dataStack) < 2 {
+		return ErrDataStackUnderflow
+	}
+	return vm.push(vm.dataStack[len(vm.dataStack)-2])
+}
+
+func opPick(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return ErrBadValue
+	}
+	if int64(len(vm.dataStack)) < n+1 {
+		return ErrDataStackUnderflow
+	}
+	return vm.push(vm.dataStack[int64(len(vm.dataStack))-(n+1)])
+}
+
+func opRoll(vm *virtualMachine) error {
+	n, err := vm.popInt64()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return ErrBadValue
+	}
+	if int64(len(vm.dataStack)) < n+1 {
+		return ErrDataStackUnderflow
+	}
+	return rot(vm, n+1)
+}
+
+func opRot(vm *virtualMachine) error {
+	return rot(vm, 3)
+}
+
+func rot(vm *virtualMachine, n int64) error {
+	if n < 1 {
+		return ErrBadValue
+	}
+	if int64(len(vm.dataStack)) < n {
+		return ErrDataStackUnderflow
+	}
+	index := int64(len(vm.dataStack)) - n
+	newStack := make([][]byte, 0, len(vm.dataStack))
+	newStack = append(newStack, vm.dataStack[:index]...)
+	newStack = append(newStack, vm.dataStack[index+1:]...)
+	newStack = append(newStack, vm.dataStack[index])
+	vm.dataStack = newStack
+	return nil
+}
+
+func opSwap(vm *virtualMachine) error {
+	l := len(vm.dataStack)
+	if l < 2 {
+		return ErrDataStackUnderflow
+	}
+	vm.dataStack[l-1], vm.dataStack[l-2] = vm.dataStack[l-2], vm.dataStack[l-1]
+	return nil
+}
+
+func opTuck(vm *virtualMachine) error {
+	if len(vm.dataStack) < 2 {
+		return ErrDataStackUnderflow
+	}
+	top2 := make([][]byte, 2)
+	copy(top2, vm.dataStack[len(vm.dataStack)-2:])
+	// temporarily remove the top two items without standard memory accounting
+	vm.dataStack = vm.dataStack[:len(vm.dataStack)-2]
+	if err := vm.push(top2[1]); err != nil {
+		return err
+	}
+	vm.dataStack = append(vm.dataStack, top2...)
+	return nil
+}
+
+func opCat(vm *virtualMachine) error {
+	b, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	a, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	return vm.push(append(append([]byte{}, a...), b...))
+}
+
+func opSize(vm *virtualMachine) error {
+	str, err := vm.top()
+	if err != nil {
+		return err
+	}
+	return vm.pushInt64(int64(len(str)))
+}
+
+func opEqual(vm *virtualMachine) error {
+	res, err := doEqual(vm)
+	if err != nil {
+		return err
+	}
+	return vm.pushBool(res)
+}
+
+func opEqualVerify(vm *virtualMachine) error {
+	res, err := doEqual(vm)
+	if err != nil {
+		return err
+	}
+	if res {
+		return nil
+	}
+	return ErrVerifyFailed
+}
+
+func doEqual(vm *virtualMachine) (bool, error) {
+	x2, err := vm.pop()
+	if err != nil {
+		return false, err
+	}
+	x1, err := vm.pop()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(x1, x2), nil
+}
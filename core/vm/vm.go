@@ -0,0 +1,191 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultRunLimit is the run limit applied to a single control program
+	// when the caller doesn't need anything more specific.
+	DefaultRunLimit = 10000
+
+	math32 = 1<<31 - 1
+)
+
+type virtualMachine struct {
+	context *Context
+
+	program  []byte // the program currently executing
+	pc       uint32 // position of the current instruction
+	nextPC   uint32 // position of the next instruction
+	runLimit int64
+	data     []byte // data of the current instruction
+
+	dataStack [][]byte
+	altStack  [][]byte
+}
+
+// TraceOut - if non-nil - will receive trace output during
+// execution.
+var TraceOut io.Writer
+
+// Verify runs the control program in context against its witness arguments.
+// It succeeds if the program runs to completion within runLimit and leaves a
+// true value on top of the data stack.
+func Verify(context *Context, runLimit int64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = fmt.Errorf("panic in vm: %v", rErr)
+			} else {
+				err = ErrUnexpected
+			}
+		}
+	}()
+
+	if context.VMVersion != 1 {
+		return ErrUnsupportedVM
+	}
+
+	vm := &virtualMachine{
+		context:  context,
+		program:  context.Code,
+		runLimit: runLimit,
+	}
+
+	for _, arg := range context.Arguments {
+		if err = vm.push(arg); err != nil {
+			return err
+		}
+	}
+
+	// A witness program is shorthand for its pay-to-pubkey-hash equivalent.
+	if IsP2WPKHProgram(vm.program) {
+		if vm.program, err = P2PKHSigProgram(vm.program[2:]); err != nil {
+			return err
+		}
+	}
+	// A witness script program runs the script given as the last argument,
+	// which must hash to the program's commitment.
+	if IsP2WSHProgram(vm.program) {
+		script, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		if h := sha256.Sum256(script); !bytes.Equal(h[:], vm.program[2:]) {
+			return ErrWitnessScript
+		}
+		vm.program = script
+	}
+
+	if err = vm.run(); err != nil {
+		return err
+	}
+	if vm.falseResult() {
+		return ErrFalseVMResult
+	}
+	return nil
+}
+
+// falseResult returns true iff the stack is empty or the top
+// item is false
+func (vm *virtualMachine) falseResult() bool {
+	return len(vm.dataStack) == 0 || !AsBool(vm.dataStack[len(vm.dataStack)-1])
+}
+
+func (vm *virtualMachine) run() error {
+	for vm.pc = 0; vm.pc < uint32(len(vm.program)); { // handle vm.pc updates in step
+		if err := vm.step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vm *virtualMachine) step() error {
+	inst, err := ParseOp(vm.program, vm.pc)
+	if err != nil {
+		return err
+	}
+
+	vm.nextPC = vm.pc + inst.Len
+
+	if TraceOut != nil {
+		opname := inst.Op.String()
+		fmt.Fprintf(TraceOut, "vm %d pc %d limit %d %s", vm.context.VMVersion, vm.pc, vm.runLimit, opname)
+		if len(inst.Data) > 0 {
+			fmt.Fprintf(TraceOut, " %x", inst.Data)
+		}
+		fmt.Fprint(TraceOut, "\n")
+	}
+
+	if err = vm.applyCost(1); err != nil {
+		return err
+	}
+
+	vm.data = inst.Data
+	if err = ops[inst.Op].fn(vm); err != nil {
+		return err
+	}
+
+	if TraceOut != nil {
+		for i := len(vm.dataStack) - 1; i >= 0; i-- {
+			fmt.Fprintf(TraceOut, "  stack %d: %x\n", len(vm.dataStack)-1-i, vm.dataStack[i])
+		}
+	}
+
+	vm.pc = vm.nextPC
+	return nil
+}
+
+func (vm *virtualMachine) push(data []byte) error {
+	if err := vm.applyCost(int64(len(data))); err != nil {
+		return err
+	}
+	vm.dataStack = append(vm.dataStack, data)
+	return nil
+}
+
+func (vm *virtualMachine) pushBool(b bool) error {
+	return vm.push(BoolBytes(b))
+}
+
+func (vm *virtualMachine) pushInt64(n int64) error {
+	return vm.push(Int64Bytes(n))
+}
+
+func (vm *virtualMachine) pop() ([]byte, error) {
+	if len(vm.dataStack) == 0 {
+		return nil, ErrDataStackUnderflow
+	}
+	res := vm.dataStack[len(vm.dataStack)-1]
+	vm.dataStack = vm.dataStack[:len(vm.dataStack)-1]
+	return res, nil
+}
+
+func (vm *virtualMachine) popInt64() (int64, error) {
+	bytes, err := vm.pop()
+	if err != nil {
+		return 0, err
+	}
+	return AsInt64(bytes)
+}
+
+func (vm *virtualMachine) top() ([]byte, error) {
+	if len(vm.dataStack) == 0 {
+		return nil, ErrDataStackUnderflow
+	}
+	return vm.dataStack[len(vm.dataStack)-1], nil
+}
+
+func (vm *virtualMachine) applyCost(n int64) error {
+	if n > vm.runLimit {
+		vm.runLimit = 0
+		return ErrRunLimitExceeded
+	}
+	vm.runLimit -= n
+	return nil
+}
@@ -65,11 +65,125 @@ func (xprv XPrv) XPub() (xpub XPub) {
 	return
 }
 
+// Child derives a child xprv based on `selector` string and `hardened` flag.
+// If `hardened` is false, child xpub can be derived independently
+// from the parent xpub without using the parent xprv.
+// If `hardened` is true, child key can only be derived from the parent xprv.
+func (xprv XPrv) Child(sel []byte, hardened bool) XPrv {
+	if hardened {
+		return xprv.hardenedChild(sel)
+	}
+	return xprv.nonhardenedChild(sel)
+}
+
+func (xprv XPrv) hardenedChild(sel []byte) (res XPrv) {
+	h := hmac.New(sha512.New, xprv[32:])
+	h.Write([]byte{'H'})
+	h.Write(xprv[:32])
+	h.Write(sel)
+	h.Sum(res[:0])
+	pruneRootScalar(res[:32])
+	return
+}
+
+func (xprv XPrv) nonhardenedChild(sel []byte) (res XPrv) {
+	xpub := xprv.XPub()
+
+	h := hmac.New(sha512.New, xpub[32:])
+	h.Write([]byte{'N'})
+	h.Write(xpub[:32])
+	h.Write(sel)
+	h.Sum(res[:0])
+
+	pruneIntermediateScalar(res[:32])
+
+	// The child scalar is the sum of the parent scalar and the derived
+	// factor, added as little-endian integers without reduction.
+	var carry int
+	for i := 0; i < 32; i++ {
+		sum := int(xprv[i]) + int(res[i]) + carry
+		res[i] = byte(sum & 0xff)
+		carry = sum >> 8
+	}
+	return
+}
+
+// Child derives a child xpub based on `selector` string.
+// The corresponding child xprv can be derived from the parent xprv
+// using non-hardened derivation: `parentxprv.Child(sel, false)`.
+func (xpub XPub) Child(sel []byte) (res XPub) {
+	h := hmac.New(sha512.New, xpub[32:])
+	h.Write([]byte{'N'})
+	h.Write(xpub[:32])
+	h.Write(sel)
+	h.Sum(res[:0])
+
+	pruneIntermediateScalar(res[:32])
+
+	var (
+		f ecmath.Scalar
+		F ecmath.Point
+	)
+	copy(f[:], res[:32])
+	F.ScMulBase(&f)
+
+	var (
+		pubkey [32]byte
+		P      ecmath.Point
+	)
+	copy(pubkey[:], xpub[:32])
+	if _, ok := P.Decode(pubkey); !ok {
+		panic("XPub should have been validated on initialization")
+	}
+
+	P.Add(&P, &F)
+	pubkey = P.Encode()
+	copy(res[:32], pubkey[:])
+	return
+}
+
+// Derive generates a child xprv by recursively deriving
+// non-hardened child xprvs over the list of selectors:
+// `Derive([a,b,c,...]) == Child(a).Child(b).Child(c)...`
+func (xprv XPrv) Derive(path [][]byte) XPrv {
+	res := xprv
+	for _, p := range path {
+		res = res.Child(p, false)
+	}
+	return res
+}
+
+// Derive generates a child xpub by recursively deriving
+// non-hardened child xpubs over the list of selectors:
+// `Derive([a,b,c,...]) == Child(a).Child(b).Child(c)...`
+func (xpub XPub) Derive(path [][]byte) XPub {
+	res := xpub
+	for _, p := range path {
+		res = res.Child(p)
+	}
+	return res
+}
+
+// s must be >= 32 bytes long and gets rewritten in place.
+// Clearing the top 23 bits keeps the sum of a scalar and 2^20 derived
+// factors from overflowing into the bits pruneRootScalar reserves.
+func pruneIntermediateScalar(f []byte) {
+	f[0] &= 248 // clear bottom 3 bits
+	f[29] &= 1  // clear 7 high bits
+	f[30] = 0   // clear 8 bits
+	f[31] = 0   // clear 8 bits
+}
+
 // PublicKey extracts the ed25519 public key from an xpub.
 func (xpub XPub) PublicKey() ed25519.PublicKey {
 	return ed25519.PublicKey(xpub[:32])
 }
 
+// Verify checks a signature made by the xprv of xpub.
+func (xpub XPub) Verify(msg []byte, sig []byte) bool {
+	return ed25519.Verify(xpub.PublicKey(), msg, sig)
+}
+
 func (xprv XPrv)Sign(msg []byte) []byte {
 	return Ed25519InnerSign(xprv.ExpandedPrivateKey(), msg)
 	//return nil
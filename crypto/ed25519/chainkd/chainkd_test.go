@@ -0,0 +1,168 @@
+package chainkd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+var testRoot = RootXPrv([]byte("chainkd test seed"))
+
+func TestVectors(t *testing.T) {
+	const rootXPub = "c11cc6e8fbad182cc1388c9d81e4ce6fb07b09c91f5368de1382a18164134d0822ff10cc40083637683e8ae365787cbfa819c673a7820c0cd99df2f1dae5a8e9"
+	if got := testRoot.XPub().String(); got != rootXPub {
+		t.Fatalf("root xpub = %s, want %s", got, rootXPub)
+	}
+
+	tests := []struct {
+		path       string
+		xprv, xpub string
+	}{
+		{
+			"m/0",
+			"b86effea601d045f09e44487532633d097d44e318e45d5562576b75a1d109f53f2b1f0f27eaceef23f9f79334cdec9dcedb01d5efcdd5bbbfc41c5f8d9a40123",
+			"0ecb1a875d430abf37b4b530c8f9ffd9353003917573c0b57c650422b532ef44f2b1f0f27eaceef23f9f79334cdec9dcedb01d5efcdd5bbbfc41c5f8d9a40123",
+		},
+		{
+			"m/0'",
+			"7000175add51825a1b82b5dd12e80985ade38897e662eacf58e4a3ae7cd9df5fb1895fc2a738253f430b9d9cea9c889efe7e4e1ed4bb5a6e39b22d3de956bdb2",
+			"ec712ad50efe59a9b7c5600e4b0b136d18cc28dda1f8e529523e23a279eea1feb1895fc2a738253f430b9d9cea9c889efe7e4e1ed4bb5a6e39b22d3de956bdb2",
+		},
+		{
+			"m/1/2",
+			"208693a62664d15120f97cd6833f8486224f6991ffec428eb01ee60420119f53e9350ae2e8df89f58046ac3a18dce1b5e3e3c8ca2dd33639bf0bf668e065c777",
+			"63504069bf95f0e191fff1e7d76da9b00f9407eae5b5fca28872f3451c9ccf95e9350ae2e8df89f58046ac3a18dce1b5e3e3c8ca2dd33639bf0bf668e065c777",
+		},
+		{
+			"m/44'/0'/7",
+			"4037fca59233fd2ff5cb7753679167325ee7935faf1274990b3f7cb1e18c0145d3b0b6496c1c0851e3bb168439f6d1b67eb13320e547b53cf995a1cb10b5281e",
+			"9c3c46398804ff57587b65a78bbd7f9e130f916bc6a90dc50f80bd36e743994fd3b0b6496c1c0851e3bb168439f6d1b67eb13320e547b53cf995a1cb10b5281e",
+		},
+		{
+			"m/0/1/2/3/4",
+			"706a9bc37960abd28bb5f088eee382a5ab59e39930aacbc84eac29a9fe159f53178a6f4d13ba75f54b28faad8b60cc531620cb27b1ca1b66d7495d608bf3ab17",
+			"7948fe63caf62bb8dfc8764612a766d21aa22aaea6b66ffea2daeb0683fe48e8178a6f4d13ba75f54b28faad8b60cc531620cb27b1ca1b66d7495d608bf3ab17",
+		},
+	}
+	for _, test := range tests {
+		path, err := ParsePath(test.path)
+		if err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		if path.String() != test.path {
+			t.Errorf("%s: path prints as %s", test.path, path)
+		}
+		xprv := testRoot.DerivePath(path)
+		if got := hex.EncodeToString(xprv[:]); got != test.xprv {
+			t.Errorf("%s: xprv = %s, want %s", test.path, got, test.xprv)
+		}
+		if got := xprv.XPub().String(); got != test.xpub {
+			t.Errorf("%s: xpub = %s, want %s", test.path, got, test.xpub)
+		}
+	}
+}
+
+// TestPublishedVectors checks the keys of the chainkd test vectors published
+// with Chain and Bytom, so that keys stay compatible with theirs.
+func TestPublishedVectors(t *testing.T) {
+	root := RootXPrv([]byte{0x01, 0x02, 0x03})
+	sel := []byte{0x01, 0x02, 0x03}
+	rootXPub := root.XPub()
+	hardChild, child := root.Child(sel, true), root.Child(sel, false)
+	childXPub := rootXPub.Child(sel)
+
+	tests := []struct {
+		name string
+		key  []byte
+		want string
+	}{
+		{"root xprv", root[:], "50f8c532ce6f088de65c2c1fbc27b491509373fab356eba300dfa7cc587b07483bc9e0d93228549c6888d3f68ad664b92c38f5ea8ca07181c1410949c02d3146"},
+		{"root xpub", rootXPub[:], "e11f321ffef364d01c2df2389e61091b15dab2e8eee87cb4c053fa65ed2812993bc9e0d93228549c6888d3f68ad664b92c38f5ea8ca07181c1410949c02d3146"},
+		{"hardened child xprv", hardChild[:], "6023c8e7633a9353a59bd930ea6dc397e400b1088b86b4a15d8de8567554df5574274bc1a0bd93b4494cb68e45c5ec5aefc1eed4d0c3bfd53b0b4e679ce52028"},
+		{"non-hardened child xprv", child[:], "705afd25a0e242b7333105d77cbb0ec15e667154916bbed5084c355dba7b0748b0faca523928f42e685ee6deb0cb3d41a09617783c87e9a161a04f2207ad4d2f"},
+		{"non-hardened child xpub", childXPub[:], "c0bbd87142e7bf90abfbb3d0cccc210c6d7eb3f912c35f205302c86ae9ef6eefb0faca523928f42e685ee6deb0cb3d41a09617783c87e9a161a04f2207ad4d2f"},
+	}
+	for _, test := range tests {
+		if got := hex.EncodeToString(test.key); got != test.want {
+			t.Errorf("%s = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestChildXPub(t *testing.T) {
+	msg := []byte("derived keys sign")
+	xprv, xpub := testRoot, testRoot.XPub()
+	for i := uint64(0); i < 20; i++ {
+		sel := IndexSelector(i)
+		xprv, xpub = xprv.Child(sel, false), xpub.Child(sel)
+		if xprv.XPub() != xpub {
+			t.Fatalf("step %d: xpub child %s differs from xprv child %s", i, xpub, xprv.XPub())
+		}
+		if !xpub.Verify(msg, xprv.Sign(msg)) {
+			t.Fatalf("step %d: signature of derived key does not verify", i)
+		}
+	}
+
+	path := IndexPath(3, 1, 4)
+	pub, err := testRoot.XPub().DerivePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub != testRoot.DerivePath(path).XPub() {
+		t.Error("xpub path derivation differs from xprv path derivation")
+	}
+}
+
+func TestHardenedChild(t *testing.T) {
+	sel := IndexSelector(0)
+	hardened, normal := testRoot.Child(sel, true), testRoot.Child(sel, false)
+	if hardened == normal {
+		t.Fatal("hardened and non-hardened children are equal")
+	}
+	if hardened.XPub() == testRoot.XPub().Child(sel) {
+		t.Error("hardened child derivable from the xpub")
+	}
+	if hardened != testRoot.Child(sel, true) {
+		t.Error("hardened derivation is not deterministic")
+	}
+
+	path, _ := ParsePath("m/0/1'")
+	if _, err := testRoot.XPub().DerivePath(path); err != ErrHardenedFromXPub {
+		t.Errorf("hardened xpub derivation: err = %v, want %v", err, ErrHardenedFromXPub)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for _, s := range []string{"", "0/1", "m/", "m/x", "m/1''", "m/-1"} {
+		if _, err := ParsePath(s); err == nil {
+			t.Errorf("ParsePath(%q) succeeded", s)
+		}
+	}
+	path, err := ParsePath("m/7h/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 2 || !path[0].Hardened || path[1].Hardened || path.String() != "m/7'/8" {
+		t.Errorf("m/7h/8 parsed as %s", path)
+	}
+	if root, err := ParsePath("m"); err != nil || len(root) != 0 {
+		t.Errorf("m parsed as %v, %v", root, err)
+	}
+}
+
+func TestXPubUnmarshal(t *testing.T) {
+	text, _ := testRoot.XPub().MarshalText()
+	var xpub XPub
+	if err := xpub.UnmarshalText(text); err != nil || xpub != testRoot.XPub() {
+		t.Fatalf("round trip: %v", err)
+	}
+
+	// y = 2 has no matching x on the curve.
+	bad := make([]byte, 64)
+	bad[0] = 2
+	if err := xpub.UnmarshalText([]byte(hex.EncodeToString(bad))); err != ErrBadKeyPoint {
+		t.Errorf("invalid point: err = %v, want %v", err, ErrBadKeyPoint)
+	}
+	if err := xpub.UnmarshalText(text[2:]); err != ErrBadKeyStr {
+		t.Errorf("short key: err = %v, want %v", err, ErrBadKeyStr)
+	}
+}
@@ -0,0 +1,116 @@
+package chainkd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadPath is returned when the text form of a derivation path can't
+	// be parsed.
+	ErrBadPath = errors.New("bad derivation path")
+
+	// ErrHardenedFromXPub is returned when a hardened child is requested
+	// from an xpub, which only the xprv can derive.
+	ErrHardenedFromXPub = errors.New("hardened child can't be derived from an xpub")
+)
+
+// PathStep is one derivation step, selecting a child of the key the previous
+// steps derived.
+type PathStep struct {
+	Selector []byte
+	Hardened bool
+}
+
+// Path is a sequence of derivation steps leading from a root key to one of
+// its descendants.
+type Path []PathStep
+
+// IndexSelector returns the selector of the child at index i: i encoded as
+// 8 little-endian bytes.
+func IndexSelector(i uint64) []byte {
+	var sel [8]byte
+	binary.LittleEndian.PutUint64(sel[:], i)
+	return sel[:]
+}
+
+// IndexPath returns the path of non-hardened index selectors.
+func IndexPath(indexes ...uint64) Path {
+	path := make(Path, len(indexes))
+	for i, index := range indexes {
+		path[i] = PathStep{Selector: IndexSelector(index)}
+	}
+	return path
+}
+
+// ParsePath parses the text form of a path made of index selectors, like
+// "m/44'/0/7". A trailing ' or h marks a hardened step.
+func ParsePath(s string) (Path, error) {
+	parts := strings.Split(s, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%v: %q does not start at m", ErrBadPath, s)
+	}
+	path := make(Path, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: step %q of %q", ErrBadPath, part, s)
+		}
+		path = append(path, PathStep{Selector: IndexSelector(index), Hardened: hardened})
+	}
+	return path, nil
+}
+
+// String returns the text form of p. Steps whose selector is not an index
+// selector are shown as hex.
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, step := range p {
+		if len(step.Selector) == 8 {
+			fmt.Fprintf(&b, "/%d", binary.LittleEndian.Uint64(step.Selector))
+		} else {
+			fmt.Fprintf(&b, "/0x%x", step.Selector)
+		}
+		if step.Hardened {
+			b.WriteByte('\'')
+		}
+	}
+	return b.String()
+}
+
+// Selectors returns the selectors of the steps of p.
+func (p Path) Selectors() [][]byte {
+	sels := make([][]byte, len(p))
+	for i, step := range p {
+		sels[i] = step.Selector
+	}
+	return sels
+}
+
+// DerivePath derives the descendant of xprv at path.
+func (xprv XPrv) DerivePath(path Path) XPrv {
+	res := xprv
+	for _, step := range path {
+		res = res.Child(step.Selector, step.Hardened)
+	}
+	return res
+}
+
+// DerivePath derives the descendant of xpub at path, which must not contain
+// hardened steps.
+func (xpub XPub) DerivePath(path Path) (XPub, error) {
+	for _, step := range path {
+		if step.Hardened {
+			return XPub{}, ErrHardenedFromXPub
+		}
+	}
+	return xpub.Derive(path.Selectors()), nil
+}
@@ -0,0 +1,85 @@
+package chainkd
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/srchain/srcd/crypto/ed25519/ecmath"
+)
+
+const extendedKeySize = 64
+
+var (
+	// ErrBadKeyStr is returned when the text form of an extended key has
+	// the wrong length.
+	ErrBadKeyStr = errors.New("bad key string")
+
+	// ErrBadKeyPoint is returned when an xpub does not hold a valid curve
+	// point, so no children can be derived from it.
+	ErrBadKeyPoint = errors.New("xpub is not a valid curve point")
+)
+
+// MarshalText satisfies the TextMarshaler interface.
+func (xpub XPub) MarshalText() ([]byte, error) {
+	hexBytes := make([]byte, hex.EncodedLen(len(xpub)))
+	hex.Encode(hexBytes, xpub[:])
+	return hexBytes, nil
+}
+
+// UnmarshalText satisfies the TextUnmarshaler interface.
+func (xpub *XPub) UnmarshalText(inp []byte) error {
+	if len(inp) != 2*extendedKeySize {
+		return ErrBadKeyStr
+	}
+	var res XPub
+	if _, err := hex.Decode(res[:], inp); err != nil {
+		return err
+	}
+	if !res.valid() {
+		return ErrBadKeyPoint
+	}
+	*xpub = res
+	return nil
+}
+
+// valid reports whether the public key of xpub is a curve point.
+func (xpub XPub) valid() bool {
+	var (
+		pubkey [32]byte
+		P      ecmath.Point
+	)
+	copy(pubkey[:], xpub[:32])
+	_, ok := P.Decode(pubkey)
+	return ok
+}
+
+// String returns the hex form of xpub.
+func (xpub XPub) String() string {
+	return hex.EncodeToString(xpub[:])
+}
+
+// Bytes returns the byte form of xpub.
+func (xpub XPub) Bytes() []byte {
+	return xpub[:]
+}
+
+// MarshalText satisfies the TextMarshaler interface.
+func (xprv XPrv) MarshalText() ([]byte, error) {
+	hexBytes := make([]byte, hex.EncodedLen(len(xprv)))
+	hex.Encode(hexBytes, xprv[:])
+	return hexBytes, nil
+}
+
+// UnmarshalText satisfies the TextUnmarshaler interface.
+func (xprv *XPrv) UnmarshalText(inp []byte) error {
+	if len(inp) != 2*extendedKeySize {
+		return ErrBadKeyStr
+	}
+	_, err := hex.Decode(xprv[:], inp)
+	return err
+}
+
+// Bytes returns the byte form of xprv.
+func (xprv XPrv) Bytes() []byte {
+	return xprv[:]
+}
@@ -1,11 +1,15 @@
 package database
 
 import (
-	"sync"
 	"errors"
+	"strings"
+	"sync"
 
 	"github.com/srchain/srcd/common/common"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // This is a test memory database. Do not use for any production it does not get persisted
@@ -76,8 +80,19 @@ func (db *MemDatabase) Close() {}
 func (db *MemDatabase) NewBatch() Batch {
 	return &memBatch{db: db}
 }
+// NewIteratorWithPrefix returns an iterator over a snapshot of the entries
+// whose keys start with prefix, in key order.
 func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
-	return iterator.NewEmptyIterator(nil)
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	snap := memdb.New(comparer.DefaultComparer, 0)
+	for key, value := range db.db {
+		if strings.HasPrefix(key, string(prefix)) {
+			snap.Put([]byte(key), value)
+		}
+	}
+	return snap.NewIterator(util.BytesPrefix(prefix))
 }
 func (db *MemDatabase) Len() int { return len(db.db) }
 
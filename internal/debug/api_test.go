@@ -0,0 +1,75 @@
+package debug
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := new(HandlerT)
+	cpu := filepath.Join(dir, "cpu.prof")
+	if err := h.StartCPUProfile(cpu); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.StartCPUProfile(cpu); err == nil {
+		t.Error("started a second CPU profile")
+	}
+	if err := h.StopCPUProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.StopCPUProfile(); err == nil {
+		t.Error("stopped a CPU profile that was not running")
+	}
+
+	trace := filepath.Join(dir, "trace.out")
+	if err := h.StartGoTrace(trace); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.StopGoTrace(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"heap", "block", "mutex"} {
+		if err := writeProfile(name, filepath.Join(dir, name+".prof")); err != nil {
+			t.Errorf("%s profile: %v", name, err)
+		}
+	}
+	for _, file := range []string{"cpu.prof", "trace.out", "heap.prof", "block.prof", "mutex.prof"} {
+		if info, err := os.Stat(filepath.Join(dir, file)); err != nil || info.Size() == 0 {
+			t.Errorf("%s not written: %v", file, err)
+		}
+	}
+}
+
+func TestLogSettings(t *testing.T) {
+	h := new(HandlerT)
+	if err := h.Vmodule("p2p/*=5,server=4"); err != nil {
+		t.Errorf("valid vmodule rejected: %v", err)
+	}
+	if err := h.Vmodule("p2p"); err == nil {
+		t.Error("invalid vmodule accepted")
+	}
+	if err := h.BacktraceAt("server.go"); err == nil {
+		t.Error("invalid backtrace location accepted")
+	}
+	if err := h.BacktraceAt(""); err != nil {
+		t.Errorf("clearing backtrace failed: %v", err)
+	}
+	h.Verbosity(3)
+
+	if stacks := h.Stacks(); !strings.Contains(stacks, "TestLogSettings") {
+		t.Error("goroutine dump misses the running test")
+	}
+	if h.MemStats().HeapAlloc == 0 {
+		t.Error("memory statistics empty")
+	}
+}
@@ -0,0 +1,45 @@
+package debug
+
+import (
+	"errors"
+	"os"
+	"runtime/trace"
+
+	"github.com/srchain/srcd/log"
+)
+
+// StartGoTrace turns on tracing, writing to the given file.
+func (h *HandlerT) StartGoTrace(file string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.traceW != nil {
+		return errors.New("trace already in progress")
+	}
+	f, err := os.Create(expandHome(file))
+	if err != nil {
+		return err
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return err
+	}
+	h.traceW = f
+	h.traceFile = file
+	log.Info("Go tracing started", "dump", h.traceFile)
+	return nil
+}
+
+// StopGoTrace stops an ongoing trace.
+func (h *HandlerT) StopGoTrace() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	trace.Stop()
+	if h.traceW == nil {
+		return errors.New("trace not in progress")
+	}
+	log.Info("Done writing Go trace", "dump", h.traceFile)
+	h.traceW.Close()
+	h.traceW = nil
+	h.traceFile = ""
+	return nil
+}
@@ -126,8 +126,17 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 // number holding a logging statement, a stack trace will be written to the Info
 // log whenever execution hits that statement.
 //
-// Unlike with Vmodule, the ".go" must be present.
+// Unlike with Vmodule, the ".go" must be present. An empty location turns
+// backtraces off.
 func (h *GlogHandler) BacktraceAt(location string) error {
+	if location == "" {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+
+		h.location = ""
+		atomic.StoreUint32(&h.backtrace, 0)
+		return nil
+	}
 	// Ensure the backtrace location contains two non-empty elements
 	parts := strings.Split(location, ":")
 	if len(parts) != 2 {
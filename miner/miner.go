@@ -2,7 +2,7 @@ package miner
 
 import (
 	"fmt"
-	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
 	"sync/atomic"
 
 	"github.com/srchain/srcd/common/common"
@@ -15,7 +15,7 @@ import (
 // Backend wraps all methods required for mining.
 type Backend interface {
 	BlockChain() *blockchain.BlockChain
-	TxPool()     *transaction.TxPool
+	TxPool()     *txpool.TxPool
 }
 
 // Miner creates blocks and searches for proof-of-work values.
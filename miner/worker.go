@@ -10,6 +10,7 @@ import (
 	"github.com/srchain/srcd/consensus"
 	"github.com/srchain/srcd/core"
 	"github.com/srchain/srcd/core/blockchain"
+	"github.com/srchain/srcd/core/transaction"
 	"github.com/srchain/srcd/core/types"
 	"github.com/srchain/srcd/event"
 	"github.com/srchain/srcd/log"
@@ -32,6 +33,10 @@ const (
 	// blockRecommitInterval is the time interval to recreate the mining block with
 	// any newly arrived transactions.
 	blockRecommitInterval = 5 * time.Second
+
+	// coinbaseWeight is the block weight kept free for the coinbase transaction
+	// when filling a block with pool transactions.
+	coinbaseWeight = 4000
 )
 
 // environment is the worker's current environment and holds all of the current state information.
@@ -389,17 +394,12 @@ func (w *worker) commitNewWork(interrupt *int32) {
 	}
 	w.makeCurrent(header)
 
-	// Fill the block with all available pending transactions.
-	pending, err := w.server.TxPool().Pending()
-	if err != nil {
-		log.Error("Failed to fetch pending transactions", "err", err)
-		return
+	// Fill the block with the best paying pending transactions that fit.
+	msgs := w.server.TxPool().SelectTransactions(transaction.MaxBlockWeight - coinbaseWeight)
+	pending := make(types.Transactions, 0, len(msgs))
+	for _, msg := range msgs {
+		pending = append(pending, &types.Transaction{Tx: msg.Tx.TxData})
 	}
-	// Short circuit if there is no available pending transactions
-	// if len(pending) == 0 {
-		// return
-	// }
-	// txs := types.NewTransactionsByPriceAndNonce(w.current.signer, pending)
 	if w.commitTransactions(pending) {
 		return
 	}
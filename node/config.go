@@ -2,17 +2,23 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/srchain/srcd/accounts/keystore"
 	"github.com/srchain/srcd/common/common"
 	"github.com/srchain/srcd/crypto/crypto"
+	"github.com/srchain/srcd/database"
 	"github.com/srchain/srcd/log"
 	"github.com/srchain/srcd/p2p"
 	"github.com/srchain/srcd/p2p/discover"
+	"github.com/srchain/srcd/rpc"
 )
 
 const (
@@ -21,6 +27,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirAccountDatabase = "walletdb"           // Path within the datadir to store the account records
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -118,10 +125,96 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCJWTSecret is the path of a file holding the hex encoded secret HTTP and
+	// websocket callers sign their bearer tokens with. If neither it nor
+	// RPCBasicAuthFile is set, callers need no credentials.
+	RPCJWTSecret string `toml:",omitempty"`
+
+	// RPCBasicAuthFile is the path of a file of "user:password" lines accepted
+	// through HTTP basic auth.
+	RPCBasicAuthFile string `toml:",omitempty"`
+
+	// RPCPublicModules is the list of API modules callers without credentials
+	// may use once credentials are configured.
+	RPCPublicModules []string `toml:",omitempty"`
+
+	// RPCRateLimit is the number of calls per second each client IP may make
+	// over HTTP and websocket, in bursts of up to RPCRateBurst. Zero disables
+	// rate limiting.
+	RPCRateLimit float64 `toml:",omitempty"`
+	RPCRateBurst int     `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	// Logger log.Logger `toml:",omitempty"`
 }
 
+// IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
+// account the set data folders as well as the designated platform we're currently
+// running on.
+func (c *Config) IPCEndpoint() string {
+	// Short circuit if IPC has not been enabled
+	if c.IPCPath == "" {
+		return ""
+	}
+	// On windows we can only use plain top-level pipes
+	if runtime.GOOS == "windows" {
+		if strings.HasPrefix(c.IPCPath, `\\.\pipe\`) {
+			return c.IPCPath
+		}
+		return `\\.\pipe\` + c.IPCPath
+	}
+	// Resolve names into the data directory full paths otherwise
+	if filepath.Base(c.IPCPath) == c.IPCPath {
+		if c.DataDir == "" {
+			return filepath.Join(os.TempDir(), c.IPCPath)
+		}
+		return filepath.Join(c.DataDir, c.IPCPath)
+	}
+	return c.IPCPath
+}
+
+// DefaultIPCEndpoint returns the IPC path used by default.
+func DefaultIPCEndpoint(clientIdentifier string) string {
+	if clientIdentifier == "" {
+		clientIdentifier = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+		if clientIdentifier == "" {
+			panic("empty executable name")
+		}
+	}
+	config := &Config{DataDir: DefaultDataDir(), IPCPath: clientIdentifier + ".ipc"}
+	return config.IPCEndpoint()
+}
+
+// HTTPEndpoint resolves an HTTP endpoint based on the configured host interface
+// and port parameters.
+func (c *Config) HTTPEndpoint() string {
+	if c.HTTPHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.HTTPHost, c.HTTPPort)
+}
+
+// DefaultHTTPEndpoint returns the HTTP endpoint used by default.
+func DefaultHTTPEndpoint() string {
+	config := &Config{HTTPHost: DefaultHTTPHost, HTTPPort: DefaultHTTPPort}
+	return config.HTTPEndpoint()
+}
+
+// WSEndpoint resolves a websocket endpoint based on the configured host interface
+// and port parameters.
+func (c *Config) WSEndpoint() string {
+	if c.WSHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
+}
+
+// DefaultWSEndpoint returns the websocket endpoint used by default.
+func DefaultWSEndpoint() string {
+	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
+	return config.WSEndpoint()
+}
+
 // NodeDB returns the path to the discovery node database.
 func (c *Config) NodeDB() string {
 	if c.DataDir == "" {
@@ -130,6 +223,21 @@ func (c *Config) NodeDB() string {
 	return c.resolvePath(datadirNodeDatabase)
 }
 
+// OpenDatabase opens the database with the given name from within the
+// instance directory, or a memory database if the node is ephemeral. It lets
+// commands reach the databases of a node without creating the node.
+func (c *Config) OpenDatabase(name string, cache, handles int) (database.Database, error) {
+	if c.DataDir == "" {
+		return database.NewMemDatabase(), nil
+	}
+	return database.NewLDBDatabase(c.resolvePath(name), cache, handles)
+}
+
+// OpenAccountDB opens the database recording the accounts of the node.
+func (c *Config) OpenAccountDB() (database.Database, error) {
+	return c.OpenDatabase(datadirAccountDatabase, 768, 1024)
+}
+
 // NodeName returns the devp2p node identifier.
 func (c *Config) NodeName() string {
 	name := c.name()
@@ -232,6 +340,53 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// RPCAccessPolicy builds the policy guarding the HTTP and websocket endpoints,
+// loading the configured credential files. It returns nil if the endpoints
+// are unrestricted.
+func (c *Config) RPCAccessPolicy() (*rpc.AccessPolicy, error) {
+	if c.RPCJWTSecret == "" && c.RPCBasicAuthFile == "" && c.RPCRateLimit <= 0 {
+		return nil, nil
+	}
+	policy := &rpc.AccessPolicy{
+		PublicModules: c.RPCPublicModules,
+		RateLimit:     c.RPCRateLimit,
+		RateBurst:     c.RPCRateBurst,
+	}
+	if c.RPCJWTSecret != "" {
+		data, err := ioutil.ReadFile(c.RPCJWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", c.RPCJWTSecret, err)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("JWT secret in %s is shorter than 32 bytes", c.RPCJWTSecret)
+		}
+		policy.JWTSecret = secret
+	}
+	if c.RPCBasicAuthFile != "" {
+		data, err := ioutil.ReadFile(c.RPCBasicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.BasicAuth = make(map[string]string)
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			sep := strings.IndexByte(line, ':')
+			if sep <= 0 {
+				return nil, fmt.Errorf("%s:%d: expected user:password", c.RPCBasicAuthFile, i+1)
+			}
+			policy.BasicAuth[line[:sep]] = line[sep+1:]
+		}
+	}
+	return policy, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*discover.Node {
 	return c.parsePersistentNodes(c.resolvePath(datadirStaticNodes))
@@ -274,53 +429,89 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	return nodes
 }
 
+// persistNode adds node to, or removes it from, the node list in the data
+// directory file path, so that it survives restarts. It does nothing if the
+// node has no data directory.
+func (c *Config) persistNode(path string, node *discover.Node, add bool) error {
+	if c.DataDir == "" {
+		return nil
+	}
+	path = c.resolvePath(path)
+
+	var nodelist []string
+	if _, err := os.Stat(path); err == nil {
+		if err := common.LoadJSON(path, &nodelist); err != nil {
+			return fmt.Errorf("can't load node file %s: %v", path, err)
+		}
+	}
+	// Drop any entry of the node, then append it again when adding.
+	url := node.String()
+	kept := nodelist[:0]
+	for _, entry := range nodelist {
+		if parsed, err := discover.ParseNode(entry); err == nil && parsed.ID == node.ID {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if add {
+		kept = append(kept, url)
+	}
+	if kept == nil {
+		kept = []string{}
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
 // AccountConfig determines the settings for scrypt and keydirectory
-//func (c *Config) AccountConfig() (int, int, string, error) {
-//	scryptN := keystore.StandardScryptN
-//	scryptP := keystore.StandardScryptP
-//
-//	var (
-//		keydir string
-//		err    error
-//	)
-//
-//	switch {
-//	case filepath.IsAbs(c.KeyStoreDir):
-//		keydir = c.KeyStoreDir
-//	case c.DataDir != "":
-//		if c.KeyStoreDir == "" {
-//			keydir = filepath.Join(c.DataDir, datadirDefaultKeyStore)
-//		} else {
-//			keydir, err = filepath.Abs(c.KeyStoreDir)
-//		}
-//	case c.KeyStoreDir != "":
-//		keydir, err = filepath.Abs(c.KeyStoreDir)
-//	}
-//
-//	return scryptN, scryptP, keydir, err
-//}
-
-
-//func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
-//	scryptN, scryptP, keydir, err := conf.AccountConfig()
-//	var ephemeral string
-//	if keydir == "" {
-//		// There is no datadir.
-//		keydir, err = ioutil.TempDir("", "srcd-keystore")
-//		ephemeral = keydir
-//	}
-//
-//	if err != nil {
-//		return nil, "", err
-//	}
-//	if err := os.MkdirAll(keydir, 0700); err != nil {
-//		return nil, "", err
-//	}
-//
-//	// Assemble the account manager and supported backends
-//	backends := []accounts.Backend{
-//		keystore.NewKeyStore(keydir, scryptN, scryptP),
-//	}
-//
-//	return accounts.NewManager(backends...), ephemeral, nil
-//}
+func (c *Config) AccountConfig() (int, int, string, error) {
+	scryptN := keystore.StandardScryptN
+	scryptP := keystore.StandardScryptP
+
+	var (
+		keydir string
+		err    error
+	)
+
+	switch {
+	case filepath.IsAbs(c.KeyStoreDir):
+		keydir = c.KeyStoreDir
+	case c.DataDir != "":
+		if c.KeyStoreDir == "" {
+			keydir = filepath.Join(c.DataDir, datadirDefaultKeyStore)
+		} else {
+			keydir, err = filepath.Abs(c.KeyStoreDir)
+		}
+	case c.KeyStoreDir != "":
+		keydir, err = filepath.Abs(c.KeyStoreDir)
+	}
+
+	return scryptN, scryptP, keydir, err
+}
+
+// makeKeyStore opens the keystore holding the keys of the wallet's accounts.
+// Without a key directory the keys go to a temporary one, which is returned
+// so the node can remove it when stopped.
+func makeKeyStore(conf *Config) (*keystore.XKeyStore, string, error) {
+	scryptN, scryptP, keydir, err := conf.AccountConfig()
+	var ephemeral string
+	if keydir == "" {
+		// There is no datadir.
+		keydir, err = ioutil.TempDir("", "srcd-keystore")
+		ephemeral = keydir
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(keydir, 0700); err != nil {
+		return nil, "", err
+	}
+	return keystore.NewXKeyStore(keydir, scryptN, scryptP), ephemeral, nil
+}
@@ -0,0 +1,55 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/srchain/srcd/p2p/discover"
+)
+
+const (
+	testNodeA = "enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:10101"
+	testNodeB = "enode://3f1d12044546b76342d59d4a05532c14b85aa669704bfe1f864fe079415aa2c02d743e03218e57a33fb94523adb54032871a6c51b2cc5514cb7c7e35b3ed0a99@13.93.211.84:10101"
+)
+
+// Tests that nodes added and removed at runtime end up in the node lists the
+// node loads on startup.
+func TestPersistNode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Name: "test", DataDir: dir}
+	a, _ := discover.ParseNode(testNodeA)
+	b, _ := discover.ParseNode(testNodeB)
+
+	for _, n := range []*discover.Node{a, b, a} {
+		if err := config.persistNode(datadirStaticNodes, n, true); err != nil {
+			t.Fatalf("failed to persist node: %v", err)
+		}
+	}
+	if err := config.persistNode(datadirTrustedNodes, b, true); err != nil {
+		t.Fatalf("failed to persist node: %v", err)
+	}
+	static := config.StaticNodes()
+	if len(static) != 2 || static[0].ID != b.ID || static[1].ID != a.ID {
+		t.Fatalf("static nodes mismatch: have %v, want [%v %v]", static, b, a)
+	}
+	if err := config.persistNode(datadirStaticNodes, b, false); err != nil {
+		t.Fatalf("failed to remove node: %v", err)
+	}
+	if static := config.StaticNodes(); len(static) != 1 || static[0].ID != a.ID {
+		t.Fatalf("static nodes mismatch after removal: have %v, want [%v]", static, a)
+	}
+	if trusted := config.TrustedNodes(); len(trusted) != 1 || trusted[0].ID != b.ID {
+		t.Fatalf("trusted nodes mismatch: have %v, want [%v]", trusted, b)
+	}
+
+	// Without a data directory nothing is persisted.
+	if err := new(Config).persistNode(datadirStaticNodes, a, true); err != nil {
+		t.Fatalf("failed to skip persisting: %v", err)
+	}
+}
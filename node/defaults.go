@@ -13,14 +13,19 @@ import (
 const (
 	DefaultHTTPHost = "localhost" // Default host interface for the HTTP RPC server
 	DefaultHTTPPort = 9090        // Default TCP port for the HTTP RPC server
+	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
+	DefaultWSPort   = 9091        // Default TCP port for the websocket RPC server
 )
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
 	DataDir:          DefaultDataDir(),
 	HTTPPort:         DefaultHTTPPort,
-	// HTTPModules:      []string{"net", "web3"},
-	// HTTPVirtualHosts: []string{"localhost"},
+	HTTPModules:      []string{"web3"},
+	HTTPVirtualHosts: []string{"localhost"},
+	WSPort:           DefaultWSPort,
+	WSModules:        []string{"web3"},
+	RPCPublicModules: []string{"chain", "web3"},
 	P2P: p2p.Config{
 		ListenAddr: ":10101",
 		MaxPeers:   25,
@@ -2,6 +2,8 @@ package node
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -9,11 +11,13 @@ import (
 	"sync"
 
 	"github.com/srchain/srcd/database"
+	"github.com/srchain/srcd/internal/debug"
 	"github.com/srchain/srcd/log"
 	"github.com/srchain/srcd/p2p"
+	"github.com/srchain/srcd/rpc"
 	"github.com/prometheus/prometheus/util/flock"
 	"github.com/srchain/srcd/account"
-	"fmt"
+	"github.com/srchain/srcd/accounts/keystore"
 )
 
 // Node is a container on which services can be registered.
@@ -21,8 +25,8 @@ type Node struct {
 	// eventmux *event.TypeMux		// Event multiplexer used between the services of a stack
 	config            *Config
 	accman            *account.AccountManager
-	//
-	//ephemeralKeystore string                   // if non-empty, the key directory that will be removed by Stop
+	keystore          *keystore.XKeyStore
+	ephemeralKeystore string                   // if non-empty, the key directory that will be removed by Stop
 	instanceDirLock   flock.Releaser           // prevents concurrent use of instance directory
 
 	serverConfig      p2p.Config
@@ -31,21 +35,21 @@ type Node struct {
 	serviceFuncs      []ServiceConstructor     // Service constructors (in dependency order)
 	services          map[reflect.Type]Service // Currently running services
 
-	// rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	// inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
+	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
-	// ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
-	// ipcListener net.Listener // IPC RPC listener socket to serve API requests
-	// ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
+	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
+	ipcListener net.Listener // IPC RPC listener socket to serve API requests
+	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
-	// httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	// httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	// httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	// httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
+	httpListener  net.Listener // HTTP RPC listener socket to server API requests
+	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
 
-	// wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
-	// wsListener net.Listener // Websocket RPC listener socket to server API requests
-	// wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
+	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
+	wsListener net.Listener // Websocket RPC listener socket to server API requests
+	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
@@ -81,20 +85,27 @@ func New(conf *Config) (*Node, error) {
 
 	// Ensure that the AccountManager method works before the node has started.
 	//am, ephemeralKeystore, err := makeAccountManaglser(conf)
-	db, err := database.NewLDBDatabase(DefaultDataDir() + "/srcd/walletdb", 768, 1024)
-	if err!=nil{
-		return nil,errors.New("walletdb create fail ")
+	db, err := conf.OpenAccountDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open account database: %v", err)
 	}
 	am := account.NewAccountManager(db)
+	ks, ephemeralKeystore, err := makeKeyStore(conf)
+	if err != nil {
+		return nil, err
+	}
+	am.SetKeyStore(ks)
 	return &Node{
 		accman:            am,
-		//ephemeralKeystore: ephemeralKeystore,
+		keystore:          ks,
+		ephemeralKeystore: ephemeralKeystore,
 		config:            conf,
 		serviceFuncs:      []ServiceConstructor{},
-		// ipcEndpoint:       conf.IPCEndpoint(),
-		// httpEndpoint:      conf.HTTPEndpoint(),
-		// wsEndpoint:        conf.WSEndpoint(),
+		ipcEndpoint:       conf.IPCEndpoint(),
+		httpEndpoint:      conf.HTTPEndpoint(),
+		wsEndpoint:        conf.WSEndpoint(),
 		// eventmux:          new(event.TypeMux),
+		log: log.New(),
 	}, nil
 }
 
@@ -149,7 +160,7 @@ func (n *Node) Start() error {
 		ctx := &ServiceContext{
 			config:         n.config,
 			services:       make(map[reflect.Type]Service),
-			//AccountManager: n.accman,
+			AccountManager: n.accman,
 		}
 		// copy needed for threaded access
 		for kind, s := range services {
@@ -169,8 +180,7 @@ func (n *Node) Start() error {
 
 	// Gather the protocols and start the freshly assembled P2P server
 	for _, service := range services {
-		fmt.Println(service)
-		running.Protocols = append(running.Protocols, running.Protocols...)
+		running.Protocols = append(running.Protocols, service.Protocols()...)
 	}
 	if err := running.Start(); err != nil {
 		return convertFileLockError(err)
@@ -192,9 +202,14 @@ func (n *Node) Start() error {
 		started = append(started, kind)
 	}
 
-
-	//TODO rpc to be start
-
+	// Lastly start the configured RPC interfaces
+	if err := n.startRPC(services); err != nil {
+		for _, service := range services {
+			service.Stop()
+		}
+		running.Stop()
+		return err
+	}
 
 	// Finish initializing the startup
 	n.services = services
@@ -204,6 +219,163 @@ func (n *Node) Start() error {
 	return nil
 }
 
+// startRPC is a helper method to start all the various RPC endpoint during node
+// startup. It's not meant to be called at any time afterwards as it makes certain
+// assumptions about the state of the node.
+func (n *Node) startRPC(services map[reflect.Type]Service) error {
+	// Gather all the possible APIs to surface
+	apis := n.apis()
+	for _, service := range services {
+		apis = append(apis, service.APIs()...)
+	}
+	// Start the various API endpoints, terminating all in case of errors
+	if err := n.startInProc(apis); err != nil {
+		return err
+	}
+	if err := n.startIPC(apis); err != nil {
+		n.stopInProc()
+		return err
+	}
+	policy, err := n.config.RPCAccessPolicy()
+	if err != nil {
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, policy); err != nil {
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, policy); err != nil {
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	// All API endpoints started successfully
+	n.rpcAPIs = apis
+	return nil
+}
+
+// startInProc initializes an in-process RPC endpoint.
+func (n *Node) startInProc(apis []rpc.API) error {
+	// Register all the APIs exposed by the services
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			return err
+		}
+		n.log.Debug("InProc registered", "namespace", api.Namespace)
+	}
+	n.inprocHandler = handler
+	return nil
+}
+
+// stopInProc terminates the in-process RPC endpoint.
+func (n *Node) stopInProc() {
+	if n.inprocHandler != nil {
+		n.inprocHandler.Stop()
+		n.inprocHandler = nil
+	}
+}
+
+// startIPC initializes and starts the IPC RPC endpoint.
+func (n *Node) startIPC(apis []rpc.API) error {
+	if n.ipcEndpoint == "" {
+		return nil // IPC disabled.
+	}
+	listener, handler, err := rpc.StartIPCEndpoint(n.ipcEndpoint, apis)
+	if err != nil {
+		return err
+	}
+	n.ipcListener = listener
+	n.ipcHandler = handler
+	n.log.Info("IPC endpoint opened", "url", n.ipcEndpoint)
+	return nil
+}
+
+// stopIPC terminates the IPC RPC endpoint.
+func (n *Node) stopIPC() {
+	if n.ipcListener != nil {
+		n.ipcListener.Close()
+		n.ipcListener = nil
+
+		n.log.Info("IPC endpoint closed", "endpoint", n.ipcEndpoint)
+	}
+	if n.ipcHandler != nil {
+		n.ipcHandler.Stop()
+		n.ipcHandler = nil
+	}
+}
+
+// startHTTP initializes and starts the HTTP RPC endpoint.
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, policy *rpc.AccessPolicy) error {
+	// Short circuit if the HTTP endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, policy)
+	if err != nil {
+		return err
+	}
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	// All listeners booted successfully
+	n.httpEndpoint = endpoint
+	n.httpWhitelist = modules
+	n.httpListener = listener
+	n.httpHandler = handler
+
+	return nil
+}
+
+// stopHTTP terminates the HTTP RPC endpoint.
+func (n *Node) stopHTTP() {
+	if n.httpListener != nil {
+		n.httpListener.Close()
+		n.httpListener = nil
+
+		n.log.Info("HTTP endpoint closed", "url", fmt.Sprintf("http://%s", n.httpEndpoint))
+	}
+	if n.httpHandler != nil {
+		n.httpHandler.Stop()
+		n.httpHandler = nil
+	}
+}
+
+// startWS initializes and starts the websocket RPC endpoint.
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, policy *rpc.AccessPolicy) error {
+	// Short circuit if the WS endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, policy)
+	if err != nil {
+		return err
+	}
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	// All listeners booted successfully
+	n.wsEndpoint = endpoint
+	n.wsListener = listener
+	n.wsHandler = handler
+
+	return nil
+}
+
+// stopWS terminates the websocket RPC endpoint.
+func (n *Node) stopWS() {
+	if n.wsListener != nil {
+		n.wsListener.Close()
+		n.wsListener = nil
+
+		n.log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("ws://%s", n.wsEndpoint))
+	}
+	if n.wsHandler != nil {
+		n.wsHandler.Stop()
+		n.wsHandler = nil
+	}
+}
+
 func (n *Node) openDataDir() error {
 	if n.config.DataDir == "" {
 		return nil
@@ -235,10 +407,10 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
-	// n.stopWS()
-	// n.stopHTTP()
-	// n.stopIPC()
-	// n.rpcAPIs = nil
+	n.stopWS()
+	n.stopHTTP()
+	n.stopIPC()
+	n.rpcAPIs = nil
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
 	}
@@ -248,6 +420,7 @@ func (n *Node) Stop() error {
 		}
 	}
 	n.server.Stop()
+	n.stopInProc()
 	n.services = nil
 	n.server = nil
 
@@ -264,9 +437,9 @@ func (n *Node) Stop() error {
 
 	// Remove the keystore if it was created ephemerally.
 	var keystoreErr error
-	//if n.ephemeralKeystore != "" {
-	//	keystoreErr = os.RemoveAll(n.ephemeralKeystore)
-	//}
+	if n.ephemeralKeystore != "" {
+		keystoreErr = os.RemoveAll(n.ephemeralKeystore)
+	}
 
 	if len(failure.Services) > 0 {
 		return failure
@@ -291,6 +464,16 @@ func (n *Node) Wait() {
 	<-stop
 }
 
+// Server retrieves the currently running P2P network layer. This method is meant
+// only to inspect fields of the currently running server, life cycle management
+// should be left to this Node entity.
+func (n *Node) Server() *p2p.Server {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.server
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (n *Node) Service(service interface{}) error {
 	n.lock.RLock()
@@ -309,11 +492,53 @@ func (n *Node) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Attach creates an RPC client attached to an in-process API handler.
+func (n *Node) Attach() (*rpc.Client, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	return rpc.DialInProc(n.inprocHandler), nil
+}
+
+// RPCHandler returns the in-process RPC request handler.
+func (n *Node) RPCHandler() (*rpc.Server, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.inprocHandler == nil {
+		return nil, ErrNodeStopped
+	}
+	return n.inprocHandler, nil
+}
+
+// IPCEndpoint retrieves the current IPC endpoint used by the protocol stack.
+func (n *Node) IPCEndpoint() string {
+	return n.ipcEndpoint
+}
+
+// HTTPEndpoint retrieves the current HTTP endpoint used by the protocol stack.
+func (n *Node) HTTPEndpoint() string {
+	return n.httpEndpoint
+}
+
+// WSEndpoint retrieves the current WS endpoint used by the protocol stack.
+func (n *Node) WSEndpoint() string {
+	return n.wsEndpoint
+}
+
 // AccountManager retrieves the account manager used by the protocol stack.
 func (n *Node) AccountManager() *account.AccountManager {
 	return n.accman
 }
 
+// KeyStore retrieves the keystore holding the keys of the node's accounts.
+func (n *Node) KeyStore() *keystore.XKeyStore {
+	return n.keystore
+}
+
 // OpenDatabase opens an existing database with the given name (or creates one if no
 // previous can be found) from within the node's instance directory. If the node is
 // ephemeral, a memory database is returned.
@@ -323,3 +548,28 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (database.Database,
 	}
 	return database.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
 }
+
+// apis returns the collection of RPC descriptors this node offers.
+func (n *Node) apis() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateAdminAPI(n),
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPublicAdminAPI(n),
+			Public:    true,
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   debug.Handler,
+		}, {
+			Namespace: "web3",
+			Version:   "1.0",
+			Service:   NewPublicWeb3API(n),
+			Public:    true,
+		},
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/srchain/srcd/database"
 	"github.com/srchain/srcd/p2p"
+	"github.com/srchain/srcd/rpc"
 
 	"github.com/srchain/srcd/account"
 
@@ -51,8 +52,9 @@ type Service interface {
 	// // Protocols retrieves the P2P protocols the service wishes to start.
 	// Protocols() []p2p.Protocol
 	Protocols() []p2p.Protocol
-	// // APIs retrieves the list of RPC descriptors the service provides
-	// APIs() []rpc.API
+	// APIs retrieves the list of RPC descriptors the service provides
+	APIs() []rpc.API
+
 	// Start is called after all services have been constructed and the networking
 	// layer was also initialized to spawn any goroutines required by the service.
 	// Start(server *p2p.Server) error
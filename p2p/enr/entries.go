@@ -6,7 +6,7 @@ import (
 	"io"
 	"net"
 
-	"github.com/srchain/srcd/crypto"
+	"github.com/srchain/srcd/crypto/crypto"
 	"github.com/srchain/srcd/rlp"
 )
 
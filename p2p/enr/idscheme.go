@@ -6,7 +6,7 @@ import (
 	"sync"
 
 	"github.com/srchain/srcd/common/math"
-	"github.com/srchain/srcd/crypto"
+	"github.com/srchain/srcd/crypto/crypto"
 	"github.com/srchain/srcd/crypto/sha3"
 	"github.com/srchain/srcd/rlp"
 )
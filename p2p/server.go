@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"crypto/ecdsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
@@ -15,8 +16,10 @@ import (
 	"github.com/srchain/srcd/log"
 	"github.com/srchain/srcd/p2p/discover"
 	"github.com/srchain/srcd/p2p/discv5"
+	"github.com/srchain/srcd/p2p/enr"
 	"github.com/srchain/srcd/p2p/nat"
 	"github.com/srchain/srcd/p2p/netutil"
+	"github.com/srchain/srcd/rlp"
 )
 
 const (
@@ -951,6 +954,7 @@ type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
 	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
 	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
+	ENR   string `json:"enr"`   // Ethereum Node Record of the host, signed with its key
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
@@ -975,6 +979,11 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
+	if record, err := srv.nodeRecord(node); err == nil {
+		info.ENR = record
+	} else {
+		log.Debug("Failed to sign node record", "err", err)
+	}
 
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
@@ -989,6 +998,30 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	return info
 }
 
+// nodeRecord returns the textual form of the signed node record describing
+// the endpoints of node.
+func (srv *Server) nodeRecord(node *discover.Node) (string, error) {
+	if srv.PrivateKey == nil {
+		return "", errServerStopped
+	}
+	var r enr.Record
+	if ip := node.IP.To4(); ip != nil {
+		r.Set(enr.IP(ip))
+	} else if node.IP != nil {
+		r.Set(enr.IP(node.IP))
+	}
+	r.Set(enr.UDP(node.UDP))
+	r.Set(enr.TCP(node.TCP))
+	if err := enr.SignV4(&r, srv.PrivateKey); err != nil {
+		return "", err
+	}
+	raw, err := rlp.EncodeToBytes(&r)
+	if err != nil {
+		return "", err
+	}
+	return "enr:" + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
 // PeersInfo returns an array of metadata objects describing connected peers.
 func (srv *Server) PeersInfo() []*PeerInfo {
 	// Gather all the generic and sub-protocol specific infos
@@ -71,17 +71,48 @@ func (err *ConfigCompatError) Error() string {
 
 type NetParams struct {
 	// Name defines a human-readable identifier for the network.
-	Name            string
+	Name string
+	// NetworkID is the network ID of the nodes of the network.
+	NetworkID       uint64
 	Bech32HRPSegwit string
+	// LegacyBech32HRPSegwit is the prefix addresses of the network were
+	// encoded with before it got its own. They are still decoded, so that
+	// the addresses handed out and recorded then stay valid.
+	LegacyBech32HRPSegwit string
 }
 
-var TestNetParams  = NetParams{
-	Name:            "testnet",
+var MainNetParams = NetParams{
+	Name:            "mainnet",
+	NetworkID:       1,
 	Bech32HRPSegwit: "sr",
 }
 
+var TestNetParams = NetParams{
+	Name:                  "testnet",
+	NetworkID:             2,
+	Bech32HRPSegwit:       "tsr",
+	LegacyBech32HRPSegwit: "sr",
+}
+
+// ActiveNetParams are the parameters of the network the node runs on, which
+// addresses are encoded for and decoded with. They follow the network ID of
+// the node's configuration, see NetParamsForNetwork.
+var ActiveNetParams = &MainNetParams
+
+// NetParamsForNetwork returns the parameters of the network whose nodes use
+// networkID. Every network but the main one is a test network.
+func NetParamsForNetwork(networkID uint64) *NetParams {
+	if networkID == MainNetParams.NetworkID {
+		return &MainNetParams
+	}
+	return &TestNetParams
+}
+
 func IsBech32SegwitPrefix(prefix string, params NetParams) bool {
 	prefix = strings.ToLower(prefix)
+	if params.LegacyBech32HRPSegwit != "" && prefix == params.LegacyBech32HRPSegwit+"1" {
+		return true
+	}
 	return prefix == params.Bech32HRPSegwit+"1"
 }
 
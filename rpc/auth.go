@@ -0,0 +1,303 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwtClockSkew is how far in the future a token may claim to have been
+	// issued, to tolerate clocks that are slightly apart.
+	jwtClockSkew = time.Minute
+
+	// rateLimitSweepInterval is how often idle rate limit buckets are
+	// dropped.
+	rateLimitSweepInterval = time.Minute
+)
+
+var (
+	errBadCredentials = errors.New("malformed authorization header")
+	errBadToken       = errors.New("invalid bearer token")
+	errTokenExpired   = errors.New("bearer token expired")
+	errBadPassword    = errors.New("invalid user name or password")
+	errNoCredentials  = errors.New("server accepts no credentials")
+)
+
+// AccessPolicy decides which remote callers may use which modules of a
+// server, and how often. Calls over IPC and in-process connections are
+// never restricted.
+type AccessPolicy struct {
+	// JWTSecret, if set, lets callers authenticate with an HS256 bearer
+	// token signed with it. A token carrying a "modules" claim may only
+	// call the modules it lists.
+	JWTSecret []byte
+
+	// BasicAuth maps user names to the passwords they may authenticate
+	// with through HTTP basic auth.
+	BasicAuth map[string]string
+
+	// PublicModules lists the modules anyone may call. Once credentials of
+	// either kind are configured, the other modules are reserved to
+	// authenticated callers.
+	PublicModules []string
+
+	// RateLimit is how many calls per second each client IP may make,
+	// with bursts of up to RateBurst calls. Zero disables the limit.
+	RateLimit float64
+	RateBurst int
+
+	public  map[string]bool
+	limiter *rateLimiter
+	once    sync.Once
+}
+
+func (p *AccessPolicy) init() {
+	p.once.Do(func() {
+		p.public = make(map[string]bool)
+		for _, module := range p.PublicModules {
+			p.public[module] = true
+		}
+		p.public[MetadataApi] = true
+		if p.RateLimit > 0 {
+			p.limiter = newRateLimiter(p.RateLimit, p.RateBurst)
+		}
+	})
+}
+
+// requiresAuth reports whether any credentials are configured.
+func (p *AccessPolicy) requiresAuth() bool {
+	return len(p.JWTSecret) > 0 || len(p.BasicAuth) > 0
+}
+
+// callerKey is the context key under which a remote caller is stored.
+type callerKey struct{}
+
+// caller describes who made a remote call.
+type caller struct {
+	ip      string
+	authed  bool
+	user    string
+	modules map[string]bool // modules the credentials are limited to, nil for all
+	expires time.Time       // when the credentials expire, zero for never
+}
+
+// authenticate identifies the caller of r from its Authorization header.
+// Callers without the header are anonymous; callers presenting invalid
+// credentials are rejected.
+func (p *AccessPolicy) authenticate(r *http.Request) (*caller, error) {
+	c := &caller{ip: remoteIP(r)}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return c, nil
+	}
+	scheme, credentials := header, ""
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		scheme, credentials = header[:i], strings.TrimSpace(header[i+1:])
+	}
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		if len(p.JWTSecret) == 0 {
+			return nil, errNoCredentials
+		}
+		claims, err := verifyJWT(credentials, p.JWTSecret, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		c.authed, c.user = true, claims.Subject
+		if claims.Expires != nil {
+			c.expires = time.Unix(*claims.Expires, 0)
+		}
+		if claims.Modules != nil {
+			c.modules = make(map[string]bool)
+			for _, module := range claims.Modules {
+				c.modules[module] = true
+			}
+		}
+	case "basic":
+		if len(p.BasicAuth) == 0 {
+			return nil, errNoCredentials
+		}
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, errBadCredentials
+		}
+		want, known := p.BasicAuth[user]
+		if subtle.ConstantTimeCompare([]byte(password), []byte(want)) != 1 || !known {
+			return nil, errBadPassword
+		}
+		c.authed, c.user = true, user
+	default:
+		return nil, errBadCredentials
+	}
+	return c, nil
+}
+
+// authorize checks whether c may call a method of module right now.
+// Credentials are checked for expiry on every call, as connections outlive
+// them.
+func (p *AccessPolicy) authorize(c *caller, module string) DataError {
+	p.init()
+	now := time.Now()
+	if p.limiter != nil {
+		if ok, wait := p.limiter.allow(c.ip, now); !ok {
+			return &rateLimitedError{retryAfter: wait}
+		}
+	}
+	if module == "" || !p.requiresAuth() {
+		return nil
+	}
+	if c.authed && !c.expires.IsZero() && !now.Before(c.expires) {
+		return &unauthorizedError{module: module, reason: "credentials expired"}
+	}
+	if !c.authed {
+		if p.public[module] {
+			return nil
+		}
+		return &unauthorizedError{module: module, reason: "authentication required"}
+	}
+	if c.modules != nil && !c.modules[module] && module != MetadataApi {
+		return &unauthorizedError{module: module, reason: "module not granted to credentials"}
+	}
+	return nil
+}
+
+// remoteIP returns the IP address r was sent from.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// jwtClaims are the token claims the server looks at.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	IssuedAt  *int64   `json:"iat"`
+	NotBefore *int64   `json:"nbf"`
+	Expires   *int64   `json:"exp"`
+	Modules   []string `json:"modules"`
+}
+
+// verifyJWT checks that token is an HS256 JSON web token signed with secret
+// and valid at now, and returns its claims.
+func verifyJWT(token string, secret []byte, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errBadToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errBadToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errBadToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errBadToken
+	}
+
+	claims := new(jwtClaims)
+	if err := decodeJWTPart(parts[1], claims); err != nil {
+		return nil, errBadToken
+	}
+	unix := now.Unix()
+	if claims.Expires != nil && unix >= *claims.Expires {
+		return nil, errTokenExpired
+	}
+	if claims.NotBefore != nil && unix < *claims.NotBefore {
+		return nil, errBadToken
+	}
+	if claims.IssuedAt != nil && *claims.IssuedAt > now.Add(jwtClockSkew).Unix() {
+		return nil, errBadToken
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// rateLimiter keeps a token bucket per client IP.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens added per second
+	burst     float64 // bucket capacity
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token from the bucket of ip. If the bucket is empty it
+// returns false and how long until a token is available.
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitSweepInterval {
+		l.sweep(now)
+	}
+	b := l.buckets[ip]
+	if b == nil {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+	}
+	b.last = now
+}
+
+// sweep drops the buckets that have filled up again, since a fresh bucket
+// behaves the same.
+func (l *rateLimiter) sweep(now time.Time) {
+	for ip, b := range l.buckets {
+		if l.refill(b, now); b.tokens >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+	l.lastSweep = now
+}
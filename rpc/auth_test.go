@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func signJWT(secret []byte, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{signJWT(testSecret, map[string]interface{}{"iat": now.Unix()}), nil},
+		{signJWT(testSecret, map[string]interface{}{"exp": now.Unix() + 10}), nil},
+		{signJWT(testSecret, map[string]interface{}{"iat": now.Unix() + 30}), nil},
+		{signJWT(testSecret, map[string]interface{}{"exp": now.Unix()}), errTokenExpired},
+		{signJWT(testSecret, map[string]interface{}{"nbf": now.Unix() + 10}), errBadToken},
+		{signJWT(testSecret, map[string]interface{}{"iat": now.Unix() + 3600}), errBadToken},
+		{signJWT([]byte("another secret"), map[string]interface{}{}), errBadToken},
+		{"not.a.token", errBadToken},
+		{"", errBadToken},
+	}
+	for i, test := range tests {
+		if _, err := verifyJWT(test.token, testSecret, now); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+
+	claims, err := verifyJWT(signJWT(testSecret, map[string]interface{}{"sub": "ops", "modules": []string{"wallet"}}), testSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "ops" || len(claims.Modules) != 1 || claims.Modules[0] != "wallet" {
+		t.Errorf("claims mismatch: %+v", claims)
+	}
+}
+
+func TestAccessPolicyAuthorize(t *testing.T) {
+	policy := &AccessPolicy{
+		JWTSecret:     testSecret,
+		BasicAuth:     map[string]string{"alice": "hunter2"},
+		PublicModules: []string{"chain"},
+	}
+	request := func(auth string) *http.Request {
+		r := httptest.NewRequest("POST", "/", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		return r
+	}
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+	bearer := func(claims map[string]interface{}) string {
+		return "Bearer " + signJWT(testSecret, claims)
+	}
+
+	tests := []struct {
+		auth    string
+		module  string
+		authErr bool
+		allowed bool
+	}{
+		{"", "chain", false, true},
+		{"", MetadataApi, false, true},
+		{"", "wallet", false, false},
+		{basic("alice", "hunter2"), "wallet", false, true},
+		{basic("alice", "wrong"), "", true, false},
+		{basic("bob", "hunter2"), "", true, false},
+		{bearer(map[string]interface{}{}), "admin", false, true},
+		{bearer(map[string]interface{}{"modules": []string{"wallet"}}), "wallet", false, true},
+		{bearer(map[string]interface{}{"modules": []string{"wallet"}}), "admin", false, false},
+		{bearer(map[string]interface{}{"modules": []string{"wallet"}}), MetadataApi, false, true},
+		{"Bearer garbage", "", true, false},
+		{"Digest foo", "", true, false},
+	}
+	for i, test := range tests {
+		c, err := policy.authenticate(request(test.auth))
+		if (err != nil) != test.authErr {
+			t.Errorf("test %d: authentication error mismatch: have %v, want error %t", i, err, test.authErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		err2 := policy.authorize(c, test.module)
+		if allowed := err2 == nil; allowed != test.allowed {
+			t.Errorf("test %d: authorization mismatch: have %v, want allowed %t", i, err2, test.allowed)
+		}
+	}
+}
+
+func TestAccessPolicyExpiry(t *testing.T) {
+	policy := &AccessPolicy{JWTSecret: testSecret}
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signJWT(testSecret, map[string]interface{}{"exp": time.Now().Unix() + 60}))
+	c, err := policy.authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.authorize(c, "admin"); err != nil {
+		t.Fatalf("valid token refused: %v", err)
+	}
+	// A connection outlives the token it was opened with.
+	c.expires = time.Now().Add(-time.Second)
+	if err := policy.authorize(c, "admin"); err == nil {
+		t.Fatal("expired token allowed")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("1.2.3.4", now); !ok {
+			t.Fatalf("call %d of burst denied", i)
+		}
+	}
+	ok, wait := l.allow("1.2.3.4", now)
+	if ok {
+		t.Fatal("call beyond burst allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("retry delay mismatch: have %v, want %v", wait, 500*time.Millisecond)
+	}
+	if ok, _ := l.allow("5.6.7.8", now); !ok {
+		t.Error("other IP limited")
+	}
+	if ok, _ := l.allow("1.2.3.4", now.Add(wait)); !ok {
+		t.Error("call denied after refill")
+	}
+
+	// Idle buckets are dropped once full again.
+	l.allow("1.2.3.4", now.Add(2*rateLimitSweepInterval))
+	if len(l.buckets) != 1 {
+		t.Errorf("have %d buckets after sweep, want 1", len(l.buckets))
+	}
+}
+
+func TestHTTPAccessPolicy(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Stop()
+	server.SetAccessPolicy(&AccessPolicy{
+		BasicAuth:     map[string]string{"alice": "hunter2"},
+		PublicModules: []string{"other"},
+		RateLimit:     1,
+		RateBurst:     2,
+	})
+	ts := httptest.NewServer(NewHTTPServer(nil, []string{"*"}, server).Handler)
+	defer ts.Close()
+
+	call := func(user, password string) (int, *jsonErrResponse) {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1,{"S":"y"}]}`))
+		req.Header.Set("content-type", contentType)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res jsonErrResponse
+		json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, &res
+	}
+
+	if status, res := call("alice", "wrong"); status != http.StatusUnauthorized || res.Error.Code != -32001 {
+		t.Errorf("bad password: have status %d, error %+v", status, res.Error)
+	}
+	if _, res := call("", ""); res.Error.Code != -32001 {
+		t.Errorf("anonymous call: have error %+v, want code -32001", res.Error)
+	}
+	if _, res := call("alice", "hunter2"); res.Error.Code != 0 {
+		t.Errorf("authenticated call failed: %+v", res.Error)
+	}
+	if _, res := call("alice", "hunter2"); res.Error.Code != -32005 {
+		t.Errorf("call beyond burst: have error %+v, want code -32005", res.Error)
+	}
+}
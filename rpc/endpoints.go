@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"net"
+
+	"github.com/srchain/srcd/log"
+)
+
+// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules.
+// A non-nil policy restricts who may call which modules.
+func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, policy *AccessPolicy) (net.Listener, *Server, error) {
+	handler, err := newWhitelistServer(apis, modules, false, "HTTP")
+	if err != nil {
+		return nil, nil, err
+	}
+	if policy != nil {
+		handler.SetAccessPolicy(policy)
+	}
+	// All APIs registered, start the HTTP listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go NewHTTPServer(cors, vhosts, handler).Serve(listener)
+	return listener, handler, nil
+}
+
+// StartWSEndpoint starts a websocket endpoint. A non-nil policy restricts who
+// may call which modules.
+func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, policy *AccessPolicy) (net.Listener, *Server, error) {
+	handler, err := newWhitelistServer(apis, modules, exposeAll, "WebSocket")
+	if err != nil {
+		return nil, nil, err
+	}
+	if policy != nil {
+		handler.SetAccessPolicy(policy)
+	}
+	// All APIs registered, start the websocket listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go NewWSServer(wsOrigins, handler).Serve(listener)
+	return listener, handler, nil
+}
+
+// StartIPCEndpoint starts an IPC endpoint.
+func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, error) {
+	// Register all the APIs exposed by the services.
+	handler, err := newWhitelistServer(apis, nil, true, "IPC")
+	if err != nil {
+		return nil, nil, err
+	}
+	// All APIs registered, start the IPC listener.
+	listener, err := ipcListen(ipcEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go handler.ServeListener(listener)
+	return listener, handler, nil
+}
+
+// newWhitelistServer registers the apis whose namespace is in modules. With an
+// empty module list only the public apis are registered, unless exposeAll is set.
+func newWhitelistServer(apis []API, modules []string, exposeAll bool, transport string) (*Server, error) {
+	// Generate the whitelist based on the allowed modules
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	// Register all the APIs exposed by the services
+	handler := NewServer()
+	for _, api := range apis {
+		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				return nil, err
+			}
+			log.Debug(transport+" registered", "namespace", api.Namespace)
+		}
+	}
+	return handler, nil
+}
@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+)
+
+// request is for an unknown service
+type methodNotFoundError struct {
+	service string
+	method  string
+}
+
+func (e *methodNotFoundError) ErrorCode() int { return -32601 }
+
+func (e *methodNotFoundError) Error() string {
+	return fmt.Sprintf("The method %s%s%s does not exist/is not available", e.service, serviceMethodSeparator, e.method)
+}
+
+// received message isn't a valid request
+type invalidRequestError struct{ message string }
+
+func (e *invalidRequestError) ErrorCode() int { return -32600 }
+
+func (e *invalidRequestError) Error() string { return e.message }
+
+// received message is invalid
+type invalidMessageError struct{ message string }
+
+func (e *invalidMessageError) ErrorCode() int { return -32700 }
+
+func (e *invalidMessageError) Error() string { return e.message }
+
+// unable to decode supplied params, or an invalid number of parameters
+type invalidParamsError struct{ message string }
+
+func (e *invalidParamsError) ErrorCode() int { return -32602 }
+
+func (e *invalidParamsError) Error() string { return e.message }
+
+// logic error, callback returned an error
+type callbackError struct{ message string }
+
+func (e *callbackError) ErrorCode() int { return -32000 }
+
+func (e *callbackError) Error() string { return e.message }
+
+// issued when a request is received after the server is issued to stop.
+type shutdownError struct{}
+
+func (e *shutdownError) ErrorCode() int { return -32000 }
+
+func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// caller may not use the module it called
+type unauthorizedError struct {
+	module string
+	reason string
+}
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string { return "unauthorized" }
+
+func (e *unauthorizedError) ErrorData() interface{} {
+	return map[string]string{"module": e.module, "reason": e.reason}
+}
+
+// caller made more calls than its rate limit allows
+type rateLimitedError struct{ retryAfter time.Duration }
+
+func (e *rateLimitedError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitedError) Error() string { return "rate limit exceeded" }
+
+func (e *rateLimitedError) ErrorData() interface{} {
+	return map[string]int64{"retry_after_ms": int64(e.retryAfter / time.Millisecond)}
+}
+
+// caller presented credentials the server does not accept
+type authenticationError struct{ reason string }
+
+func (e *authenticationError) ErrorCode() int { return -32001 }
+
+func (e *authenticationError) Error() string { return "unauthorized" }
+
+func (e *authenticationError) ErrorData() interface{} {
+	return map[string]string{"reason": e.reason}
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPCall(t *testing.T) {
+	server, _ := newTestServer(t)
+	ts := httptest.NewServer(NewHTTPServer(nil, []string{"localhost"}, server).Handler)
+	defer ts.Close()
+
+	client, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res Result
+	if err := client.Call(&res, "test_echo", "hello", 1); err != nil {
+		t.Fatal(err)
+	}
+	if res.String != "hello" || res.Int != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestHTTPVirtualHosts(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := NewHTTPServer(nil, []string{"localhost"}, server).Handler
+
+	tests := []struct {
+		host string
+		code int
+	}{
+		{"localhost:9090", http.StatusOK},
+		{"127.0.0.1:9090", http.StatusOK},
+		{"evil.example.com", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`))
+		req.Host = test.host
+		req.Header.Set("content-type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("host %s: got status %d, want %d", test.host, rec.Code, test.code)
+		}
+	}
+}
+
+func TestHTTPCors(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := NewHTTPServer([]string{"http://allowed.example"}, []string{"*"}, server).Handler
+
+	for origin, want := range map[string]string{
+		"http://allowed.example": "http://allowed.example",
+		"http://other.example":   "",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %s: got allow-origin %q, want %q", origin, got, want)
+		}
+	}
+}
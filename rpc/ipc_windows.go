@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// errIPCUnsupported is returned on Windows, where named pipes would need a
+// library the tree does not vendor.
+var errIPCUnsupported = errors.New("IPC is not supported on windows")
+
+// ipcListen will create a named pipe on the given endpoint.
+func ipcListen(endpoint string) (net.Listener, error) {
+	return nil, errIPCUnsupported
+}
+
+// newIPCConnection will connect to a named pipe with the given endpoint as name.
+func newIPCConnection(ctx context.Context, endpoint string) (net.Conn, error) {
+	return nil, errIPCUnsupported
+}
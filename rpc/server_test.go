@@ -0,0 +1,194 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Args struct {
+	S string
+}
+
+type Result struct {
+	String string
+	Int    int
+	Args   *Args
+}
+
+type TestService struct {
+	notified int32
+}
+
+func (s *TestService) Echo(str string, i int, args *Args) Result {
+	return Result{str, i, args}
+}
+
+func (s *TestService) Fail() (string, error) {
+	return "", errors.New("failure")
+}
+
+func (s *TestService) Ping(ctx context.Context) {
+	atomic.AddInt32(&s.notified, 1)
+}
+
+func (s *TestService) Count(ctx context.Context, n int) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		time.Sleep(50 * time.Millisecond) // wait for the subscription to be activated
+		for i := 0; i < n; i++ {
+			if err := notifier.Notify(sub.ID, i); err != nil {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *TestService) {
+	server := NewServer()
+	service := new(TestService)
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	return server, service
+}
+
+func TestServerRegisterName(t *testing.T) {
+	server, _ := newTestServer(t)
+	svc, ok := server.services["test"]
+	if !ok {
+		t.Fatal("service not registered")
+	}
+	if len(svc.callbacks) != 3 {
+		t.Errorf("got %d callbacks, want 3", len(svc.callbacks))
+	}
+	if len(svc.subscriptions) != 1 {
+		t.Errorf("got %d subscriptions, want 1", len(svc.subscriptions))
+	}
+}
+
+func TestClientCall(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := DialInProc(server)
+	defer client.Close()
+
+	var res Result
+	if err := client.Call(&res, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if res.String != "hello" || res.Int != 10 || res.Args == nil || res.Args.S != "world" {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	// optional trailing pointer argument
+	if err := client.Call(&res, "test_echo", "hello", 10); err != nil {
+		t.Fatal(err)
+	}
+	if res.Args != nil {
+		t.Errorf("expected nil args, got %+v", res.Args)
+	}
+
+	err := client.Call(&res, "test_fail")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32000 || err.Error() != "failure" {
+		t.Errorf("unexpected error %v", err)
+	}
+	err = client.Call(&res, "test_missing")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32601 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestClientBatchCall(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"a", 1}, Result: new(Result)},
+		{Method: "test_fail", Result: new(string)},
+		{Method: "rpc_modules", Result: new(map[string]string)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if res := batch[0].Result.(*Result); batch[0].Error != nil || res.String != "a" || res.Int != 1 {
+		t.Errorf("unexpected first element %+v %v", res, batch[0].Error)
+	}
+	if batch[1].Error == nil {
+		t.Error("expected error for second element")
+	}
+	if mods := *batch[2].Result.(*map[string]string); mods["test"] == "" || mods[MetadataApi] == "" {
+		t.Errorf("unexpected modules %v", mods)
+	}
+}
+
+func TestServerNotification(t *testing.T) {
+	server, service := newTestServer(t)
+	p1, p2 := net.Pipe()
+	go server.ServeCodec(NewJSONCodec(p1), OptionMethodInvocation)
+	defer p2.Close()
+
+	// A batch with one notification and one call is answered with the call only.
+	p2.SetDeadline(time.Now().Add(5 * time.Second))
+	req := `[{"jsonrpc":"2.0","method":"test_ping"},{"jsonrpc":"2.0","id":7,"method":"test_echo","params":["x",2]}]`
+	if _, err := p2.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	var resps []jsonrpcMessage
+	if err := json.NewDecoder(p2).Decode(&resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 1 || string(resps[0].ID) != "7" {
+		t.Fatalf("unexpected responses %v", resps)
+	}
+	if n := atomic.LoadInt32(&service.notified); n != 1 {
+		t.Errorf("notification ran %d times, want 1", n)
+	}
+
+	client := DialInProc(server)
+	defer client.Close()
+	if err := client.Notify(context.Background(), "test_ping"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; atomic.LoadInt32(&service.notified) != 2; i++ {
+		if i == 100 {
+			t.Fatal("notification not executed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "test", ch, "count", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for want := 0; want < 5; want++ {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("got notification %d, want %d", got, want)
+			}
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for notification")
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/srchain/srcd/log"
+)
+
+// notificationQueueSize is the number of notifications buffered for a single
+// connection. A client that falls this far behind is disconnected, so that a
+// slow reader never holds up the services producing the notifications.
+const notificationQueueSize = 1000
+
+var (
+	// ErrNotificationsUnsupported is returned when the connection doesn't support notifications
+	ErrNotificationsUnsupported = errors.New("notifications not supported")
+	// ErrSubscriptionNotFound is returned when the notification for the given id is not found
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrNotificationQueueFull is returned when a client doesn't keep up with its
+	// notifications and its connection is dropped
+	ErrNotificationQueueFull = errors.New("notification queue full")
+)
+
+// ID defines a pseudo random number that is used to identify RPC subscriptions.
+type ID string
+
+// a Subscription is created by a notifier and tight to that notifier. The client can use
+// this subscription to wait for an unsubscribe request for the client, see Err().
+type Subscription struct {
+	ID        ID
+	namespace string
+	err       chan error // closed on unsubscribe
+}
+
+// Err returns a channel that is closed when the client send an unsubscribe request.
+func (s *Subscription) Err() <-chan error {
+	return s.err
+}
+
+// notifierKey is used to store a notifier within the connection context.
+type notifierKey struct{}
+
+// Notifier is tight to a RPC connection that supports subscriptions.
+// Server callbacks use the notifier to send notifications.
+type Notifier struct {
+	codec    ServerCodec
+	subMu    sync.RWMutex // guards active and inactive maps
+	active   map[ID]*Subscription
+	inactive map[ID]*Subscription
+	queue    chan interface{} // notifications waiting to be written
+}
+
+// newNotifier creates a new notifier that can be used to send subscription
+// notifications to the client.
+func newNotifier(codec ServerCodec) *Notifier {
+	n := &Notifier{
+		codec:    codec,
+		active:   make(map[ID]*Subscription),
+		inactive: make(map[ID]*Subscription),
+		queue:    make(chan interface{}, notificationQueueSize),
+	}
+	go n.sendLoop()
+	return n
+}
+
+// NotifierFromContext returns the Notifier value stored in ctx, if any.
+func NotifierFromContext(ctx context.Context) (*Notifier, bool) {
+	n, ok := ctx.Value(notifierKey{}).(*Notifier)
+	return n, ok
+}
+
+// CreateSubscription returns a new subscription that is coupled to the
+// RPC connection. By default subscriptions are inactive and notifications
+// are dropped until the subscription is marked as active. This is done
+// by the RPC server after the subscription ID is send to the client.
+func (n *Notifier) CreateSubscription() *Subscription {
+	s := &Subscription{ID: NewID(), err: make(chan error)}
+	n.subMu.Lock()
+	n.inactive[s.ID] = s
+	n.subMu.Unlock()
+	return s
+}
+
+// Notify queues a notification to the client with the given data as payload.
+// It never blocks: if the client has fallen too far behind, the RPC connection
+// is closed and ErrNotificationQueueFull is returned.
+func (n *Notifier) Notify(id ID, data interface{}) error {
+	n.subMu.RLock()
+	defer n.subMu.RUnlock()
+
+	sub, active := n.active[id]
+	if !active {
+		return nil
+	}
+	notification := n.codec.CreateNotification(string(id), sub.namespace, data)
+	select {
+	case n.queue <- notification:
+		return nil
+	case <-n.codec.Closed():
+		return nil
+	default:
+		log.Warn("Dropping slow RPC subscriber", "queued", len(n.queue))
+		n.codec.Close()
+		return ErrNotificationQueueFull
+	}
+}
+
+// sendLoop writes queued notifications to the client until the connection is
+// closed. A failed write closes the connection.
+func (n *Notifier) sendLoop() {
+	for {
+		select {
+		case notification := <-n.queue:
+			if err := n.codec.Write(notification); err != nil {
+				n.codec.Close()
+				return
+			}
+		case <-n.codec.Closed():
+			return
+		}
+	}
+}
+
+// Closed returns a channel that is closed when the RPC connection is closed.
+func (n *Notifier) Closed() <-chan interface{} {
+	return n.codec.Closed()
+}
+
+// unsubscribe a subscription.
+// If the subscription could not be found ErrSubscriptionNotFound is returned.
+func (n *Notifier) unsubscribe(id ID) error {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+	if s, found := n.active[id]; found {
+		close(s.err)
+		delete(n.active, id)
+		return nil
+	}
+	return ErrSubscriptionNotFound
+}
+
+// activate enables a subscription. Until a subscription is enabled all
+// notifications are dropped. This method is called by the RPC server after
+// the subscription ID was sent to client. This prevents notifications being
+// send to the client before the subscription ID is send to the client.
+func (n *Notifier) activate(id ID, namespace string) {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+	if sub, found := n.inactive[id]; found {
+		sub.namespace = namespace
+		n.active[id] = sub
+		delete(n.inactive, id)
+	}
+}
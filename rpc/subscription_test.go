@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Tests that a client which stops reading its notifications is disconnected
+// instead of blocking the notifying service.
+func TestNotifierDropsSlowClient(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	notifier := newNotifier(NewJSONCodec(serverConn))
+	sub := notifier.CreateSubscription()
+	notifier.activate(sub.ID, "test")
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i <= notificationQueueSize+1; i++ {
+			if err := notifier.Notify(sub.ID, i); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != ErrNotificationQueueFull {
+			t.Fatalf("Notify error mismatch: have %v, want %v", err, ErrNotificationQueueFull)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked on a slow client")
+	}
+	select {
+	case <-notifier.Closed():
+	case <-time.After(time.Second):
+		t.Fatal("slow client connection not closed")
+	}
+}
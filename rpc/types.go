@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/srchain/srcd/common/hexutil"
+	"gopkg.in/fatih/set.v0"
+)
+
+// API describes the set of methods offered over the RPC interface
+type API struct {
+	Namespace string      // namespace under which the rpc methods of Service are exposed
+	Version   string      // api version for DApp's
+	Service   interface{} // receiver instance which holds the methods
+	Public    bool        // indication if the methods must be considered safe for public use
+}
+
+// callback is a method callback which was registered in the server
+type callback struct {
+	rcvr        reflect.Value  // receiver of method
+	method      reflect.Method // callback
+	argTypes    []reflect.Type // input argument types
+	hasCtx      bool           // method's first argument is a context (not included in argTypes)
+	errPos      int            // err return idx, of -1 when method cannot return error
+	isSubscribe bool           // indication if the callback is a subscription
+}
+
+// service represents a registered object
+type service struct {
+	name          string        // name for service
+	typ           reflect.Type  // receiver type
+	callbacks     callbacks     // registered handlers
+	subscriptions subscriptions // available subscriptions/notifications
+}
+
+// serverRequest is an incoming request
+type serverRequest struct {
+	id             interface{}
+	svcname        string
+	callb          *callback
+	args           []reflect.Value
+	isUnsubscribe  bool
+	isNotification bool // request carried no id, no response is written
+	err            Error
+}
+
+type serviceRegistry map[string]*service // collection of services
+type callbacks map[string]*callback      // collection of RPC callbacks
+type subscriptions map[string]*callback  // collection of subscription callbacks
+
+// Server represents a RPC server
+type Server struct {
+	services serviceRegistry
+
+	run      int32
+	codecsMu sync.Mutex
+	codecs   *set.Set
+
+	policy *AccessPolicy // restrictions on remote callers, nil for none
+}
+
+// rpcRequest represents a raw incoming RPC request
+type rpcRequest struct {
+	service        string
+	method         string
+	id             interface{}
+	isPubSub       bool
+	isNotification bool
+	params         interface{}
+	err            Error // invalid batch element
+}
+
+// Error wraps RPC errors, which contain an error code in addition to the message.
+type Error interface {
+	Error() string  // returns the message
+	ErrorCode() int // returns the code
+}
+
+// DataError is an Error carrying structured details, sent to the client as
+// the data member of the error object.
+type DataError interface {
+	Error
+	ErrorData() interface{} // returns the details
+}
+
+// ServerCodec implements reading, parsing and writing RPC messages for the server side of
+// a RPC session. Implementations must be go-routine safe since the codec can be called in
+// multiple go-routines concurrently.
+type ServerCodec interface {
+	// Read next request
+	ReadRequestHeaders() ([]rpcRequest, bool, Error)
+	// Parse request argument to the given types
+	ParseRequestArguments(argTypes []reflect.Type, params interface{}) ([]reflect.Value, Error)
+	// Assemble success response, expects response id and payload
+	CreateResponse(id interface{}, reply interface{}) interface{}
+	// Assemble error response, expects response id and error
+	CreateErrorResponse(id interface{}, err Error) interface{}
+	// Assemble error response with extra information about the error through info
+	CreateErrorResponseWithInfo(id interface{}, err Error, info interface{}) interface{}
+	// Create notification response
+	CreateNotification(id, namespace string, event interface{}) interface{}
+	// Write msg to client.
+	Write(msg interface{}) error
+	// Close underlying data stream
+	Close()
+	// Closed when underlying connection is closed
+	Closed() <-chan interface{}
+}
+
+// BlockNumber is a block height as passed over RPC. Besides plain numbers it
+// accepts the "latest" and "earliest" tags.
+type BlockNumber int64
+
+const (
+	LatestBlockNumber   = BlockNumber(-1)
+	EarliestBlockNumber = BlockNumber(0)
+)
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
+// - "latest" or "earliest" as string arguments
+// - the block number, either as a JSON number or a decimal or 0x-prefixed string
+// Returned errors:
+// - an invalid block number error when the given argument isn't a known strings
+// - an out of range error when the given block number is either too little or too large
+func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
+	input := strings.TrimSpace(string(data))
+	if len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"' {
+		input = input[1 : len(input)-1]
+	}
+
+	switch input {
+	case "earliest":
+		*bn = EarliestBlockNumber
+		return nil
+	case "latest":
+		*bn = LatestBlockNumber
+		return nil
+	}
+
+	var (
+		blckNum uint64
+		err     error
+	)
+	if strings.HasPrefix(input, "0x") || strings.HasPrefix(input, "0X") {
+		blckNum, err = hexutil.DecodeUint64(input)
+	} else {
+		blckNum, err = strconv.ParseUint(input, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid block number %q", input)
+	}
+	if blckNum > math.MaxInt64 {
+		return fmt.Errorf("block number larger than int64")
+	}
+	*bn = BlockNumber(blckNum)
+	return nil
+}
+
+// Int64 returns the block number as int64.
+func (bn BlockNumber) Int64() int64 {
+	return (int64)(bn)
+}
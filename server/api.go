@@ -0,0 +1,324 @@
+package server
+
+import (
+	"github.com/srchain/srcd"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/common/hexutil"
+	"github.com/srchain/srcd/core/rawdb"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/rpc"
+)
+
+// PublicChainAPI provides an API to access the block chain, its transactions
+// and their outputs.
+type PublicChainAPI struct {
+	s *SilkRoad
+}
+
+// NewPublicChainAPI creates a new chain API.
+func NewPublicChainAPI(s *SilkRoad) *PublicChainAPI {
+	return &PublicChainAPI{s}
+}
+
+// BlockNumber returns the number of the current head block.
+func (api *PublicChainAPI) BlockNumber() uint64 {
+	return api.s.blockchain.CurrentBlock().NumberU64()
+}
+
+// TotalDifficulty returns the total difficulty of the current head block.
+func (api *PublicChainAPI) TotalDifficulty() *hexutil.Big {
+	head := api.s.blockchain.CurrentBlock()
+	return (*hexutil.Big)(api.s.blockchain.GetTd(head.Hash(), head.NumberU64()))
+}
+
+// Syncing returns false when the node isn't synchronising with the network,
+// and the sync progress otherwise.
+func (api *PublicChainAPI) Syncing() (interface{}, error) {
+	progress := api.s.protocolManager.downloader.Progress()
+
+	// Return not syncing if the synchronisation already completed
+	if progress.CurrentBlock >= progress.HighestBlock {
+		return false, nil
+	}
+	return progress, nil
+}
+
+// GetBlockByNumber returns the requested block. When fullTx is true all
+// transactions in the block are returned in full detail, otherwise only their
+// IDs are returned.
+func (api *PublicChainAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	block := api.blockByNumber(number)
+	if block == nil {
+		return nil, nil
+	}
+	return api.rpcOutputBlock(block, fullTx), nil
+}
+
+// GetBlockByHash returns the requested block. When fullTx is true all
+// transactions in the block are returned in full detail, otherwise only their
+// IDs are returned.
+func (api *PublicChainAPI) GetBlockByHash(hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	block := api.s.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, nil
+	}
+	return api.rpcOutputBlock(block, fullTx), nil
+}
+
+// GetHeaderByNumber returns the requested canonical block header.
+func (api *PublicChainAPI) GetHeaderByNumber(number rpc.BlockNumber) map[string]interface{} {
+	var header *types.Header
+	if number == rpc.LatestBlockNumber {
+		header = api.s.blockchain.CurrentBlock().Header()
+	} else {
+		header = api.s.blockchain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil
+	}
+	return rpcMarshalHeader(header)
+}
+
+// GetHeaderByHash returns the requested header by hash.
+func (api *PublicChainAPI) GetHeaderByHash(hash common.Hash) map[string]interface{} {
+	header := api.s.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil
+	}
+	return rpcMarshalHeader(header)
+}
+
+// GetBlockTransactionCountByHash returns the number of transactions in the
+// block with the given hash.
+func (api *PublicChainAPI) GetBlockTransactionCountByHash(hash common.Hash) *uint64 {
+	block := api.s.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil
+	}
+	n := uint64(len(block.Transactions()))
+	return &n
+}
+
+// GetTransactionByBlockHashAndIndex returns the transaction at the given
+// index of the block with the given hash.
+func (api *PublicChainAPI) GetTransactionByBlockHashAndIndex(hash common.Hash, index uint64) *RPCTransaction {
+	block := api.s.blockchain.GetBlockByHash(hash)
+	if block == nil || index >= uint64(len(block.Transactions())) {
+		return nil
+	}
+	tx := transaction.NewTx(block.Transactions()[index].Tx)
+	number := block.NumberU64()
+	result := &RPCTransaction{
+		TxJSON:      transaction.NewTxJSON(&tx),
+		BlockHash:   &hash,
+		BlockNumber: &number,
+		Index:       &index,
+	}
+	if rawdb.ReadCanonicalHash(api.s.chainDb, number) == hash {
+		result.Confirmations = api.confirmations(number)
+	}
+	return result
+}
+
+// RPCTransaction represents a transaction that will serialize to the RPC
+// representation of a transaction. Pending transactions have no block and
+// no confirmations.
+type RPCTransaction struct {
+	*transaction.TxJSON
+	BlockHash     *common.Hash `json:"block_hash,omitempty"`
+	BlockNumber   *uint64      `json:"block_number,omitempty"`
+	Index         *uint64      `json:"index,omitempty"`
+	Confirmations uint64       `json:"confirmations"`
+}
+
+// GetTransaction returns the transaction with the given ID, looking in the
+// canonical chain first and the transaction pool after.
+func (api *PublicChainAPI) GetTransaction(id transaction.Hash) (*RPCTransaction, error) {
+	if t, blockHash, blockNumber, index := rawdb.ReadTransaction(api.s.chainDb, id); t != nil {
+		tx := transaction.NewTx(t.Tx)
+		return &RPCTransaction{
+			TxJSON:        transaction.NewTxJSON(&tx),
+			BlockHash:     &blockHash,
+			BlockNumber:   &blockNumber,
+			Index:         &index,
+			Confirmations: api.confirmations(blockNumber),
+		}, nil
+	}
+	if msg, err := api.s.txPool.GetTransaction(&id); err == nil {
+		return &RPCTransaction{TxJSON: transaction.NewTxJSON(&msg.Tx)}, nil
+	}
+	return nil, nil
+}
+
+// RPCOutput represents a transaction output in the canonical chain along
+// with its spent status.
+type RPCOutput struct {
+	*transaction.OutputJSON
+	TxID          transaction.Hash  `json:"tx_id"`
+	BlockHash     common.Hash       `json:"block_hash"`
+	BlockNumber   uint64            `json:"block_number"`
+	Confirmations uint64            `json:"confirmations"`
+	Spent         bool              `json:"spent"`
+	SpentBy       *transaction.Hash `json:"spent_by,omitempty"`
+}
+
+// GetOutput returns the output with the given ID and whether a transaction
+// in the canonical chain has spent it.
+func (api *PublicChainAPI) GetOutput(id transaction.Hash) (*RPCOutput, error) {
+	db := api.s.chainDb
+
+	entry := rawdb.ReadOutputLookupEntry(db, id)
+	if entry == nil || rawdb.ReadCanonicalHash(db, entry.BlockIndex) != entry.BlockHash {
+		return nil, nil
+	}
+	body := rawdb.ReadBody(db, entry.BlockHash, entry.BlockIndex)
+	if body == nil || uint64(len(body.Transactions)) <= entry.Index {
+		return nil, nil
+	}
+	tx := transaction.NewTx(body.Transactions[entry.Index].Tx)
+	txJSON := transaction.NewTxJSON(&tx)
+	if uint64(len(txJSON.Outputs)) <= entry.Position {
+		return nil, nil
+	}
+
+	out := &RPCOutput{
+		OutputJSON:    txJSON.Outputs[entry.Position],
+		TxID:          tx.ID,
+		BlockHash:     entry.BlockHash,
+		BlockNumber:   entry.BlockIndex,
+		Confirmations: api.confirmations(entry.BlockIndex),
+	}
+	if spender, ok := rawdb.ReadOutputSpender(db, id); ok {
+		out.Spent, out.SpentBy = true, &spender
+	}
+	return out, nil
+}
+
+// GetUnspentOutputs returns the confirmed outputs paying to program that
+// have not been spent.
+func (api *PublicChainAPI) GetUnspentOutputs(program transaction.HexBytes) []*transaction.UTXO {
+	utxos := rawdb.ReadUnspentOutputs(api.s.chainDb, program)
+	if utxos == nil {
+		return []*transaction.UTXO{}
+	}
+	return utxos
+}
+
+// GetBalance returns the total amount of each asset held in the confirmed,
+// unspent outputs paying to program.
+func (api *PublicChainAPI) GetBalance(program transaction.HexBytes) map[transaction.AssetID]uint64 {
+	balances := make(map[transaction.AssetID]uint64)
+	for _, utxo := range rawdb.ReadUnspentOutputs(api.s.chainDb, program) {
+		balances[utxo.AssetID] += utxo.Amount
+	}
+	return balances
+}
+
+// CheckTransaction validates a signed transaction the way the transaction
+// pool would, without submitting it.
+func (api *PublicChainAPI) CheckTransaction(tx transaction.Tx) (*silkroad.TxCheck, error) {
+	if err := api.s.txPool.CheckTransaction(&tx); err != nil {
+		return nil, err
+	}
+	return &silkroad.TxCheck{
+		ID:     tx.ID,
+		Fee:    transaction.CalculateTxFee(&tx.TxData),
+		Weight: tx.TxData.Weight(),
+	}, nil
+}
+
+// SendTransaction submits a signed transaction to the transaction pool and
+// returns its ID.
+func (api *PublicChainAPI) SendTransaction(tx transaction.Tx) (transaction.Hash, error) {
+	if err := api.s.txPool.AddTransaction(tx, transaction.CalculateTxFee(&tx.TxData)); err != nil {
+		return transaction.Hash{}, err
+	}
+	return tx.ID, nil
+}
+
+// BadBlockArgs represents the entries in the list returned when bad blocks
+// are queried.
+type BadBlockArgs struct {
+	Hash  common.Hash            `json:"hash"`
+	Block map[string]interface{} `json:"block"`
+}
+
+// BadBlocks returns a list of the last 'bad blocks' that the client has seen
+// on the network.
+func (api *PublicChainAPI) BadBlocks() []*BadBlockArgs {
+	blocks := api.s.blockchain.BadBlocks()
+	results := make([]*BadBlockArgs, len(blocks))
+	for i, block := range blocks {
+		results[i] = &BadBlockArgs{
+			Hash:  block.Hash(),
+			Block: rpcMarshalBlock(block, true),
+		}
+	}
+	return results
+}
+
+func (api *PublicChainAPI) blockByNumber(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber {
+		return api.s.blockchain.CurrentBlock()
+	}
+	return api.s.blockchain.GetBlockByNumber(uint64(number.Int64()))
+}
+
+// confirmations returns how many blocks, itself included, are built on the
+// canonical block with the given number.
+func (api *PublicChainAPI) confirmations(number uint64) uint64 {
+	head := api.s.blockchain.CurrentBlock().NumberU64()
+	if number > head {
+		return 0
+	}
+	return head - number + 1
+}
+
+// rpcOutputBlock uses the generalized output filler, then adds the total
+// difficulty field.
+func (api *PublicChainAPI) rpcOutputBlock(b *types.Block, fullTx bool) map[string]interface{} {
+	fields := rpcMarshalBlock(b, fullTx)
+	fields["total_difficulty"] = (*hexutil.Big)(api.s.blockchain.GetTd(b.Hash(), b.NumberU64()))
+	return fields
+}
+
+// rpcMarshalHeader converts the given header to the RPC output.
+func rpcMarshalHeader(head *types.Header) map[string]interface{} {
+	return map[string]interface{}{
+		"number":      head.Number.Uint64(),
+		"hash":        head.Hash(),
+		"parent_hash": head.ParentHash,
+		"nonce":       head.Nonce,
+		"coinbase":    head.Coinbase,
+		"tx_root":     head.TxHash,
+		"difficulty":  (*hexutil.Big)(head.Difficulty),
+		"timestamp":   head.Time.Uint64(),
+		"extra_data":  hexutil.Bytes(head.Extra),
+		"size":        uint64(head.Size()),
+	}
+}
+
+// rpcMarshalBlock converts the given block to the RPC output. When fullTx is
+// true the decoded transactions are included, otherwise only their IDs.
+func rpcMarshalBlock(b *types.Block, fullTx bool) map[string]interface{} {
+	fields := rpcMarshalHeader(b.Header())
+	fields["size"] = uint64(b.Size())
+
+	txs := b.Transactions()
+	if fullTx {
+		decoded := make([]*transaction.TxJSON, len(txs))
+		for i, t := range txs {
+			tx := transaction.NewTx(t.Tx)
+			decoded[i] = transaction.NewTxJSON(&tx)
+		}
+		fields["transactions"] = decoded
+	} else {
+		ids := make([]transaction.Hash, len(txs))
+		for i, t := range txs {
+			ids[i] = transaction.NewTx(t.Tx).ID
+		}
+		fields["transactions"] = ids
+	}
+	return fields
+}
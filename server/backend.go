@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"net"
 	"github.com/srchain/srcd/core/rawdb"
 	"github.com/srchain/srcd/event"
 	"github.com/srchain/srcd/server/downloader"
@@ -9,12 +10,13 @@ import (
 	"sync"
 
 	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/account/wallet"
 	"github.com/srchain/srcd/common/common"
 	"github.com/srchain/srcd/common/hexutil"
 	"github.com/srchain/srcd/consensus"
 	"github.com/srchain/srcd/consensus/pow"
 	"github.com/srchain/srcd/core/blockchain"
-	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/txpool"
 	"github.com/srchain/srcd/database"
 	"github.com/srchain/srcd/log"
 	"github.com/srchain/srcd/miner"
@@ -22,6 +24,7 @@ import (
 	"github.com/srchain/srcd/p2p"
 	"github.com/srchain/srcd/params"
 	"github.com/srchain/srcd/rlp"
+	"github.com/srchain/srcd/rpc"
 )
 
 // SilkRoad implements the full node service.
@@ -33,7 +36,7 @@ type SilkRoad struct {
 	// shutdownChan chan bool
 
 	// Handlers
-	txPool          *transaction.TxPool
+	txPool          *txpool.TxPool
 	blockchain      *blockchain.BlockChain
 	protocolManager *ProtocolManager
 
@@ -43,6 +46,8 @@ type SilkRoad struct {
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *account.AccountManager
+	wallet         *wallet.Wallet
+	walletDb       database.Database
 
 	// bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	// bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
@@ -53,6 +58,8 @@ type SilkRoad struct {
 	coinbase common.Address
 
 	networkID     uint64
+
+	restListener net.Listener // REST gateway listener, nil if the gateway is disabled
 	// netRPCService *ethapi.PublicNetAPI
 
 	lock sync.RWMutex
@@ -71,6 +78,9 @@ func New(ctx *node.ServiceContext, config *Config) (*SilkRoad, error) {
 
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	params.ActiveNetParams = params.NetParamsForNetwork(config.NetworkId)
+	log.Info("Encoding addresses for network", "network", params.ActiveNetParams.Name, "id", config.NetworkId)
+
 	silk := &SilkRoad{
 		config:         config,
 		chainDb:        chainDb,
@@ -97,7 +107,17 @@ func New(ctx *node.ServiceContext, config *Config) (*SilkRoad, error) {
 	// if config.TxPool.Journal != "" {
 	// config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	// }
-	silk.txPool = transaction.NewTxPool()
+	silk.txPool = txpool.NewTxPool(silk.blockchain, chainDb)
+	silk.txPool.Policy = config.TxPolicy.CheckTx
+
+	if silk.accountManager != nil {
+		if silk.walletDb, err = CreateDB(ctx, config, "wallet"); err != nil {
+			return nil, err
+		}
+		if silk.wallet, err = wallet.New(silk.walletDb, silk.accountManager, silk.blockchain, silk.txPool); err != nil {
+			return nil, err
+		}
+	}
 
 	if silk.protocolManager, err = NewProtocolManager(silk.chainConfig, downloader.FullSync, config.NetworkId, silk.eventMux, silk.txPool, silk.engine, silk.blockchain, chainDb); err != nil {
 		return nil, err
@@ -198,8 +218,9 @@ func (s *SilkRoad) IsMining() bool { return s.miner.Mining() }
 
 
 func (s *SilkRoad) AccountManager() *account.AccountManager { return s.accountManager }
+func (s *SilkRoad) Wallet() *wallet.Wallet         { return s.wallet }
 func (s *SilkRoad) BlockChain() *blockchain.BlockChain { return s.blockchain }
-func (s *SilkRoad) TxPool() *transaction.TxPool            { return s.txPool }
+func (s *SilkRoad) TxPool() *txpool.TxPool            { return s.txPool }
 func (s *SilkRoad) Engine() consensus.Engine           { return s.engine }
 func (s *SilkRoad) ChainDb() database.Database         { return s.chainDb }
 
@@ -207,6 +228,27 @@ func (s *SilkRoad) ChainDb() database.Database         { return s.chainDb }
 
 
 
+// APIs returns the collection of RPC services the SilkRoad package offers.
+func (s *SilkRoad) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "chain",
+			Version:   "1.0",
+			Service:   NewPublicChainAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "chain",
+			Version:   "1.0",
+			Service:   downloader.NewPublicDownloaderAPI(s.protocolManager.downloader, s.eventMux),
+			Public:    true,
+		}, {
+			Namespace: "wallet",
+			Version:   "1.0",
+			Service:   NewPrivateWalletAPI(s),
+		},
+	}
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *SilkRoad) Protocols() []p2p.Protocol {
@@ -225,17 +267,25 @@ func (s *SilkRoad) Start(server *p2p.Server) error {
 	// Start the networking layer
 	maxPeers := server.MaxPeers
 	s.protocolManager.Start(maxPeers)
+	if s.wallet != nil {
+		s.wallet.Start()
+	}
 
-	return nil
+	return s.startREST()
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // SilkRoad protocol.
 func (s *SilkRoad) Stop() error {
+	s.stopREST()
+	if s.wallet != nil {
+		s.wallet.Stop()
+		s.walletDb.Close()
+	}
 	// s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
-	// s.txPool.Stop()
+	s.txPool.Stop()
 	s.miner.Stop()
 	// s.eventMux.Stop()
 
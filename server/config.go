@@ -4,6 +4,7 @@ import (
 	"github.com/srchain/srcd/common/common"
 	"github.com/srchain/srcd/core/blockchain"
 	"github.com/srchain/srcd/core/mempool"
+	"github.com/srchain/srcd/core/policy"
 )
 
 // DefaultConfig contains default settings for use on main net.
@@ -16,6 +17,12 @@ var DefaultConfig = Config{
 	// TrieTimeout:   60 * time.Minute,
 
 	// TxPool: core.DefaultTxPoolConfig,
+
+	TxPolicy: policy.DefaultConfig,
+
+	WalletFeeRate: 1,
+
+	REST: RESTConfig{Port: DefaultRESTPort},
 }
 
 type Config struct {
@@ -42,6 +49,14 @@ type Config struct {
 	// Transaction pool options
 	TxPool mempool.TxPoolConfig
 
+	// Standardness rules for transactions entering the pool
+	TxPolicy policy.Config
+
+	// Wallet options
+	WalletFeeRate uint64 // Fee paid per unit of weight by wallet-built transactions
+
+	// REST gateway options
+	REST RESTConfig
 
 	// Gas Price Oracle options
 	// GPO gasprice.Config
@@ -20,9 +20,9 @@ import (
 	"context"
 	"sync"
 
-		"github.com/ethereum/go-ethereum/event"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/srchain/srcd"
+	"github.com/srchain/srcd/event"
+	"github.com/srchain/srcd/rpc"
 )
 
 // PublicDownloaderAPI provides an API which gives information about the current synchronisation status.
@@ -454,6 +454,17 @@ func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error
 	return p2p.Send(p.rw, NewBlockHashesMsg, request)
 }
 
+// Info gathers and returns a collection of metadata known about a peer.
+func (p *peer) Info() *PeerInfo {
+	hash, td := p.Head()
+
+	return &PeerInfo{
+		Version:    p.version,
+		Difficulty: td,
+		Head:       hash.Hex(),
+	}
+}
+
 // Head retrieves a copy of the current head hash and total difficulty of the
 // peer.
 func (p *peer) Head() (hash common.Hash, td *big.Int) {
@@ -0,0 +1,466 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core/rawdb"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/crypto/sha3pool"
+	"github.com/srchain/srcd/log"
+	"github.com/srchain/srcd/params"
+	"github.com/srchain/srcd/rlp"
+)
+
+// DefaultRESTPort is the default TCP port of the REST gateway.
+const DefaultRESTPort = 9092
+
+// immutableCacheControl is sent along with resources that can never change
+// once they exist, such as blocks addressed by hash.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// pendingCacheControl is sent along with transactions of the pool, which may
+// still be dropped or replaced, so that clients check them on every use.
+const pendingCacheControl = "no-cache"
+
+// RESTConfig holds the settings of the read-only REST gateway.
+type RESTConfig struct {
+	// Host is the interface the gateway listens on. The gateway is disabled
+	// if it is empty.
+	Host string `toml:",omitempty"`
+	Port int    `toml:",omitempty"`
+
+	// CORSOrigins lists the origins browsers may call the gateway from.
+	CORSOrigins []string `toml:",omitempty"`
+}
+
+// Endpoint returns the address the gateway listens on, or the empty string
+// if it is disabled.
+func (c *RESTConfig) Endpoint() string {
+	if c.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// restHandler serves read-only chain and transaction pool data over plain
+// HTTP. Resources are rendered as JSON, or in their binary encoding when the
+// client asks for it with ?format=bin or an Accept header of
+// application/octet-stream.
+//
+//	/blocks/{id}             block by hash, number or "latest"
+//	/blocks/{id}/txs         transactions of a block
+//	/txs/{id}                confirmed or pending transaction
+//	/outputs/{id}            confirmed output and its spent status
+//	/addresses/{addr}/utxos  confirmed unspent outputs paying to addr
+//	/mempool                 transactions waiting in the pool
+//	/status                  chain head, sync progress and pool size
+type restHandler struct {
+	s   *SilkRoad
+	api *PublicChainAPI
+
+	cors map[string]bool
+}
+
+func newRESTHandler(s *SilkRoad, origins []string) *restHandler {
+	h := &restHandler{s: s, api: NewPublicChainAPI(s), cors: make(map[string]bool)}
+	for _, origin := range origins {
+		h.cors[origin] = true
+	}
+	return h
+}
+
+// startREST starts the REST gateway if one is configured.
+func (s *SilkRoad) startREST() error {
+	endpoint := s.config.REST.Endpoint()
+	if endpoint == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      newRESTHandler(s, s.config.REST.CORSOrigins),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	go srv.Serve(listener)
+	log.Info("REST endpoint opened", "url", fmt.Sprintf("http://%s", endpoint))
+
+	s.restListener = listener
+	return nil
+}
+
+// stopREST terminates the REST gateway.
+func (s *SilkRoad) stopREST() {
+	if s.restListener != nil {
+		s.restListener.Close()
+		s.restListener = nil
+
+		log.Info("REST endpoint closed", "url", fmt.Sprintf("http://%s", s.config.REST.Endpoint()))
+	}
+}
+
+// restError is the body of every failed REST request.
+type restError struct {
+	Error string `json:"error"`
+}
+
+func (h *restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && (h.cors["*"] || h.cors[origin]) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		h.fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	binary, err := wantsBinary(r)
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "blocks":
+		h.serveBlock(w, r, parts[1], binary)
+	case len(parts) == 3 && parts[0] == "blocks" && parts[2] == "txs":
+		h.serveBlockTxs(w, r, parts[1], binary)
+	case len(parts) == 2 && parts[0] == "txs":
+		h.serveTx(w, r, parts[1], binary)
+	case len(parts) == 2 && parts[0] == "outputs" && !binary:
+		h.serveOutput(w, parts[1])
+	case len(parts) == 3 && parts[0] == "addresses" && parts[2] == "utxos" && !binary:
+		h.serveUTXOs(w, parts[1])
+	case len(parts) == 1 && parts[0] == "mempool" && !binary:
+		h.serveMempool(w)
+	case len(parts) == 1 && parts[0] == "status" && !binary:
+		h.serveStatus(w)
+	case binary:
+		h.fail(w, http.StatusNotAcceptable, "resource has no binary form")
+	default:
+		h.fail(w, http.StatusNotFound, "no such resource")
+	}
+}
+
+// wantsBinary reports whether the client asked for the binary encoding of
+// the resource rather than JSON.
+func wantsBinary(r *http.Request) (bool, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "bin":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), "application/octet-stream"), nil
+	default:
+		return false, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// resolveBlock returns the block id names, and whether id is a hash and so
+// names the same block forever.
+func (h *restHandler) resolveBlock(id string) (block *types.Block, byHash bool, err error) {
+	switch {
+	case id == "latest":
+		return h.s.blockchain.CurrentBlock(), false, nil
+	case strings.HasPrefix(id, "0x") && len(id) == 2+2*common.HashLength:
+		b, err := hex.DecodeString(id[2:])
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid block hash %q", id)
+		}
+		return h.s.blockchain.GetBlockByHash(common.BytesToHash(b)), true, nil
+	default:
+		number, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid block id %q", id)
+		}
+		return h.s.blockchain.GetBlockByNumber(number), false, nil
+	}
+}
+
+func (h *restHandler) serveBlock(w http.ResponseWriter, r *http.Request, id string, binary bool) {
+	etag := blockETag(id, "", binary)
+	if etag != "" && notModified(w, r, etag, immutableCacheControl) {
+		return
+	}
+	block, byHash, err := h.resolveBlock(id)
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if block == nil {
+		h.fail(w, http.StatusNotFound, "block not found")
+		return
+	}
+	if byHash {
+		setImmutable(w, etag)
+	}
+	if binary {
+		header := rawdb.ReadHeaderRLP(h.s.chainDb, block.Hash(), block.NumberU64())
+		body := rawdb.ReadBodyRLP(h.s.chainDb, block.Hash(), block.NumberU64())
+		data, err := rlp.EncodeToBytes([]rlp.RawValue{header, body})
+		if err != nil {
+			h.fail(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.writeBinary(w, data)
+		return
+	}
+	h.writeJSON(w, h.api.rpcOutputBlock(block, false))
+}
+
+func (h *restHandler) serveBlockTxs(w http.ResponseWriter, r *http.Request, id string, binary bool) {
+	etag := blockETag(id, "txs", binary)
+	if etag != "" && notModified(w, r, etag, immutableCacheControl) {
+		return
+	}
+	block, byHash, err := h.resolveBlock(id)
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if block == nil {
+		h.fail(w, http.StatusNotFound, "block not found")
+		return
+	}
+	if byHash {
+		setImmutable(w, etag)
+	}
+	txs := block.Transactions()
+	if binary {
+		raw := make([][]byte, len(txs))
+		for i, t := range txs {
+			if raw[i], err = serializeTx(&t.Tx); err != nil {
+				h.fail(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		data, err := rlp.EncodeToBytes(raw)
+		if err != nil {
+			h.fail(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.writeBinary(w, data)
+		return
+	}
+	decoded := make([]*transaction.TxJSON, len(txs))
+	for i, t := range txs {
+		tx := transaction.NewTx(t.Tx)
+		decoded[i] = transaction.NewTxJSON(&tx)
+	}
+	h.writeJSON(w, decoded)
+}
+
+func (h *restHandler) serveTx(w http.ResponseWriter, r *http.Request, id string, binary bool) {
+	var txID transaction.Hash
+	if err := txID.UnmarshalText([]byte(id)); err != nil {
+		h.fail(w, http.StatusBadRequest, fmt.Sprintf("invalid transaction id %q", id))
+		return
+	}
+	tx, err := h.api.GetTransaction(txID)
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tx == nil {
+		h.fail(w, http.StatusNotFound, "transaction not found")
+		return
+	}
+	if !binary {
+		h.writeJSON(w, tx)
+		return
+	}
+	data, err := tx.TxJSON.TxData()
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	raw, err := serializeTx(data)
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The wire encoding includes the witness, which the transaction ID does
+	// not commit to, so the tag is taken over the bytes themselves.
+	var sum [32]byte
+	sha3pool.Sum256(sum[:], raw)
+	etag := fmt.Sprintf(`"%x"`, sum)
+	// Only transactions included in a block are settled.
+	cacheControl := immutableCacheControl
+	if tx.BlockHash == nil {
+		cacheControl = pendingCacheControl
+	}
+	if notModified(w, r, etag, cacheControl) {
+		return
+	}
+	setCacheHeaders(w, etag, cacheControl)
+	h.writeBinary(w, raw)
+}
+
+func (h *restHandler) serveOutput(w http.ResponseWriter, id string) {
+	var outputID transaction.Hash
+	if err := outputID.UnmarshalText([]byte(id)); err != nil {
+		h.fail(w, http.StatusBadRequest, fmt.Sprintf("invalid output id %q", id))
+		return
+	}
+	out, err := h.api.GetOutput(outputID)
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if out == nil {
+		h.fail(w, http.StatusNotFound, "output not found")
+		return
+	}
+	h.writeJSON(w, out)
+}
+
+func (h *restHandler) serveUTXOs(w http.ResponseWriter, addr string) {
+	program, err := account.AddressProgram(addr)
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.writeJSON(w, h.api.GetUnspentOutputs(program))
+}
+
+// restMempool is the JSON form of the transaction pool.
+type restMempool struct {
+	Size         int              `json:"size"`
+	Weight       uint64           `json:"weight"`
+	Fee          uint64           `json:"fee"`
+	Transactions []*restPoolEntry `json:"transactions"`
+}
+
+// restPoolEntry describes a transaction waiting in the pool.
+type restPoolEntry struct {
+	ID     transaction.Hash `json:"tx_id"`
+	Weight uint64           `json:"weight"`
+	Fee    uint64           `json:"fee"`
+	Added  int64            `json:"added"`
+}
+
+func (h *restHandler) serveMempool(w http.ResponseWriter) {
+	msgs := h.s.txPool.SelectTransactions(math.MaxUint64)
+
+	pool := &restMempool{Size: len(msgs), Transactions: make([]*restPoolEntry, len(msgs))}
+	for i, msg := range msgs {
+		pool.Weight += msg.Weight
+		pool.Fee += msg.Fee
+		pool.Transactions[i] = &restPoolEntry{
+			ID:     msg.Tx.ID,
+			Weight: msg.Weight,
+			Fee:    msg.Fee,
+			Added:  msg.Added.Unix(),
+		}
+	}
+	h.writeJSON(w, pool)
+}
+
+// restStatus is the JSON form of the node's status.
+type restStatus struct {
+	Version     string      `json:"version"`
+	NetworkID   uint64      `json:"network_id"`
+	BlockNumber uint64      `json:"block_number"`
+	BlockHash   common.Hash `json:"block_hash"`
+	Syncing     interface{} `json:"syncing"`
+	PoolSize    int         `json:"pool_size"`
+}
+
+func (h *restHandler) serveStatus(w http.ResponseWriter) {
+	head := h.s.blockchain.CurrentBlock()
+	syncing, _ := h.api.Syncing()
+
+	h.s.txPool.Mtx.RLock()
+	poolSize := len(h.s.txPool.Pool)
+	h.s.txPool.Mtx.RUnlock()
+
+	h.writeJSON(w, &restStatus{
+		Version:     params.Version,
+		NetworkID:   h.s.config.NetworkId,
+		BlockNumber: head.NumberU64(),
+		BlockHash:   head.Hash(),
+		Syncing:     syncing,
+		PoolSize:    poolSize,
+	})
+}
+
+func (h *restHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write REST response", "err", err)
+	}
+}
+
+func (h *restHandler) writeBinary(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+func (h *restHandler) fail(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&restError{Error: msg})
+}
+
+// blockETag returns the entity tag of the representation of block id, or
+// the empty string if id does not always name the same block.
+func blockETag(id, resource string, binary bool) string {
+	if !strings.HasPrefix(id, "0x") || len(id) != 2+2*common.HashLength {
+		return ""
+	}
+	tag := strings.ToLower(id[2:])
+	if resource != "" {
+		tag += "-" + resource
+	}
+	if binary {
+		tag += ".bin"
+	}
+	return `"` + tag + `"`
+}
+
+// notModified answers the request with 304 Not Modified if the client
+// already holds the representation tagged etag.
+func notModified(w http.ResponseWriter, r *http.Request, etag, cacheControl string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if strings.TrimSpace(tag) == etag {
+			setCacheHeaders(w, etag, cacheControl)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func setImmutable(w http.ResponseWriter, etag string) {
+	setCacheHeaders(w, etag, immutableCacheControl)
+}
+
+func setCacheHeaders(w http.ResponseWriter, etag, cacheControl string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+}
+
+// serializeTx returns the wire encoding of tx.
+func serializeTx(tx *transaction.TxData) ([]byte, error) {
+	text, err := tx.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(string(text))
+}
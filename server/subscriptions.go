@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/core"
+	"github.com/srchain/srcd/core/txpool"
+	"github.com/srchain/srcd/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+	// chainReorgChanSize is the size of channel listening to ChainReorgEvent.
+	chainReorgChanSize = 10
+	// pendingTxChanSize is the size of channel listening to new pool
+	// transactions.
+	pendingTxChanSize = 4096
+)
+
+// ReorgResult is the notification sent to reorg subscribers. Both lists run
+// from the highest block down.
+type ReorgResult struct {
+	Dropped []common.Hash `json:"dropped"`
+	Added   []common.Hash `json:"added"`
+}
+
+// NewHeads sends a notification each time a new block becomes the head of
+// the chain.
+func (api *PublicChainAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+		sub := api.s.blockchain.SubscribeChainHeadEvent(heads)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-heads:
+				if notifier.Notify(rpcSub.ID, rpcMarshalHeader(ev.Block.Header())) != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// NewPendingTransactions sends the ID of each transaction entering the
+// transaction pool.
+func (api *PublicChainAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		msgs := make(chan *txpool.TxPoolMsg, pendingTxChanSize)
+		sub := api.s.txPool.SubscribeNewTxs(msgs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case msg := <-msgs:
+				if notifier.Notify(rpcSub.ID, msg.Tx.ID) != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// Reorg sends a notification each time the canonical chain switches branch,
+// listing the blocks dropped from and added to it.
+func (api *PublicChainAPI) Reorg(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent, chainReorgChanSize)
+		sub := api.s.blockchain.SubscribeChainReorgEvent(reorgs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgs:
+				if notifier.Notify(rpcSub.ID, &ReorgResult{Dropped: ev.Dropped, Added: ev.Added}) != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
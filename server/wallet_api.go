@@ -0,0 +1,239 @@
+package server
+
+import (
+	"time"
+
+	"github.com/srchain/srcd/account"
+	"github.com/srchain/srcd/account/wallet"
+	"github.com/srchain/srcd/core/rawdb"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/crypto/ed25519/chainkd"
+	"github.com/srchain/srcd/errors"
+	"github.com/srchain/srcd/log"
+)
+
+var (
+	errNoAccountManager = errors.New("node has no account manager")
+	errNoWallet         = errors.New("node has no wallet")
+)
+
+// PrivateWalletAPI builds, signs and submits transactions spending from the
+// node's accounts, and manages its watch-only accounts.
+type PrivateWalletAPI struct {
+	s *SilkRoad
+}
+
+// NewPrivateWalletAPI creates a new wallet API.
+func NewPrivateWalletAPI(s *SilkRoad) *PrivateWalletAPI {
+	return &PrivateWalletAPI{s}
+}
+
+// BuildArgs are the arguments of the wallet methods building a transaction.
+type BuildArgs struct {
+	Actions []*account.Action `json:"actions"`
+
+	// FeeRate is the fee paid per unit of transaction weight. Zero selects
+	// the node's configured rate.
+	FeeRate uint64 `json:"fee_rate,omitempty"`
+
+	// Strategy is the coin selection strategy: largest_first, the default,
+	// branch_and_bound or random.
+	Strategy string `json:"strategy,omitempty"`
+
+	// ReserveSeconds is how long the selected inputs stay reserved for the
+	// built transaction. Zero selects account.DefaultReserveTTL.
+	ReserveSeconds uint64 `json:"reserve_seconds,omitempty"`
+}
+
+// FeeEstimate is the fee a transaction built from actions would pay.
+type FeeEstimate struct {
+	Fee     uint64 `json:"fee"`
+	Weight  uint64 `json:"weight"`
+	FeeRate uint64 `json:"fee_rate"`
+}
+
+// BuildTransaction builds an unsigned transaction template from the actions
+// in args, selecting the inputs and paying the fee from the spending
+// accounts.
+func (api *PrivateWalletAPI) BuildTransaction(args BuildArgs) (*account.BuildResult, error) {
+	return api.build(&args, true)
+}
+
+// EstimateFee returns the fee and weight of the transaction args describes,
+// without signing it.
+func (api *PrivateWalletAPI) EstimateFee(args BuildArgs) (*FeeEstimate, error) {
+	res, err := api.build(&args, false)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeEstimate{Fee: res.Fee, Weight: res.Weight, FeeRate: api.feeRate(&args)}, nil
+}
+
+// SignTransaction signs the inputs of tpl belonging to the node's accounts
+// with their keys, unlocked with password.
+func (api *PrivateWalletAPI) SignTransaction(tpl transaction.Template, password string) (*transaction.Template, error) {
+	if api.s.accountManager == nil {
+		return nil, errNoAccountManager
+	}
+	if err := api.s.accountManager.SignTemplate(&tpl, password); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// SendTransaction builds a transaction from the actions in args, signs it
+// with the keys of the spending accounts unlocked with password, submits it
+// to the transaction pool and returns its ID.
+func (api *PrivateWalletAPI) SendTransaction(args BuildArgs, password string) (transaction.Hash, error) {
+	res, err := api.build(&args, true)
+	if err != nil {
+		return transaction.Hash{}, err
+	}
+	tx := &res.Template.Transaction
+	if err := api.s.accountManager.SignTemplate(res.Template, password); err != nil {
+		api.s.accountManager.ReleaseReservation(tx)
+		return transaction.Hash{}, err
+	}
+	if err := api.s.txPool.AddTransaction(*tx, transaction.CalculateTxFee(&tx.TxData)); err != nil {
+		api.s.accountManager.ReleaseReservation(tx)
+		return transaction.Hash{}, err
+	}
+	return tx.ID, nil
+}
+
+// GetBalances returns the confirmed and unconfirmed balance of every asset
+// held by the account accountID, or by every account of the node if
+// accountID is empty.
+func (api *PrivateWalletAPI) GetBalances(accountID string) ([]*wallet.Balance, error) {
+	if api.s.wallet == nil {
+		return nil, errNoWallet
+	}
+	return api.s.wallet.Balances(accountID)
+}
+
+// GetHistory returns a page of the transactions touching the node's
+// accounts, newest first.
+func (api *PrivateWalletAPI) GetHistory(filter wallet.HistoryFilter) (*wallet.HistoryPage, error) {
+	if api.s.wallet == nil {
+		return nil, errNoWallet
+	}
+	return api.s.wallet.History(filter)
+}
+
+// Rescan scans the chain again from height from, or from the wallet's
+// birthday when from is omitted, to find the outputs of imported keys. It
+// returns once the rescan started; RescanStatus follows it.
+func (api *PrivateWalletAPI) Rescan(from *uint64) error {
+	if api.s.wallet == nil {
+		return errNoWallet
+	}
+	return api.s.wallet.Rescan(from)
+}
+
+// RescanStatus returns the progress of the running rescan, or nil if none
+// runs.
+func (api *PrivateWalletAPI) RescanStatus() (*wallet.RescanProgress, error) {
+	if api.s.wallet == nil {
+		return nil, errNoWallet
+	}
+	return api.s.wallet.RescanProgress(), nil
+}
+
+// ImportWatchOnly records a watch-only account controlled by quorum of
+// xpubs. Its transactions are built by the node, unsigned, and signed
+// offline; the node never holds its private keys. A zero gapLimit selects
+// account.DefaultGapLimit.
+func (api *PrivateWalletAPI) ImportWatchOnly(xpubs []chainkd.XPub, quorum int, gapLimit uint64) (*account.WatchAccount, error) {
+	if api.s.accountManager == nil {
+		return nil, errNoAccountManager
+	}
+	return api.s.accountManager.ImportWatchOnly(xpubs, quorum, gapLimit)
+}
+
+// ListWatchOnly returns the watch-only accounts of the node.
+func (api *PrivateWalletAPI) ListWatchOnly() ([]*account.WatchAccount, error) {
+	if api.s.accountManager == nil {
+		return nil, errNoAccountManager
+	}
+	return api.s.accountManager.WatchAccounts()
+}
+
+// NewReceiveAddress hands out the next unused receive address of the
+// watch-only account with address accountAddr.
+func (api *PrivateWalletAPI) NewReceiveAddress(accountAddr string) (string, error) {
+	if api.s.accountManager == nil {
+		return "", errNoAccountManager
+	}
+	cp, err := api.s.accountManager.CreateReceiveProgram(accountAddr)
+	if err != nil {
+		return "", err
+	}
+	return cp.Address, nil
+}
+
+// build builds the transaction args describes, reserving its inputs if
+// reserve is set.
+func (api *PrivateWalletAPI) build(args *BuildArgs, reserve bool) (*account.BuildResult, error) {
+	if api.s.accountManager == nil {
+		return nil, errNoAccountManager
+	}
+	opts := account.BuildOptions{
+		FeeRate:       api.feeRate(args),
+		Strategy:      args.Strategy,
+		DustThreshold: api.s.config.TxPolicy.DustThreshold(*transaction.SRCAssetID),
+	}
+	if reserve {
+		opts.ReserveTTL = account.DefaultReserveTTL
+		if args.ReserveSeconds != 0 {
+			opts.ReserveTTL = time.Duration(args.ReserveSeconds) * time.Second
+		}
+	}
+	return api.s.accountManager.Build(args.Actions, api.unspentOutputs, opts)
+}
+
+func (api *PrivateWalletAPI) feeRate(args *BuildArgs) uint64 {
+	if args.FeeRate != 0 {
+		return args.FeeRate
+	}
+	return api.s.config.WalletFeeRate
+}
+
+// unspentOutputs returns the confirmed outputs of the account with address
+// accountAddr that neither the chain nor the transaction pool has spent. They
+// come from the wallet, which knows the account's change outputs, or else
+// from the chain's index of the account's own program.
+func (api *PrivateWalletAPI) unspentOutputs(accountAddr string) []*transaction.UTXO {
+	var candidates []*transaction.UTXO
+	if api.s.wallet != nil {
+		owned, err := api.s.wallet.UTXOs(accountAddr)
+		if err != nil {
+			log.Error("Failed to read wallet outputs", "account", accountAddr, "err", err)
+			return nil
+		}
+		for _, u := range owned {
+			candidates = append(candidates, &transaction.UTXO{
+				OutputID:       u.OutputID,
+				SourceID:       u.SourceID,
+				AssetID:        u.AssetID,
+				Amount:         u.Amount,
+				SourcePos:      u.SourcePos,
+				ControlProgram: u.ControlProgram,
+				Address:        u.Address,
+			})
+		}
+	} else {
+		program, err := account.AddressProgram(accountAddr)
+		if err != nil {
+			return nil
+		}
+		candidates = rawdb.ReadUnspentOutputs(api.s.chainDb, program)
+	}
+
+	var utxos []*transaction.UTXO
+	for _, utxo := range candidates {
+		if _, spent := api.s.txPool.OutputSpender(utxo.OutputID); !spent {
+			utxos = append(utxos, utxo)
+		}
+	}
+	return utxos
+}
@@ -0,0 +1,316 @@
+// Package srcclient provides a client for the SilkRoad RPC API.
+package srcclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/srchain/srcd"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/common/hexutil"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/event"
+	"github.com/srchain/srcd/rpc"
+)
+
+// Client defines typed wrappers for the SilkRoad RPC API.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL, giving up when ctx is
+// cancelled.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (sc *Client) Close() {
+	sc.c.Close()
+}
+
+// Blockchain Access
+
+// BlockByHash returns the given full block. Use HeaderByHash if you don't
+// need the transactions.
+func (sc *Client) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return sc.getBlock(ctx, "chain_getBlockByHash", hash, true)
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is nil, the
+// latest known block is returned.
+func (sc *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return sc.getBlock(ctx, "chain_getBlockByNumber", toBlockNumArg(number), true)
+}
+
+// BlockNumber returns the number of the most recent block.
+func (sc *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := sc.c.CallContext(ctx, &number, "chain_blockNumber")
+	return number, err
+}
+
+type rpcHeader struct {
+	Number     uint64           `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	ParentHash common.Hash      `json:"parent_hash"`
+	Nonce      types.BlockNonce `json:"nonce"`
+	Coinbase   common.Address   `json:"coinbase"`
+	TxRoot     common.Hash      `json:"tx_root"`
+	Difficulty *hexutil.Big     `json:"difficulty"`
+	Timestamp  uint64           `json:"timestamp"`
+	ExtraData  hexutil.Bytes    `json:"extra_data"`
+}
+
+// header rebuilds the header h describes, checking it against the hash the
+// server reported.
+func (h *rpcHeader) header() (*types.Header, error) {
+	if h.Difficulty == nil {
+		return nil, errors.New("server returned header without difficulty")
+	}
+	head := &types.Header{
+		ParentHash: h.ParentHash,
+		Coinbase:   h.Coinbase,
+		TxHash:     h.TxRoot,
+		Difficulty: h.Difficulty.ToInt(),
+		Number:     new(big.Int).SetUint64(h.Number),
+		Time:       new(big.Int).SetUint64(h.Timestamp),
+		Extra:      h.ExtraData,
+		Nonce:      h.Nonce,
+	}
+	if hash := head.Hash(); hash != h.Hash {
+		return nil, fmt.Errorf("server returned header with wrong hash: have %x, want %x", hash, h.Hash)
+	}
+	return head, nil
+}
+
+type rpcBlock struct {
+	rpcHeader
+	Transactions []transaction.Tx `json:"transactions"`
+}
+
+func (sc *Client) getBlock(ctx context.Context, method string, args ...interface{}) (*types.Block, error) {
+	var body *rpcBlock
+	if err := sc.c.CallContext(ctx, &body, method, args...); err != nil {
+		return nil, err
+	} else if body == nil {
+		return nil, silkroad.NotFound
+	}
+	head, err := body.header()
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*types.Transaction, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		txs[i] = &types.Transaction{Tx: tx.TxData}
+	}
+	return types.NewBlockWithHeader(head).WithBody(txs), nil
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (sc *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return sc.getHeader(ctx, "chain_getHeaderByHash", hash)
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (sc *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return sc.getHeader(ctx, "chain_getHeaderByNumber", toBlockNumArg(number))
+}
+
+func (sc *Client) getHeader(ctx context.Context, method string, args ...interface{}) (*types.Header, error) {
+	var head *rpcHeader
+	if err := sc.c.CallContext(ctx, &head, method, args...); err != nil {
+		return nil, err
+	} else if head == nil {
+		return nil, silkroad.NotFound
+	}
+	return head.header()
+}
+
+type rpcTransaction struct {
+	tx transaction.Tx
+	txExtraInfo
+}
+
+type txExtraInfo struct {
+	BlockHash     *common.Hash `json:"block_hash,omitempty"`
+	BlockNumber   *uint64      `json:"block_number,omitempty"`
+	Confirmations uint64       `json:"confirmations"`
+}
+
+func (tx *rpcTransaction) UnmarshalJSON(msg []byte) error {
+	if err := json.Unmarshal(msg, &tx.tx); err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, &tx.txExtraInfo)
+}
+
+// TransactionByID returns the transaction with the given ID.
+func (sc *Client) TransactionByID(ctx context.Context, id transaction.Hash) (tx *types.Transaction, isPending bool, err error) {
+	var json *rpcTransaction
+	if err = sc.c.CallContext(ctx, &json, "chain_getTransaction", id); err != nil {
+		return nil, false, err
+	} else if json == nil {
+		return nil, false, silkroad.NotFound
+	}
+	return &types.Transaction{Tx: json.tx.TxData}, json.BlockHash == nil, nil
+}
+
+// TransactionCount returns the total number of transactions in the given block.
+func (sc *Client) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	var num *uint64
+	if err := sc.c.CallContext(ctx, &num, "chain_getBlockTransactionCountByHash", blockHash); err != nil {
+		return 0, err
+	} else if num == nil {
+		return 0, silkroad.NotFound
+	}
+	return uint(*num), nil
+}
+
+// TransactionInBlock returns a single transaction at index in the given block.
+func (sc *Client) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	var json *rpcTransaction
+	if err := sc.c.CallContext(ctx, &json, "chain_getTransactionByBlockHashAndIndex", blockHash, uint64(index)); err != nil {
+		return nil, err
+	} else if json == nil {
+		return nil, silkroad.NotFound
+	}
+	return &types.Transaction{Tx: json.tx.TxData}, nil
+}
+
+// SyncProgress retrieves the current progress of the sync algorithm. If there's
+// no sync currently running, it returns nil.
+func (sc *Client) SyncProgress(ctx context.Context) (*silkroad.SyncProgress, error) {
+	var raw json.RawMessage
+	if err := sc.c.CallContext(ctx, &raw, "chain_syncing"); err != nil {
+		return nil, err
+	}
+	// Handle the possible response types
+	var syncing bool
+	if err := json.Unmarshal(raw, &syncing); err == nil {
+		return nil, nil // Not syncing (always false)
+	}
+	progress := new(silkroad.SyncProgress)
+	if err := json.Unmarshal(raw, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head
+// on the given channel.
+func (sc *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (silkroad.Subscription, error) {
+	heads := make(chan *rpcHeader)
+	sub, err := sc.c.Subscribe(ctx, "chain", heads, "newHeads")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case h := <-heads:
+				head, err := h.header()
+				if err != nil {
+					return err
+				}
+				select {
+				case ch <- head:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// Reorg describes a switch of the canonical chain to another branch. Both
+// lists run from the highest block down.
+type Reorg struct {
+	Dropped []common.Hash `json:"dropped"`
+	Added   []common.Hash `json:"added"`
+}
+
+// SubscribeReorg subscribes to notifications about the canonical chain
+// switching branch on the given channel.
+func (sc *Client) SubscribeReorg(ctx context.Context, ch chan<- *Reorg) (silkroad.Subscription, error) {
+	return sc.c.Subscribe(ctx, "chain", ch, "reorg")
+}
+
+// State Access
+
+// UnspentOutputs returns the confirmed outputs paying to program that no
+// confirmed transaction has spent.
+func (sc *Client) UnspentOutputs(ctx context.Context, program []byte) ([]*transaction.UTXO, error) {
+	var utxos []*transaction.UTXO
+	err := sc.c.CallContext(ctx, &utxos, "chain_getUnspentOutputs", transaction.HexBytes(program))
+	return utxos, err
+}
+
+// AssetBalances sums the unspent outputs paying to program by asset.
+func (sc *Client) AssetBalances(ctx context.Context, program []byte) (map[transaction.AssetID]uint64, error) {
+	var balances map[transaction.AssetID]uint64
+	err := sc.c.CallContext(ctx, &balances, "chain_getBalance", transaction.HexBytes(program))
+	return balances, err
+}
+
+// Pending State
+
+// SubscribePendingTransactions subscribes to the IDs of the transactions
+// entering the node's transaction pool.
+func (sc *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- transaction.Hash) (silkroad.Subscription, error) {
+	return sc.c.Subscribe(ctx, "chain", ch, "newPendingTransactions")
+}
+
+// CheckTransaction validates a signed transaction against the node's chain
+// and transaction pool without submitting it.
+func (sc *Client) CheckTransaction(ctx context.Context, tx *types.Transaction) (*silkroad.TxCheck, error) {
+	data, err := tx.Tx.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	var check *silkroad.TxCheck
+	if err := sc.c.CallContext(ctx, &check, "chain_checkTransaction", string(data)); err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// SendTransaction injects a signed transaction into the pending pool for execution.
+func (sc *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	data, err := tx.Tx.MarshalText()
+	if err != nil {
+		return err
+	}
+	return sc.c.CallContext(ctx, nil, "chain_sendTransaction", string(data))
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
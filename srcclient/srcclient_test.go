@@ -0,0 +1,119 @@
+package srcclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/srchain/srcd"
+	"github.com/srchain/srcd/common/common"
+	"github.com/srchain/srcd/common/hexutil"
+	"github.com/srchain/srcd/core/transaction"
+	"github.com/srchain/srcd/core/types"
+	"github.com/srchain/srcd/rpc"
+)
+
+// Verify that Client implements the silkroad interfaces.
+var (
+	_ = silkroad.ChainReader(&Client{})
+	_ = silkroad.TransactionReader(&Client{})
+	_ = silkroad.UTXOReader(&Client{})
+	_ = silkroad.ChainSyncReader(&Client{})
+	_ = silkroad.TransactionChecker(&Client{})
+	_ = silkroad.TransactionSender(&Client{})
+	_ = silkroad.PendingStateEventer(&Client{})
+)
+
+var testHeader = &types.Header{
+	ParentHash: common.HexToHash("0x01"),
+	Difficulty: big.NewInt(131072),
+	Number:     big.NewInt(7),
+	Time:       big.NewInt(1534000000),
+	Extra:      []byte("srcd"),
+	Nonce:      types.EncodeNonce(42),
+}
+
+// ChainStub serves a canned chain over the chain RPC namespace.
+type ChainStub struct {
+	hash common.Hash // reported header hash
+}
+
+func (c *ChainStub) GetHeaderByNumber(number rpc.BlockNumber) map[string]interface{} {
+	if number != rpc.LatestBlockNumber && number.Int64() != testHeader.Number.Int64() {
+		return nil
+	}
+	return map[string]interface{}{
+		"number":      testHeader.Number.Uint64(),
+		"hash":        c.hash,
+		"parent_hash": testHeader.ParentHash,
+		"nonce":       testHeader.Nonce,
+		"coinbase":    testHeader.Coinbase,
+		"tx_root":     testHeader.TxHash,
+		"difficulty":  (*hexutil.Big)(testHeader.Difficulty),
+		"timestamp":   testHeader.Time.Uint64(),
+		"extra_data":  hexutil.Bytes(testHeader.Extra),
+	}
+}
+
+func (c *ChainStub) GetUnspentOutputs(program transaction.HexBytes) []*transaction.UTXO {
+	return []*transaction.UTXO{{
+		OutputID:       transaction.Hash{V0: 1},
+		AssetID:        *transaction.SRCAssetID,
+		Amount:         500,
+		ControlProgram: program,
+	}}
+}
+
+func (c *ChainStub) Syncing() interface{} {
+	return false
+}
+
+func newTestClient(t *testing.T, hash common.Hash) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("chain", &ChainStub{hash: hash}); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(rpc.DialInProc(server))
+}
+
+func TestHeaderByNumber(t *testing.T) {
+	client := newTestClient(t, testHeader.Hash())
+	defer client.Close()
+
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != testHeader.Hash() {
+		t.Errorf("header hash mismatch: have %x, want %x", head.Hash(), testHeader.Hash())
+	}
+	if _, err := client.HeaderByNumber(context.Background(), big.NewInt(8)); err != silkroad.NotFound {
+		t.Errorf("missing header error mismatch: have %v, want %v", err, silkroad.NotFound)
+	}
+}
+
+func TestHeaderByNumberWrongHash(t *testing.T) {
+	client := newTestClient(t, common.HexToHash("0xbad"))
+	defer client.Close()
+
+	if _, err := client.HeaderByNumber(context.Background(), nil); err == nil {
+		t.Fatal("expected error for header with wrong hash")
+	}
+}
+
+func TestUnspentOutputs(t *testing.T) {
+	client := newTestClient(t, testHeader.Hash())
+	defer client.Close()
+
+	program := []byte{0x00, 0x14, 0x01}
+	utxos, err := client.UnspentOutputs(context.Background(), program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 || utxos[0].Amount != 500 || string(utxos[0].ControlProgram) != string(program) {
+		t.Fatalf("unexpected unspent outputs %+v", utxos)
+	}
+	if progress, err := client.SyncProgress(context.Background()); err != nil || progress != nil {
+		t.Errorf("sync progress mismatch: have %v (%v), want nil", progress, err)
+	}
+}